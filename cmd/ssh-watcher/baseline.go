@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/baseline"
+	"github.com/mgla96/ssh-watcher/internal/config"
+)
+
+const baselineUsage = `usage: ssh-watcher baseline list [user]
+       ssh-watcher baseline prune [-older-than duration] [-user user]`
+
+// runBaseline inspects and prunes the per user login baseline.
+func runBaseline(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(baselineUsage)
+	}
+	cfg, err := config.NewBaseline()
+	if err != nil {
+		return err
+	}
+	store := baseline.NewFileStore(cfg.StateFilePath)
+
+	switch args[0] {
+	case "list":
+		users, err := store.Load()
+		if err != nil {
+			return err
+		}
+		return listBaseline(users, args[1:], stdout)
+	case "prune":
+		flags := flag.NewFlagSet("baseline prune", flag.ContinueOnError)
+		olderThan := flags.Duration("older-than", cfg.Retention.Duration, "drop entries last seen longer ago than this")
+		user := flags.String("user", "", "only prune this user, or remove it entirely when -older-than is 0")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		// A retention of 0 keeps the baseline forever, so 0 only removes
+		// entries of a single user.
+		if *olderThan <= 0 && *user == "" {
+			return errors.New("-older-than must be positive unless -user is given")
+		}
+
+		users, err := store.Load()
+		if err != nil {
+			return err
+		}
+		removed := pruneBaseline(users, *user, time.Now().Add(-*olderThan), *olderThan == 0)
+		if err := store.Save(users); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "removed %d users\n", removed)
+		return nil
+	default:
+		return errors.New(baselineUsage)
+	}
+}
+
+// pruneBaseline drops entries last seen before cutoff, only for user if set,
+// and returns the number of users removed. removeUser removes user entirely.
+func pruneBaseline(users baseline.Users, user string, cutoff time.Time, removeUser bool) int {
+	if user == "" {
+		return users.Prune(cutoff)
+	}
+	u, ok := users[user]
+	if !ok {
+		return 0
+	}
+	if removeUser || u.Prune(cutoff) {
+		delete(users, user)
+		return 1
+	}
+	return 0
+}
+
+func listBaseline(users baseline.Users, names []string, stdout io.Writer) error {
+	if len(names) == 0 {
+		for name := range users {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tKIND\tVALUE\tLAST SEEN")
+	for _, name := range names {
+		user, ok := users[name]
+		if !ok {
+			return fmt.Errorf("user %q is not in the baseline", name)
+		}
+		for _, kind := range []struct {
+			name string
+			seen map[string]time.Time
+		}{
+			{name: "ip", seen: user.IPs},
+			{name: "network", seen: user.Networks},
+			{name: "asn", seen: user.ASNs},
+			{name: "key", seen: user.KeyFingerprints},
+		} {
+			values := make([]string, 0, len(kind.seen))
+			for value := range kind.seen {
+				values = append(values, value)
+			}
+			sort.Strings(values)
+			for _, value := range values {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, kind.name, value, kind.seen[value].Format(time.RFC3339))
+			}
+		}
+	}
+	return w.Flush()
+}
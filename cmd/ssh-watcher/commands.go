@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// runCommand runs the subcommand named by args[0] with the remaining
+// arguments.
+func runCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	switch args[0] {
+	case "baseline":
+		return runBaseline(args[1:], stdout)
+	case "rules":
+		return runRules(args[1:], stdin, stdout)
+	case "patterns":
+		return runPatterns(args[1:], stdin, stdout)
+	case "silence":
+		return runSilence(args[1:], stdout)
+	case "posture":
+		return runPosture(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
@@ -1,26 +1,53 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/mgla96/ssh-watcher/internal/app"
 	"github.com/mgla96/ssh-watcher/internal/config"
 	"github.com/mgla96/ssh-watcher/internal/file"
 	"github.com/mgla96/ssh-watcher/internal/linetracker"
-	"github.com/mgla96/ssh-watcher/internal/notifier"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Info().Msg("Starting watcher")
 	config, err := config.New()
 	if err != nil {
 		panic(err)
 	}
 
-	notifier := notifier.NewSlackNotifier(config.Slack.WebhookUrl, config.Slack.Channel, config.Slack.Username, config.Slack.Icon, log.Logger)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	notifier, err := newRouter(ctx, config)
+	if err != nil {
+		panic(err)
+	}
+
+	pipeline, err := newPipeline(ctx, config, notifier, notifier.SinkNames())
+	if err != nil {
+		panic(err)
+	}
+
+	customParser, err := loadPatterns(config.Patterns.File)
+	if err != nil {
+		panic(err)
+	}
 	processedLineTracker := linetracker.NewFileProcessedLineTracker(config.StateFilePath)
 
 	fileOps := file.FileOps{}
@@ -31,10 +58,23 @@ func main() {
 		config.WatchSettings,
 		processedLineTracker,
 		fileOps,
+		customParser,
+		pipeline.stages...,
 	)
 
-	log.Info().Msg(fmt.Sprintf("starting watcher, webhook url: %s, logfile: %s", config.Slack.WebhookUrl, config.WatchSettings.LogFileLocation))
-	if err = watcher.Watch(); err != nil {
+	log.Info().Msg(fmt.Sprintf("starting watcher, notifier: %s, logfile: %s", config.Notifier, config.WatchSettings.LogFileLocation))
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- watcher.Watch()
+	}()
+
+	select {
+	case err = <-watchErr:
+	case <-ctx.Done():
+		log.Info().Msg("shutting down watcher")
+	}
+	pipeline.Close()
+	if err != nil {
 		panic(err)
 	}
 }
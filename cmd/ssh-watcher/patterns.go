@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/patterns"
+)
+
+const patternsUsage = `usage: ssh-watcher patterns test [-file patterns.json] [line ...]`
+
+// lineParser parses log lines the built-in parser does not know.
+type lineParser interface {
+	Parse(line string) (notifier.LogLine, bool)
+}
+
+// loadPatterns loads the patterns file at path. It returns nil without a
+// path so only the built-in parser is used.
+func loadPatterns(path string) (lineParser, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := patterns.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := patterns.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patterns: %w", err)
+	}
+	return parser, nil
+}
+
+// runPatterns validates the patterns file and shows which pattern matches
+// sample log lines, given as arguments or on stdin, and the event it yields.
+func runPatterns(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "test" {
+		return errors.New(patternsUsage)
+	}
+	cfg, err := config.NewPatterns()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("patterns test", flag.ContinueOnError)
+	path := flags.String("file", cfg.File, "patterns file, defaults to WR_PATTERNS_FILE")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New(patternsUsage)
+	}
+	file, err := patterns.Load(*path)
+	if err != nil {
+		return err
+	}
+	parser, err := patterns.New(file)
+	if err != nil {
+		return fmt.Errorf("invalid patterns: %w", err)
+	}
+
+	lines, err := sampleLines(flags.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tPATTERN\tEVENT\tSEVERITY\tUSER\tIP\tAUTH METHOD\tKEY")
+	for i, line := range lines {
+		name, event, ok := parser.Match(line)
+		if !ok {
+			fmt.Fprintf(w, "%d\t-\t-\t-\t-\t-\t-\t-\n", i+1)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i+1, name, event.EventType, event.Severity, orDash(event.Username), orDash(event.IpAddress), orDash(event.AuthMethod), orDash(event.KeyFingerprint))
+	}
+	return w.Flush()
+}
+
+// sampleLines returns args, or the lines read from stdin without args.
+func sampleLines(args []string, stdin io.Reader) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	var lines []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading lines: %w", err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/posture"
+)
+
+const postureUsage = `usage: ssh-watcher posture audit [-config sshd_config]`
+
+// runPosture audits the sshd configuration against the posture policy and
+// prints the findings. It fails when there are findings so it can gate
+// provisioning.
+func runPosture(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "audit" {
+		return errors.New(postureUsage)
+	}
+	cfg, err := config.NewPosture()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("posture audit", flag.ContinueOnError)
+	path := flags.String("config", cfg.SshdConfig, "sshd configuration file, defaults to WR_POSTURE_SSHD_CONFIG")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	findings, err := posture.Audit(*path)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Fprintf(stdout, "%s: no findings\n", *path)
+		return nil
+	}
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tCHECK\tMATCH\tVALUE\tWANT\tLOCATION")
+	for _, f := range findings {
+		location := f.Location
+		if location == "" {
+			location = "default"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Check, orDash(f.Match), f.Value, f.Want, location)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d findings", len(findings))
+}
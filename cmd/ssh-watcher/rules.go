@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/app"
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/rules"
+)
+
+const rulesUsage = `usage: ssh-watcher rules test [-file rules.json] [-patterns patterns.json] [-host name] [-at time] [line ...]`
+
+// runRules validates the rules file and shows how sample log lines, given as
+// arguments or on stdin, are handled by it.
+func runRules(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "test" {
+		return errors.New(rulesUsage)
+	}
+	cfg, err := config.NewRules()
+	if err != nil {
+		return err
+	}
+	patternsCfg, err := config.NewPatterns()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("rules test", flag.ContinueOnError)
+	path := flags.String("file", cfg.File, "rules file, defaults to WR_RULES_FILE")
+	host := flags.String("host", "localhost", "host machine of the events")
+	at := flags.String("at", "", "RFC 3339 time the lines are matched at, defaults to now")
+	patternsPath := flags.String("patterns", patternsCfg.File, "patterns file, defaults to WR_PATTERNS_FILE")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New(rulesUsage)
+	}
+	matchedAt := time.Now()
+	if *at != "" {
+		matchedAt, err = time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at: %w", err)
+		}
+	}
+
+	file, err := rules.Load(*path)
+	if err != nil {
+		return err
+	}
+	engine, err := rules.New(file, nil)
+	if err != nil {
+		return fmt.Errorf("invalid rules: %w", err)
+	}
+
+	customParser, err := loadPatterns(*patternsPath)
+	if err != nil {
+		return err
+	}
+	lines, err := sampleLines(flags.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	// Without stages the app only parses the lines.
+	parser := app.New("", nil, *host, config.WatchSettings{}, nil, nil, customParser)
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tEVENT\tUSER\tIP\tSEVERITY\tRULES\tACTION")
+	for i, line := range lines {
+		events := parser.DryRun(line)
+		if len(events) == 0 {
+			fmt.Fprintf(w, "%d\t-\t-\t-\t-\t-\tnot an event\n", i+1)
+			continue
+		}
+		for _, event := range events {
+			event, suppressed := engine.Evaluate(event, matchedAt)
+			action := "route"
+			switch {
+			case suppressed:
+				action = "suppress"
+			case len(event.Sinks) > 0:
+				action = "notify " + strings.Join(event.Sinks, ",")
+			}
+			if event.Respond {
+				action += ", respond"
+			}
+			if len(event.Tags) > 0 {
+				action += ", tag " + strings.Join(event.Tags, ",")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", i+1, event.EventType, orDash(event.Username), orDash(event.IpAddress), event.Severity, orDash(strings.Join(event.MatchedRules, ",")), action)
+		}
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
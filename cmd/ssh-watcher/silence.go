@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/silence"
+	"github.com/rs/zerolog"
+)
+
+const silenceUsage = `usage: ssh-watcher silence add [-duration 2h] [-starts-at time] [-created-by name] [-comment text] matcher ...
+       ssh-watcher silence list [-expired]
+       ssh-watcher silence expire id ...
+
+matchers are name=value or name=~regex, name being one of event_type,
+username, ip_address, host_machine, auth_method, country or severity`
+
+// runSilence manages the silences persisted in the silences file. A running
+// ssh-watcher picks up changes on its next reload.
+func runSilence(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(silenceUsage)
+	}
+	cfg, err := config.NewSilence()
+	if err != nil {
+		return err
+	}
+	silencer, err := silence.New(cfg.StateFilePath, 0, zerolog.Nop())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		return addSilence(silencer, args[1:], stdout)
+	case "list":
+		flags := flag.NewFlagSet("silence list", flag.ContinueOnError)
+		expired := flags.Bool("expired", false, "include expired silences")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return listSilences(silencer.List(), *expired, stdout)
+	case "expire":
+		if len(args) < 2 {
+			return errors.New(silenceUsage)
+		}
+		for _, id := range args[1:] {
+			if err := silencer.Expire(id); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "expired %s\n", id)
+		}
+		return nil
+	default:
+		return errors.New(silenceUsage)
+	}
+}
+
+func addSilence(silencer *silence.Silencer, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("silence add", flag.ContinueOnError)
+	duration := flags.Duration("duration", 2*time.Hour, "how long the silence lasts")
+	startsAt := flags.String("starts-at", "", "RFC 3339 time the silence starts, defaults to now")
+	createdBy := flags.String("created-by", currentUser(), "who created the silence")
+	comment := flags.String("comment", "", "why the silence was created")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New(silenceUsage)
+	}
+
+	s := silence.Silence{CreatedBy: *createdBy, Comment: *comment, StartsAt: time.Now()}
+	if *startsAt != "" {
+		var err error
+		s.StartsAt, err = time.Parse(time.RFC3339, *startsAt)
+		if err != nil {
+			return fmt.Errorf("invalid -starts-at: %w", err)
+		}
+	}
+	s.EndsAt = s.StartsAt.Add(*duration)
+	for _, arg := range flags.Args() {
+		m, err := silence.ParseMatcher(arg)
+		if err != nil {
+			return err
+		}
+		s.Matchers = append(s.Matchers, m)
+	}
+
+	s, err := silencer.Add(s)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, s.ID)
+	return nil
+}
+
+func listSilences(silences []silence.Silence, expired bool, stdout io.Writer) error {
+	now := time.Now()
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tMATCHERS\tSTARTS\tENDS\tCREATED BY\tCOMMENT")
+	for _, s := range silences {
+		status := s.Status(now)
+		if status == silence.Expired && !expired {
+			continue
+		}
+		matchers := make([]string, 0, len(s.Matchers))
+		for _, m := range s.Matchers {
+			matchers = append(matchers, m.String())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, status, strings.Join(matchers, " "), s.StartsAt.Format(time.RFC3339), s.EndsAt.Format(time.RFC3339), orDash(s.CreatedBy), orDash(s.Comment))
+	}
+	return w.Flush()
+}
+
+// currentUser returns the name of the user running the command, if known.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
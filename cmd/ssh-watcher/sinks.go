@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/digest"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/router"
+	"github.com/mgla96/ssh-watcher/internal/throttle"
+	"github.com/rs/zerolog/log"
+)
+
+// notifierClient is the interface implemented by all notification backends.
+type notifierClient interface {
+	Notify(logLine notifier.LogLine) error
+}
+
+func newNotifier(cfg *config.Config) notifierClient {
+	switch cfg.Notifier {
+	case config.PagerDutyNotifier:
+		return notifier.NewPagerDutyNotifier(cfg.PagerDuty.RoutingKey, cfg.PagerDuty.EventsUrl, log.Logger)
+	case config.OpsgenieNotifier:
+		return notifier.NewOpsgenieNotifier(cfg.Opsgenie.ApiKey, cfg.Opsgenie.ApiUrl, cfg.Opsgenie.Tags, log.Logger)
+	case config.AlertmanagerNotifier:
+		return notifier.NewAlertmanagerNotifier(cfg.Alertmanager.Url, cfg.Alertmanager.ResolveTimeout.Duration, cfg.Alertmanager.Labels, log.Logger)
+	case config.TelegramNotifier:
+		return notifier.NewTelegramNotifier(cfg.Telegram.BotToken, cfg.Telegram.ChatId, cfg.Telegram.ApiUrl, log.Logger)
+	case config.MatrixNotifier:
+		return notifier.NewMatrixNotifier(cfg.Matrix.HomeserverUrl, cfg.Matrix.AccessToken, cfg.Matrix.RoomId, log.Logger)
+	case config.NtfyNotifier:
+		priorities := map[notifier.EventType]notifier.NtfyPriority{}
+		for eventType, priority := range cfg.Ntfy.Priorities {
+			priorities[notifier.EventType(eventType)] = notifier.NtfyPriority(priority)
+		}
+		return notifier.NewNtfyNotifier(cfg.Ntfy.ServerUrl, cfg.Ntfy.Topic, cfg.Ntfy.AccessToken, priorities, log.Logger)
+	case config.GotifyNotifier:
+		priorities := map[notifier.EventType]int{}
+		for eventType, priority := range cfg.Gotify.Priorities {
+			priorities[notifier.EventType(eventType)] = priority
+		}
+		return notifier.NewGotifyNotifier(cfg.Gotify.ServerUrl, cfg.Gotify.AppToken, priorities, log.Logger)
+	case config.ExecNotifier:
+		return notifier.NewExecNotifier(cfg.Exec.Command, cfg.Exec.Args, cfg.Exec.Timeout.Duration, cfg.Exec.MaxConcurrent, log.Logger)
+	default:
+		return notifier.NewSlackNotifier(cfg.Slack.WebhookUrl, cfg.Slack.Channel, cfg.Slack.Username, cfg.Slack.Icon, log.Logger)
+	}
+}
+
+// newRouter builds the router delivering events to the sink selected with
+// WR_NOTIFIER and the sinks and routes defined in the routing file. Digests,
+// dedup follow-ups and overflow summaries are sent until ctx is done.
+func newRouter(ctx context.Context, cfg *config.Config) (router.Router, error) {
+	defaultSink := string(cfg.Notifier)
+	client, err := wrapSink(ctx, cfg, defaultSinkLimits(cfg, defaultSink), newNotifier(cfg))
+	if err != nil {
+		return router.Router{}, err
+	}
+	sinks := router.Sinks{defaultSink: client}
+	minSeverities := map[string]notifier.Severity{}
+	if err := addMinSeverity(minSeverities, defaultSink, cfg.MinSeverity); err != nil {
+		return router.Router{}, err
+	}
+	if cfg.RoutingFile == "" {
+		return router.New(sinks, minSeverities, nil, []string{defaultSink}, log.Logger)
+	}
+
+	routing, err := config.LoadRouting(cfg.RoutingFile)
+	if err != nil {
+		return router.Router{}, fmt.Errorf("failed loading routing: %w", err)
+	}
+	for _, sink := range routing.Sinks {
+		if sink.Name == defaultSink {
+			return router.Router{}, fmt.Errorf("sink %q of the routing file has the name of the default sink selected with %s_NOTIFIER", sink.Name, config.ServicePrefix)
+		}
+		sinkCfg, err := cfg.SinkConfig(sink)
+		if err != nil {
+			return router.Router{}, fmt.Errorf("failed configuring sink: %w", err)
+		}
+		client, err := wrapSink(ctx, cfg, sink, newNotifier(sinkCfg))
+		if err != nil {
+			return router.Router{}, err
+		}
+		sinks[sink.Name] = client
+		if err := addMinSeverity(minSeverities, sink.Name, sink.MinSeverity); err != nil {
+			return router.Router{}, err
+		}
+	}
+
+	defaultSinks := routing.DefaultSinks
+	if len(defaultSinks) == 0 {
+		defaultSinks = []string{defaultSink}
+	}
+	r, err := router.New(sinks, minSeverities, routing.Routes, defaultSinks, log.Logger)
+	if err != nil {
+		return router.Router{}, fmt.Errorf("failed creating router: %w", err)
+	}
+	return r, nil
+}
+
+// throttleCheckInterval is how often dedup follow-ups and overflow summaries
+// are sent.
+const throttleCheckInterval = 5 * time.Second
+
+// defaultSinkLimits returns the digest, dedup and rate limit settings of the
+// sink selected with WR_NOTIFIER.
+func defaultSinkLimits(cfg *config.Config, name string) config.Sink {
+	sink := config.Sink{Name: name}
+	if cfg.Digest.Enabled {
+		sink.Digest = &config.SinkDigest{EventTypes: cfg.Digest.EventTypes, Interval: cfg.Digest.Interval}
+	}
+	if cfg.Dedup.Window.Duration > 0 {
+		sink.Dedup = &config.SinkDedup{Window: cfg.Dedup.Window, Fields: cfg.Dedup.Fields}
+	}
+	if cfg.RateLimit.PerMinute > 0 {
+		sink.RateLimit = &config.SinkRateLimit{PerMinute: cfg.RateLimit.PerMinute, Burst: cfg.RateLimit.Burst}
+	}
+	return sink
+}
+
+// wrapSink wraps the client of sink in the digest, dedup and rate limiter it
+// configures, in that order, and runs their periodic work until ctx is done.
+// Digests see every event and the rate limiter applies to what remains.
+func wrapSink(ctx context.Context, cfg *config.Config, sink config.Sink, client notifierClient) (notifierClient, error) {
+	if sink.RateLimit != nil {
+		burst := sink.RateLimit.Burst
+		if burst == 0 {
+			burst = cfg.RateLimit.Burst
+		}
+		limiter, err := throttle.NewRateLimiter(client, throttle.RateLimitSettings{
+			Sink:        sink.Name,
+			HostMachine: cfg.HostMachineName,
+			PerMinute:   sink.RateLimit.PerMinute,
+			Burst:       burst,
+		}, log.Logger)
+		if err != nil {
+			return nil, err
+		}
+		go limiter.Run(ctx, throttleCheckInterval)
+		client = limiter
+	}
+	if sink.Dedup != nil {
+		fields := sink.Dedup.Fields
+		if len(fields) == 0 {
+			fields = cfg.Dedup.Fields
+		}
+		if sink.Dedup.Window.Duration <= 0 {
+			return nil, fmt.Errorf("dedup window of sink %q must be positive", sink.Name)
+		}
+		dedup, err := throttle.NewDedup(client, sink.Dedup.Window.Duration, fields, log.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dedup of sink %q: %w", sink.Name, err)
+		}
+		go dedup.Run(ctx, throttleCheckInterval)
+		client = dedup
+	}
+	if sink.Digest != nil {
+		settings := digest.Settings{
+			HostMachine: cfg.HostMachineName,
+			Interval:    sink.Digest.Interval.Duration,
+		}
+		if settings.Interval == 0 {
+			settings.Interval = cfg.Digest.Interval.Duration
+		}
+		if settings.Interval <= 0 {
+			return nil, fmt.Errorf("digest interval of sink %q must be positive", sink.Name)
+		}
+		for _, eventType := range sink.Digest.EventTypes {
+			settings.EventTypes = append(settings.EventTypes, notifier.EventType(eventType))
+		}
+		digester, err := digest.New(client, filepath.Join(cfg.Digest.StateDir, sink.Name+".json"), settings, log.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed restoring digest of sink %q: %w", sink.Name, err)
+		}
+		go digester.Run(ctx)
+		client = digester
+	}
+	return client, nil
+}
+
+// addMinSeverity records the minimum severity of the named sink unless it is
+// empty.
+func addMinSeverity(minSeverities map[string]notifier.Severity, sink, minSeverity string) error {
+	if minSeverity == "" {
+		return nil
+	}
+	severity, err := notifier.ParseSeverity(minSeverity)
+	if err != nil {
+		return fmt.Errorf("invalid minimum severity of sink %q: %w", sink, err)
+	}
+	minSeverities[sink] = severity
+	return nil
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/accesslist"
+	"github.com/mgla96/ssh-watcher/internal/app"
+	"github.com/mgla96/ssh-watcher/internal/baseline"
+	"github.com/mgla96/ssh-watcher/internal/canary"
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/detector"
+	"github.com/mgla96/ssh-watcher/internal/geoip"
+	"github.com/mgla96/ssh-watcher/internal/geopolicy"
+	"github.com/mgla96/ssh-watcher/internal/integrity"
+	"github.com/mgla96/ssh-watcher/internal/keyindex"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/posture"
+	"github.com/mgla96/ssh-watcher/internal/responder"
+	"github.com/mgla96/ssh-watcher/internal/rules"
+	"github.com/mgla96/ssh-watcher/internal/schedule"
+	"github.com/mgla96/ssh-watcher/internal/severity"
+	"github.com/mgla96/ssh-watcher/internal/silence"
+	"github.com/mgla96/ssh-watcher/internal/threatintel"
+	"github.com/rs/zerolog/log"
+)
+
+// pipeline holds the stages log lines pass through before they are sent, in
+// the order they run, and the functions releasing their resources.
+type pipeline struct {
+	stages  []app.Stage
+	closers []func() error
+}
+
+// newPipeline builds the enabled stages. The active responder reports its
+// own actions to client; the integrity monitor and the posture auditor report
+// through the classifier, the rules and the silences. sinks are the names of
+// the sinks rules may send events to. Background work stops when ctx is done.
+func newPipeline(ctx context.Context, cfg *config.Config, client notifierClient, sinks []string) (*pipeline, error) {
+	p := &pipeline{}
+
+	for eventType, s := range cfg.Severity.Defaults {
+		severity, err := notifier.ParseSeverity(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default severity of %q: %w", eventType, err)
+		}
+		notifier.SetDefaultSeverity(notifier.EventType(eventType), severity)
+	}
+	classifier, err := newClassifier(cfg.Severity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enrichment runs first so every later stage sees the location and
+	// threat feeds.
+	var geo *geoip.GeoIP
+	if cfg.Geoip.CityDatabasePath != "" || cfg.Geoip.AsnDatabasePath != "" {
+		geo, err = geoip.New(cfg.Geoip.CityDatabasePath, cfg.Geoip.AsnDatabasePath, cfg.Geoip.ReloadInterval.Duration, log.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed opening geoip databases: %w", err)
+		}
+		p.stages = append(p.stages, geo)
+		p.closers = append(p.closers, geo.Close)
+	}
+	if len(cfg.ThreatIntel.Feeds) > 0 {
+		threatIntel, err := threatintel.New(cfg.ThreatIntel.Feeds, cfg.ThreatIntel.ReloadInterval.Duration, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.stages = append(p.stages, threatIntel)
+	}
+	// Canary events are marked before any stage that may suppress them, which
+	// then passes them on regardless.
+	if len(cfg.Canary.Usernames) > 0 || len(cfg.Canary.KeyFingerprints) > 0 {
+		p.stages = append(p.stages, canary.New(canary.Settings{
+			Usernames:         cfg.Canary.Usernames,
+			KeyFingerprints:   cfg.Canary.KeyFingerprints,
+			ContextLines:      cfg.Canary.ContextLines,
+			MaxTrackedSources: cfg.Canary.MaxTrackedSources,
+		}))
+	}
+	if cfg.AccessList.File != "" {
+		accessList, err := accesslist.New(cfg.AccessList.File, cfg.AccessList.ReloadInterval.Duration, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.stages = append(p.stages, accessList)
+	}
+	if len(cfg.KeyIndex.Paths) > 0 {
+		keyIndex, err := keyindex.New(cfg.KeyIndex.Paths, cfg.KeyIndex.ReloadInterval.Duration, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.stages = append(p.stages, keyIndex)
+	}
+	// The baseline runs before the detectors so it records logins the
+	// correlator replaces.
+	if cfg.Baseline.Enabled {
+		p.stages = append(p.stages, newBaseline(cfg.Baseline, geo))
+	}
+	if cfg.ImpossibleTravel.Enabled {
+		travel, err := geopolicy.NewImpossibleTravel(geopolicy.TravelSettings{
+			MaxSpeedKmh:    cfg.ImpossibleTravel.MaxSpeedKmh,
+			IgnoreRadiusKm: cfg.ImpossibleTravel.IgnoreRadiusKm,
+			VpnAsns:        cfg.ImpossibleTravel.VpnAsns,
+			StateFilePath:  cfg.ImpossibleTravel.StateFilePath,
+		}, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.stages = append(p.stages, travel)
+	}
+	if len(cfg.CountryPolicy.AllowedCountries) > 0 || len(cfg.CountryPolicy.UserAllowedCountries) > 0 {
+		userCountries := map[string][]string{}
+		for user, countries := range cfg.CountryPolicy.UserAllowedCountries {
+			userCountries[user] = strings.Fields(countries)
+		}
+		p.stages = append(p.stages, geopolicy.NewCountryPolicy(cfg.CountryPolicy.AllowedCountries, userCountries))
+	}
+	if cfg.Schedule.File != "" {
+		policies, err := schedule.New(cfg.Schedule.File, cfg.Schedule.ReloadInterval.Duration, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.stages = append(p.stages, policies)
+	}
+	// The correlator and spray detectors run before the brute force detector
+	// so they still see the failures it suppresses.
+	if cfg.FailureCorrelation.Enabled {
+		p.stages = append(p.stages, detector.NewCorrelator(detector.CorrelatorSettings{
+			Window:      cfg.FailureCorrelation.Window.Duration,
+			MinFailures: cfg.FailureCorrelation.MinFailures,
+			MaxTracked:  cfg.FailureCorrelation.MaxTrackedPairs,
+		}))
+	}
+	if cfg.PasswordSpray.Enabled {
+		p.stages = append(p.stages, detector.NewPasswordSpray(detector.SpraySettings{
+			Window:     cfg.PasswordSpray.Window.Duration,
+			Threshold:  cfg.PasswordSpray.Threshold,
+			Cooldown:   cfg.PasswordSpray.Cooldown.Duration,
+			MaxTracked: cfg.PasswordSpray.MaxTrackedSources,
+		}))
+	}
+	if cfg.DistributedAttack.Enabled {
+		p.stages = append(p.stages, detector.NewDistributedAttack(detector.SpraySettings{
+			Window:     cfg.DistributedAttack.Window.Duration,
+			Threshold:  cfg.DistributedAttack.Threshold,
+			Cooldown:   cfg.DistributedAttack.Cooldown.Duration,
+			MaxTracked: cfg.DistributedAttack.MaxTrackedUsers,
+		}))
+	}
+	if cfg.BruteForce.Enabled {
+		p.stages = append(p.stages, detector.NewBruteForce(detector.BruteForceSettings{
+			Window:        cfg.BruteForce.Window.Duration,
+			IpThreshold:   cfg.BruteForce.IpThreshold,
+			UserThreshold: cfg.BruteForce.UserThreshold,
+			Cooldown:      cfg.BruteForce.Cooldown.Duration,
+			MaxTracked:    cfg.BruteForce.MaxTrackedSources,
+		}))
+	}
+	// The classifier runs after every stage that marks or emits events and
+	// the rules run last so suppressed events still reach the detectors. Both
+	// run before the responder so it triggers on their outcome.
+	p.stages = append(p.stages, classifier)
+	ruleEngine, err := newRules(cfg, sinks)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	p.stages = append(p.stages, ruleEngine)
+	if cfg.ActiveResponse.Enabled {
+		activeResponder, err := newResponder(cfg, client)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		go activeResponder.Run(ctx, cfg.ActiveResponse.CheckInterval.Duration)
+		p.stages = append(p.stages, activeResponder)
+		// Unbanning on shutdown must happen before the databases close.
+		p.closers = append([]func() error{activeResponder.Close}, p.closers...)
+	}
+	// Silences run last so they only mute notifications; silenced events
+	// still reach the detectors and trigger bans.
+	silencer, err := silence.New(cfg.Silence.StateFilePath, cfg.Silence.ReloadInterval.Duration, log.Logger)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	p.stages = append(p.stages, silencer)
+	if cfg.Silence.ListenAddress != "" {
+		p.closers = append(p.closers, serveSilences(cfg.Silence.ListenAddress, cfg.Silence.ApiToken, silencer))
+	}
+	// Integrity and posture events do not come from the log file, so they
+	// only pass through the stages that apply to any event before they are
+	// sent.
+	backgroundClient := app.NewStagedNotifier(client, classifier, ruleEngine, silencer)
+	if cfg.Integrity.Enabled {
+		monitor, err := integrity.New(backgroundClient, integrity.Settings{
+			HostMachine:    cfg.HostMachineName,
+			AuthorizedKeys: cfg.Integrity.AuthorizedKeys,
+			SshdConfig:     cfg.Integrity.SshdConfig,
+			StateFilePath:  cfg.Integrity.StateFilePath,
+		}, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		go monitor.Run(ctx, cfg.Integrity.CheckInterval.Duration)
+	}
+	if cfg.Posture.Enabled {
+		auditor, err := posture.New(backgroundClient, posture.Settings{
+			HostMachine:   cfg.HostMachineName,
+			SshdConfig:    cfg.Posture.SshdConfig,
+			StateFilePath: cfg.Posture.StateFilePath,
+		}, log.Logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		go auditor.Run(ctx, cfg.Posture.CheckInterval.Duration)
+	}
+	return p, nil
+}
+
+// serveSilences serves the silences API on address in the background and
+// returns the function shutting the server down.
+func serveSilences(address, token string, silencer *silence.Silencer) func() error {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           silence.Handler(silencer, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Info().Msg(fmt.Sprintf("serving silences API on %s", address))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("silences API stopped")
+		}
+	}()
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
+
+// Close releases the resources of the stages, logging failures.
+func (p *pipeline) Close() {
+	for _, closer := range p.closers {
+		if err := closer(); err != nil {
+			log.Error().Err(err).Msg("failed shutting down")
+		}
+	}
+}
+
+// asnLookup resolves the autonomous system number announcing an IP address.
+type asnLookup interface {
+	ASN(ip netip.Addr) (uint, bool)
+}
+
+// newBaseline builds the baseline, resolving ASNs with geo if it is not nil.
+func newBaseline(cfg config.Baseline, geo *geoip.GeoIP) *baseline.Baseline {
+	// A nil *geoip.GeoIP must not become a non nil interface.
+	var lookup asnLookup
+	if geo != nil {
+		lookup = geo
+	}
+	return baseline.New(
+		baseline.NewFileStore(cfg.StateFilePath),
+		lookup,
+		baseline.Settings{
+			LearningPeriod: cfg.LearningPeriod.Duration,
+			Retention:      cfg.Retention.Duration,
+			Granularity:    baseline.Granularity(cfg.Granularity),
+			IPv4PrefixLen:  cfg.Ipv4PrefixLen,
+			IPv6PrefixLen:  cfg.Ipv6PrefixLen,
+		},
+		log.Logger,
+	)
+}
+
+// newClassifier builds the classifier applying the escalation rules of cfg.
+func newClassifier(cfg config.Severity) (severity.Classifier, error) {
+	settings := severity.Settings{PrivilegedUsers: cfg.PrivilegedUsers}
+	for _, rule := range []struct {
+		name   string
+		value  string
+		target *notifier.Severity
+	}{
+		{name: "privileged login", value: cfg.PrivilegedLogin, target: &settings.PrivilegedLogin},
+		{name: "denylisted", value: cfg.Denylisted, target: &settings.Denylisted},
+		{name: "out of hours", value: cfg.OutOfHours, target: &settings.OutOfHours},
+	} {
+		if rule.value == "" {
+			continue
+		}
+		s, err := notifier.ParseSeverity(rule.value)
+		if err != nil {
+			return severity.Classifier{}, fmt.Errorf("invalid %s severity: %w", rule.name, err)
+		}
+		*rule.target = s
+	}
+	return severity.NewClassifier(settings), nil
+}
+
+// newRules builds the rules of the rules file, or the rules equivalent to the
+// watch settings without one.
+func newRules(cfg *config.Config, sinks []string) (rules.Engine, error) {
+	file := rules.FromWatchSettings(cfg.WatchSettings)
+	if cfg.Rules.File != "" {
+		var err error
+		file, err = rules.Load(cfg.Rules.File)
+		if err != nil {
+			return rules.Engine{}, err
+		}
+	}
+	engine, err := rules.New(file, sinks)
+	if err != nil {
+		return rules.Engine{}, fmt.Errorf("invalid rules: %w", err)
+	}
+	return engine, nil
+}
+
+// banExecutor applies bans to the host firewall.
+type banExecutor interface {
+	Ban(ip netip.Addr, ttl time.Duration) error
+	Unban(ip netip.Addr) error
+}
+
+// newResponder builds the active responder reporting bans through client.
+func newResponder(cfg *config.Config, client notifierClient) (*responder.Responder, error) {
+	activeResponse := cfg.ActiveResponse
+	runner := responder.ExecRunner{}
+
+	var executor banExecutor
+	switch activeResponse.Backend {
+	case config.NftablesBackend:
+		executor = responder.NewNftablesExecutor(runner, activeResponse.Nftables.Family, activeResponse.Nftables.Table, activeResponse.Nftables.Set, activeResponse.Nftables.Set6)
+	case config.IpsetBackend:
+		executor = responder.NewIpsetExecutor(runner, activeResponse.Ipset.Set, activeResponse.Ipset.Set6)
+	case config.IptablesBackend:
+		executor = responder.NewIptablesExecutor(runner, activeResponse.Iptables.Chain)
+	default:
+		return nil, fmt.Errorf("unknown active response backend %q", activeResponse.Backend)
+	}
+
+	var allowlist []netip.Prefix
+	for _, entry := range activeResponse.Allowlist {
+		prefix, err := cidr.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid active response allowlist: %w", err)
+		}
+		allowlist = append(allowlist, prefix)
+	}
+	triggers := make([]notifier.EventType, 0, len(activeResponse.Triggers))
+	for _, trigger := range activeResponse.Triggers {
+		triggers = append(triggers, notifier.EventType(trigger))
+	}
+
+	return responder.New(
+		executor,
+		responder.NewFileBanStore(activeResponse.StateFilePath),
+		client,
+		responder.Settings{
+			HostMachine:     cfg.HostMachineName,
+			BanDuration:     activeResponse.BanDuration.Duration,
+			Triggers:        triggers,
+			TriggerRules:    activeResponse.TriggerRules,
+			BanDenylisted:   activeResponse.BanDenylisted,
+			Allowlist:       allowlist,
+			UnbanOnShutdown: activeResponse.UnbanOnShutdown,
+		},
+		log.Logger,
+	)
+}
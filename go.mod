@@ -4,6 +4,7 @@ go 1.21.5
 
 require (
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/oschwald/maxminddb-golang v1.13.1
 	github.com/rs/zerolog v1.31.0
 )
 
@@ -12,7 +13,7 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.8.1 // indirect
 	golang.org/x/mod v0.14.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/tools v0.17.0 // indirect
 )
@@ -0,0 +1,224 @@
+// Package accesslist suppresses events from trusted users and sources and
+// escalates events from distrusted ones.
+package accesslist
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// rule is the compiled form of a Rule.
+type rule struct {
+	name       string
+	action     Action
+	severity   notifier.Severity
+	eventTypes map[notifier.EventType]bool
+	users      map[string]bool
+	prefixes   []netip.Prefix
+	// matchUsers and matchIPs are set when the rule lists users or IPs, so a
+	// rule whose list file is empty matches nothing instead of everything.
+	matchUsers bool
+	matchIPs   bool
+}
+
+func compile(r Rule) (rule, error) {
+	if r.Name == "" {
+		return rule{}, fmt.Errorf("rule has no name")
+	}
+	compiled := rule{
+		name:       r.Name,
+		action:     r.Action,
+		severity:   notifier.SeverityCritical,
+		eventTypes: map[notifier.EventType]bool{},
+		users:      map[string]bool{},
+		matchUsers: len(r.Users) > 0 || r.UsersFile != "",
+		matchIPs:   len(r.IPs) > 0 || r.IPsFile != "",
+	}
+	switch r.Action {
+	case Allow, Deny:
+	default:
+		return rule{}, fmt.Errorf("rule %q has unknown action %q", r.Name, r.Action)
+	}
+	if len(r.EventTypes) == 0 && len(r.Users) == 0 && len(r.IPs) == 0 && r.UsersFile == "" && r.IPsFile == "" {
+		return rule{}, fmt.Errorf("rule %q matches every event, set users, ips or event_types", r.Name)
+	}
+	if r.Severity != "" {
+		severity, err := notifier.ParseSeverity(r.Severity)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		compiled.severity = severity
+	}
+	for _, eventType := range r.EventTypes {
+		compiled.eventTypes[notifier.EventType(eventType)] = true
+	}
+
+	users, ips := r.Users, r.IPs
+	if r.UsersFile != "" {
+		lines, err := readLines(r.UsersFile)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		users = append(append([]string{}, users...), lines...)
+	}
+	if r.IPsFile != "" {
+		lines, err := readLines(r.IPsFile)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		ips = append(append([]string{}, ips...), lines...)
+	}
+	for _, user := range users {
+		compiled.users[user] = true
+	}
+	for _, ip := range ips {
+		prefix, err := cidr.Parse(ip)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		compiled.prefixes = append(compiled.prefixes, prefix)
+	}
+	return compiled, nil
+}
+
+func (r rule) matches(logLine notifier.LogLine) bool {
+	if len(r.eventTypes) > 0 && !r.eventTypes[logLine.EventType] {
+		return false
+	}
+	if r.matchUsers && !r.users[logLine.Username] {
+		return false
+	}
+	if r.matchIPs && !cidr.Contains(r.prefixes, logLine.IpAddress) {
+		return false
+	}
+	return true
+}
+
+// New loads the access list file at path. The file and the list files it
+// references are checked for changes at most every reloadInterval.
+func New(path string, reloadInterval time.Duration, log zerolog.Logger) (*AccessList, error) {
+	a := &AccessList{
+		path:           path,
+		reloadInterval: reloadInterval,
+		now:            time.Now,
+		log:            log,
+	}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	a.lastCheck = a.now()
+	return a, nil
+}
+
+// AccessList is an app stage applying allow and deny rules. Events matching
+// a deny rule are escalated and marked Denylisted, events matching only allow
+// rules are suppressed.
+// The names of all matching rules are recorded in MatchedRules.
+type AccessList struct {
+	path           string
+	rules          []rule
+	modTimes       map[string]time.Time
+	reloadInterval time.Duration
+	lastCheck      time.Time
+	now            func() time.Time
+	log            zerolog.Logger
+	mu             sync.Mutex
+}
+
+// load reads the access list and list files and replaces the rules.
+func (a *AccessList) load() error {
+	file, err := readFile(a.path)
+	if err != nil {
+		return err
+	}
+	modTimes := map[string]time.Time{}
+	paths := []string{a.path}
+	var rules []rule
+	for _, r := range file.Rules {
+		compiled, err := compile(r)
+		if err != nil {
+			return fmt.Errorf("invalid access list %s: %w", a.path, err)
+		}
+		rules = append(rules, compiled)
+		for _, path := range []string{r.UsersFile, r.IPsFile} {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	a.rules, a.modTimes = rules, modTimes
+	return nil
+}
+
+// changed reports whether any of the files changed since they were loaded.
+func (a *AccessList) changed() bool {
+	for path, modTime := range a.modTimes {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadChanged reloads the rules once reloadInterval passed since the last
+// check if a file changed. Invalid changes are logged and the previous rules
+// stay in use. a.mu must be held.
+func (a *AccessList) reloadChanged() {
+	now := a.now()
+	if now.Sub(a.lastCheck) < a.reloadInterval {
+		return
+	}
+	a.lastCheck = now
+	if !a.changed() {
+		return
+	}
+	if err := a.load(); err != nil {
+		a.log.Error().Err(err).Msg("failed reloading access list, keeping previous rules")
+		return
+	}
+	a.log.Info().Msg(fmt.Sprintf("reloaded access list %s", a.path))
+}
+
+// Process implements the app stage interface.
+func (a *AccessList) Process(logLine notifier.LogLine) []notifier.LogLine {
+	a.mu.Lock()
+	a.reloadChanged()
+	rules := a.rules
+	a.mu.Unlock()
+
+	allowed, denied := false, false
+	for _, r := range rules {
+		if !r.matches(logLine) {
+			continue
+		}
+		logLine.MatchedRules = append(logLine.MatchedRules, r.name)
+		switch r.action {
+		case Allow:
+			allowed = true
+		case Deny:
+			denied = true
+			logLine.Denylisted = true
+			if r.severity.Rank() > logLine.Severity.Rank() {
+				logLine.Severity = r.severity
+			}
+		}
+	}
+	if allowed && !denied {
+		a.log.Debug().Msg(fmt.Sprintf("%s suppressed by %v", logLine.EventType, logLine.MatchedRules))
+		return nil
+	}
+	return []notifier.LogLine{logLine}
+}
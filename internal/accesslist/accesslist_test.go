@@ -0,0 +1,148 @@
+package accesslist
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccessList_Process(t *testing.T) {
+	dir := t.TempDir()
+	hostile := filepath.Join(dir, "hostile.txt")
+	writeFile(t, hostile, "# hostile networks\n203.0.113.0/24\n\n2001:db8:bad::/48\n")
+	path := filepath.Join(dir, "access.json")
+	writeFile(t, path, `{"rules": [
+		{"name": "ci", "action": "allow", "users": ["ci"], "ips": ["10.0.0.0/8", "fd00::/8"], "event_types": ["logged in"]},
+		{"name": "bastion", "action": "allow", "ips": ["192.168.1.10"]},
+		{"name": "hostile", "action": "deny", "ips_file": "`+hostile+`"},
+		{"name": "root", "action": "deny", "users": ["root"], "severity": "high"}
+	]}`)
+	a, err := New(path, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    []notifier.LogLine
+	}{
+		{
+			name:    "allowed user and network",
+			logLine: notifier.LogLine{Username: "ci", IpAddress: "10.1.2.3", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+		},
+		{
+			name:    "allowed ipv6 network",
+			logLine: notifier.LogLine{Username: "ci", IpAddress: "fd12::1", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+		},
+		{
+			name:    "allow rule limited to event types",
+			logLine: notifier.LogLine{Username: "ci", IpAddress: "10.1.2.3", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow},
+			want:    []notifier.LogLine{{Username: "ci", IpAddress: "10.1.2.3", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow}},
+		},
+		{
+			name:    "denied network from list file",
+			logLine: notifier.LogLine{Username: "alice", IpAddress: "2001:db8:bad::1", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow},
+			want:    []notifier.LogLine{{Username: "alice", IpAddress: "2001:db8:bad::1", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityCritical, MatchedRules: []string{"hostile"}, Denylisted: true}},
+		},
+		{
+			name:    "deny wins over allow",
+			logLine: notifier.LogLine{Username: "root", IpAddress: "192.168.1.10", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+			want:    []notifier.LogLine{{Username: "root", IpAddress: "192.168.1.10", EventType: notifier.LoggedIn, Severity: notifier.SeverityHigh, MatchedRules: []string{"bastion", "root"}, Denylisted: true}},
+		},
+		{
+			name:    "severity is never lowered",
+			logLine: notifier.LogLine{Username: "root", IpAddress: "1.2.3.4", EventType: notifier.BruteForceDetected, Severity: notifier.SeverityCritical},
+			want:    []notifier.LogLine{{Username: "root", IpAddress: "1.2.3.4", EventType: notifier.BruteForceDetected, Severity: notifier.SeverityCritical, MatchedRules: []string{"root"}, Denylisted: true}},
+		},
+		{
+			name:    "no match",
+			logLine: notifier.LogLine{Username: "alice", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+			want:    []notifier.LogLine{{Username: "alice", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Process(tt.logLine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Process() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid json", content: `{"rules": [`},
+		{name: "missing name", content: `{"rules": [{"action": "allow"}]}`},
+		{name: "unknown action", content: `{"rules": [{"name": "x", "action": "block"}]}`},
+		{name: "invalid cidr", content: `{"rules": [{"name": "x", "action": "deny", "ips": ["10.0.0.0/33"]}]}`},
+		{name: "invalid severity", content: `{"rules": [{"name": "x", "action": "deny", "users": ["root"], "severity": "urgent"}]}`},
+		{name: "no matchers", content: `{"rules": [{"name": "x", "action": "allow"}]}`},
+		{name: "unknown field", content: `{"rules": [{"name": "x", "action": "allow", "user": ["ci"]}]}`},
+		{name: "missing list file", content: `{"rules": [{"name": "x", "action": "deny", "ips_file": "/nonexistent"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "access.json")
+			writeFile(t, path, tt.content)
+			if _, err := New(path, time.Minute, zerolog.Nop()); err == nil {
+				t.Errorf("New() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestAccessList_Reload(t *testing.T) {
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.txt")
+	writeFile(t, users, "ci\n")
+	path := filepath.Join(dir, "access.json")
+	writeFile(t, path, `{"rules": [{"name": "trusted", "action": "allow", "users_file": "`+users+`"}]}`)
+
+	a, err := New(path, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	deploy := notifier.LogLine{Username: "deploy", EventType: notifier.LoggedIn}
+
+	writeFile(t, users, "ci\ndeploy\n")
+	future := now.Add(time.Hour)
+	if err := os.Chtimes(users, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Process(deploy); len(got) != 1 {
+		t.Errorf("Process() before reload interval = %v, want passed through", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := a.Process(deploy); len(got) != 0 {
+		t.Errorf("Process() after reload = %v, want suppressed", got)
+	}
+
+	// An invalid change keeps the previous rules.
+	writeFile(t, path, `{"rules": [`)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if got := a.Process(deploy); len(got) != 0 {
+		t.Errorf("Process() after invalid change = %v, want still suppressed", got)
+	}
+}
@@ -0,0 +1,82 @@
+package accesslist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Action is what happens to events matching a rule.
+type Action string
+
+const (
+	// Allow suppresses matching events unless a deny rule matches too.
+	Allow Action = "allow"
+	// Deny escalates matching events to the severity of the rule.
+	Deny Action = "deny"
+)
+
+// File is the content of the access list file.
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches events by username and source. Every non empty field must
+// match the event and a field matches when any of its values does.
+type Rule struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	// Severity is the severity deny rules escalate events to, critical by
+	// default.
+	Severity   string   `json:"severity"`
+	EventTypes []string `json:"event_types"`
+	Users      []string `json:"users"`
+	// IPs holds IPv4 and IPv6 addresses or CIDR ranges.
+	IPs []string `json:"ips"`
+	// UsersFile and IPsFile name files listing additional users and IPs, one
+	// per line. Empty lines and lines starting with # are ignored. Changes to
+	// them are picked up like changes to the access list file.
+	UsersFile string `json:"users_file"`
+	IPsFile   string `json:"ips_file"`
+}
+
+// readFile reads and parses the access list file at path.
+func readFile(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("failed reading access list: %w", err)
+	}
+	file := File{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return File{}, fmt.Errorf("failed parsing access list %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// readLines returns the entries of a list file.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading list: %w", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading list %s: %w", path, err)
+	}
+	return lines, nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -43,7 +44,33 @@ type file interface {
 	Open(name string) (*os.File, error)
 }
 
-func New(logFile string, notifier notifierClient, hostMachine string, watchSettings config.WatchSettings, processedLineTracker processedLineTracker, file file) App {
+// lineParser parses log lines the built-in parser does not know, such as the
+// lines of PAM modules.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . lineParser
+type lineParser interface {
+	Parse(line string) (notifier.LogLine, bool)
+}
+
+// Stage inspects parsed log lines before notifications are sent. It returns
+// the log lines to pass on to the next stage, which may be none to suppress
+// the line or include events derived from it.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Stage
+type Stage interface {
+	Process(logLine notifier.LogLine) []notifier.LogLine
+}
+
+// lineObserver is implemented by stages that need every raw log line,
+// including the lines that are not events.
+type lineObserver interface {
+	Observe(line string)
+}
+
+// New creates the app. Lines are parsed by customParser, if not nil, before
+// the built-in parser. Parsed log lines run through stages in order before
+// notifications are sent; every log line the last stage returns is sent.
+func New(logFile string, notifier notifierClient, hostMachine string, watchSettings config.WatchSettings, processedLineTracker processedLineTracker, file file, customParser lineParser, stages ...Stage) App {
 	return App{
 		logFile:              logFile,
 		notifier:             notifier,
@@ -51,6 +78,9 @@ func New(logFile string, notifier notifierClient, hostMachine string, watchSetti
 		watchSettings:        watchSettings,
 		processedLineTracker: processedLineTracker,
 		file:                 file,
+		customParser:         customParser,
+		stages:               stages,
+		now:                  time.Now,
 	}
 }
 
@@ -61,19 +91,10 @@ type App struct {
 	watchSettings        config.WatchSettings
 	processedLineTracker processedLineTracker
 	file                 file
-}
-
-func (a App) shouldSendMessage(eventType notifier.EventType) bool {
-	switch {
-	case eventType == notifier.LoggedIn && a.watchSettings.AcceptedLogins:
-		return true
-	case eventType == notifier.FailedLoginAttempt && a.watchSettings.FailedLogins:
-		return true
-	case eventType == notifier.FailedLoginAttemptInvalidUsername && a.watchSettings.FailedLoginInvalidUsername:
-		return true
-	default:
-		return false
-	}
+	customParser         lineParser
+	stages               []Stage
+	// now is the time syslog timestamps without a year are placed relative to.
+	now func() time.Time
 }
 
 func (a App) parseLogLine(line string) notifier.LogLine {
@@ -93,7 +114,7 @@ func (a App) parseLogLine(line string) notifier.LogLine {
 
 	if logLine.EventType != "" {
 		parts := strings.Split(line, " ")
-		logLine.LoginTime = parts[0] + " " + parts[1]
+		logLine.LoginTime, logLine.Time = a.timestamp(line)
 		for i, part := range parts {
 			if part == "from" {
 				logLine.IpAddress = parts[i+1]
@@ -101,20 +122,141 @@ func (a App) parseLogLine(line string) notifier.LogLine {
 			if part == "user" || part == "for" {
 				logLine.Username = parts[i+1]
 			}
+			if (part == "Accepted" || part == "Failed") && i+1 < len(parts) {
+				logLine.AuthMethod = parts[i+1]
+			}
+			// Public key logins end with the key type and fingerprint, e.g.
+			// "ssh2: ED25519 SHA256:...".
+			if strings.HasPrefix(part, "SHA256:") || strings.HasPrefix(part, "MD5:") {
+				logLine.KeyFingerprint = part
+			}
 		}
 	}
 
 	return logLine
 }
 
-func (a App) processLine(line string, lineNumber int) error {
-	logLine := a.parseLogLine(line)
-	if !a.shouldSendMessage(logLine.EventType) {
+// parse parses the line with the custom parser and falls back to the built-in
+// parser.
+func (a App) parse(line string) notifier.LogLine {
+	if a.customParser != nil {
+		if logLine, ok := a.customParser.Parse(line); ok {
+			loginTime, at := a.timestamp(line)
+			if logLine.LoginTime == "" {
+				logLine.LoginTime = loginTime
+			}
+			if logLine.Time.IsZero() {
+				logLine.Time = at
+			}
+			return logLine
+		}
+	}
+	return a.parseLogLine(line)
+}
+
+// syslogTimeLayout is the layout of traditional syslog timestamps, e.g.
+// "Dec  1 10:00:00". They have no year.
+const syslogTimeLayout = "Jan 2 15:04:05"
+
+// timestamp returns the timestamp at the start of a log line and the time it
+// denotes, zero if it cannot be parsed. RFC 3339 timestamps of high precision
+// syslog formats and traditional syslog timestamps are understood. The latter
+// are placed in the current year, or the previous one if that puts them more
+// than a day in the future, e.g. December lines read in January.
+func (a App) timestamp(line string) (string, time.Time) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return line, time.Time{}
+	}
+	if at, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+		return parts[0], at
+	}
+	if len(parts) < 3 {
+		return strings.Join(parts, " "), time.Time{}
+	}
+	text := strings.Join(parts[:3], " ")
+	now := time.Now()
+	if a.now != nil {
+		now = a.now()
+	}
+	at, err := time.ParseInLocation(syslogTimeLayout, text, now.Location())
+	if err != nil {
+		return text, time.Time{}
+	}
+	inYear := func(year int) time.Time {
+		return time.Date(year, at.Month(), at.Day(), at.Hour(), at.Minute(), at.Second(), 0, now.Location())
+	}
+	if at = inYear(now.Year()); at.After(now.Add(24 * time.Hour)) {
+		at = inYear(now.Year() - 1)
+	}
+	return text, at
+}
+
+// runStages passes the log line through every stage in order and returns the
+// resulting log lines.
+func (a App) runStages(logLine notifier.LogLine) []notifier.LogLine {
+	return processStages(a.stages, logLine)
+}
+
+// processStages passes the log line through stages in order and returns the
+// resulting log lines. Canary events are never suppressed: a stage dropping
+// one passes it on unchanged.
+func processStages(stages []Stage, logLine notifier.LogLine) []notifier.LogLine {
+	logLines := []notifier.LogLine{logLine}
+	for _, s := range stages {
+		var next []notifier.LogLine
+		for _, l := range logLines {
+			processed := s.Process(l)
+			if l.Canary && !hasCanary(processed) {
+				processed = append([]notifier.LogLine{l}, processed...)
+			}
+			next = append(next, processed...)
+		}
+		logLines = next
+	}
+	return logLines
+}
+
+func hasCanary(logLines []notifier.LogLine) bool {
+	for _, l := range logLines {
+		if l.Canary {
+			return true
+		}
+	}
+	return false
+}
+
+// DryRun parses the line and passes it through the stages without sending
+// notifications. It returns the events that would be sent.
+func (a App) DryRun(line string) []notifier.LogLine {
+	for _, s := range a.stages {
+		if observer, ok := s.(lineObserver); ok {
+			observer.Observe(line)
+		}
+	}
+	logLine := a.parse(line)
+	if logLine.EventType == "" {
 		return nil
 	}
+	logLine.HostMachine = a.hostMachine
+	if logLine.Severity == "" {
+		logLine.Severity = notifier.DefaultSeverity(logLine.EventType)
+	}
+	return a.runStages(logLine)
+}
 
-	if err := a.notifier.Notify(logLine); err != nil {
-		return fmt.Errorf("error sending notification: %w", err)
+// errDelivery marks errors of lines whose notifications could not be sent.
+var errDelivery = errors.New("error sending notification")
+
+func (a App) processLine(line string, lineNumber int) error {
+	events := a.DryRun(line)
+	for _, event := range events {
+		if err := a.notifier.Notify(event); err != nil {
+			return fmt.Errorf("%w: %w", errDelivery, err)
+		}
+	}
+	if len(events) == 0 {
+		return nil
 	}
 
 	log.Info().Msg("notification message sent")
@@ -185,11 +327,17 @@ func (a App) Watch() error {
 				return fmt.Errorf("error getting last processed line: %w", err)
 			}
 
+			// A line whose notifications could not be sent stays unprocessed
+			// and is retried on the next iteration.
 			err = a.processNewLogLines(file, lastProcessedLine)
-			if err != nil {
+			switch {
+			case errors.Is(err, errDelivery):
+				log.Error().Err(err).Msg("failed sending notifications, retrying")
+			case err != nil:
 				return fmt.Errorf("error processing new log lines: %w", err)
+			default:
+				lastProcessedOffset = stat.Size()
 			}
-			lastProcessedOffset = stat.Size()
 		}
 
 		time.Sleep(time.Duration(a.watchSettings.SleepInterval) * time.Second)
@@ -1,124 +1,26 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/mgla96/ssh-watcher/internal/app/appfakes"
 	"github.com/mgla96/ssh-watcher/internal/config"
 	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/rules"
 )
 
-func TestLogWatcher_shouldSendMessage(t *testing.T) {
-	type fields struct {
-		watchSettings config.WatchSettings
-	}
-	type args struct {
-		eventType notifier.EventType
-	}
-	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   bool
-	}{
-		{
-			name: "test should send accepted login",
-			fields: fields{
-				watchSettings: config.WatchSettings{
-					AcceptedLogins:             true,
-					FailedLogins:               false,
-					FailedLoginInvalidUsername: false,
-				},
-			},
-			args: args{
-				eventType: notifier.LoggedIn,
-			},
-			want: true,
-		},
-		{
-			name: "test should send failed login",
-			fields: fields{
-				watchSettings: config.WatchSettings{
-					AcceptedLogins:             false,
-					FailedLogins:               true,
-					FailedLoginInvalidUsername: false,
-				},
-			},
-			args: args{
-				eventType: notifier.FailedLoginAttempt,
-			},
-			want: true,
-		},
-		{
-			name: "test should send failed login invalid username",
-			fields: fields{
-				watchSettings: config.WatchSettings{
-					AcceptedLogins:             false,
-					FailedLogins:               false,
-					FailedLoginInvalidUsername: true,
-				},
-			},
-			args: args{
-				eventType: notifier.FailedLoginAttemptInvalidUsername,
-			},
-			want: true,
-		},
-		{
-			name: "test should not send accepted login",
-			fields: fields{
-				watchSettings: config.WatchSettings{
-					AcceptedLogins:             false,
-					FailedLogins:               true,
-					FailedLoginInvalidUsername: true,
-				},
-			},
-			args: args{
-				eventType: notifier.LoggedIn,
-			},
-			want: false,
-		},
-		{
-			name: "test should not send failed login",
-			fields: fields{
-				watchSettings: config.WatchSettings{
-					AcceptedLogins:             true,
-					FailedLogins:               false,
-					FailedLoginInvalidUsername: true,
-				},
-			},
-			args: args{
-				eventType: notifier.FailedLoginAttempt,
-			},
-			want: false,
-		},
-		{
-			name: "test should not send failed login invalid username",
-			fields: fields{
-				watchSettings: config.WatchSettings{
-					AcceptedLogins:             true,
-					FailedLogins:               true,
-					FailedLoginInvalidUsername: false,
-				},
-			},
-			args: args{
-				eventType: notifier.FailedLoginAttemptInvalidUsername,
-			},
-			want: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			w := App{
-				watchSettings: tt.fields.watchSettings,
-			}
-			if got := w.shouldSendMessage(tt.args.eventType); got != tt.want {
-				t.Errorf("App.shouldSendMessage() = %v, want %v", got, tt.want)
-			}
-		})
+// watchSettingsRules returns the rules stage synthesized from settings.
+func watchSettingsRules(settings config.WatchSettings) Stage {
+	engine, err := rules.New(rules.FromWatchSettings(settings), nil)
+	if err != nil {
+		panic(err)
 	}
+	return engine
 }
 
 func Test_parseLogLine(t *testing.T) {
@@ -136,10 +38,25 @@ func Test_parseLogLine(t *testing.T) {
 				line: "Dec 1 10:0:0 fake sshd[0000]: Accepted password for foo from 1.2.3.4 port 57000 ssh2",
 			},
 			want: notifier.LogLine{
-				Username:  "foo",
-				IpAddress: "1.2.3.4",
-				LoginTime: "Dec 1",
-				EventType: notifier.LoggedIn,
+				Username:   "foo",
+				IpAddress:  "1.2.3.4",
+				LoginTime:  "Dec 1 10:0:0",
+				EventType:  notifier.LoggedIn,
+				AuthMethod: "password",
+			},
+		},
+		{
+			name: "accepted publickey",
+			args: args{
+				line: "Dec 1 10:0:0 fake sshd[0000]: Accepted publickey for foo from 1.2.3.4 port 57000 ssh2: ED25519 SHA256:Ab1+cd/EF",
+			},
+			want: notifier.LogLine{
+				Username:       "foo",
+				IpAddress:      "1.2.3.4",
+				LoginTime:      "Dec 1 10:0:0",
+				EventType:      notifier.LoggedIn,
+				AuthMethod:     "publickey",
+				KeyFingerprint: "SHA256:Ab1+cd/EF",
 			},
 		},
 		{
@@ -148,10 +65,11 @@ func Test_parseLogLine(t *testing.T) {
 				line: "Dec 1 10:0:0 fake sshd[0000]: Failed password for foo from 1.2.3.4 port 57000 ssh2",
 			},
 			want: notifier.LogLine{
-				Username:  "foo",
-				IpAddress: "1.2.3.4",
-				LoginTime: "Dec 1",
-				EventType: notifier.FailedLoginAttempt,
+				Username:   "foo",
+				IpAddress:  "1.2.3.4",
+				LoginTime:  "Dec 1 10:0:0",
+				EventType:  notifier.FailedLoginAttempt,
+				AuthMethod: "password",
 			},
 		},
 		{
@@ -160,10 +78,11 @@ func Test_parseLogLine(t *testing.T) {
 				line: "Dec 1 10:0:0 fake sshd[0000]: Failed password for invalid user bar from 1.2.3.4 port 57000 ssh2",
 			},
 			want: notifier.LogLine{
-				Username:  "bar",
-				IpAddress: "1.2.3.4",
-				LoginTime: "Dec 1",
-				EventType: notifier.FailedLoginAttemptInvalidUsername,
+				Username:   "bar",
+				IpAddress:  "1.2.3.4",
+				LoginTime:  "Dec 1 10:0:0",
+				EventType:  notifier.FailedLoginAttemptInvalidUsername,
+				AuthMethod: "password",
 			},
 		},
 		{
@@ -259,6 +178,7 @@ func TestApp_processLine(t *testing.T) {
 		watchSettings        config.WatchSettings
 		processedLineTracker processedLineTracker
 		file                 file
+		stages               []Stage
 	}
 	type args struct {
 		line       string
@@ -300,32 +220,56 @@ func TestApp_processLine(t *testing.T) {
 			fields: fields{
 				notifier: &appfakes.FakeNotifierClient{
 					NotifyStub: func(notifier.LogLine) error {
-						return nil
+						return fmt.Errorf("should not notify")
 					},
 				},
-				processedLineTracker: &appfakes.FakeProcessedLineTracker{
-					UpdateLastProcessedLineStub: func(int) error {
-						return nil
+				stages: []Stage{
+					watchSettingsRules(config.WatchSettings{
+						AcceptedLogins:             true,
+						FailedLogins:               true,
+						FailedLoginInvalidUsername: false,
+					}),
+				},
+			},
+			args: args{
+				line:       "Mar 30 00:00:00 foo sshd[5052]: Invalid user foo from x.x.x.x port xxx",
+				lineNumber: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "error notifying",
+			fields: fields{
+				notifier: &appfakes.FakeNotifierClient{
+					NotifyStub: func(notifier.LogLine) error {
+						return fmt.Errorf("error notifying")
 					},
 				},
 				watchSettings: config.WatchSettings{
 					AcceptedLogins:             true,
 					FailedLogins:               true,
-					FailedLoginInvalidUsername: false,
+					FailedLoginInvalidUsername: true,
 				},
 			},
 			args: args{
 				line:       "Mar 30 00:00:00 foo sshd[5052]: Invalid user foo from x.x.x.x port xxx",
 				lineNumber: 1,
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "error notifying",
+			name: "stage suppresses log line",
 			fields: fields{
 				notifier: &appfakes.FakeNotifierClient{
 					NotifyStub: func(notifier.LogLine) error {
-						return fmt.Errorf("error notifying")
+						return fmt.Errorf("should not notify")
+					},
+				},
+				stages: []Stage{
+					&appfakes.FakeStage{
+						ProcessStub: func(notifier.LogLine) []notifier.LogLine {
+							return nil
+						},
 					},
 				},
 				watchSettings: config.WatchSettings{
@@ -338,7 +282,40 @@ func TestApp_processLine(t *testing.T) {
 				line:       "Mar 30 00:00:00 foo sshd[5052]: Invalid user foo from x.x.x.x port xxx",
 				lineNumber: 1,
 			},
-			wantErr: true,
+			wantErr: false,
+		},
+		{
+			name: "stage emits detection for disabled event type",
+			fields: fields{
+				notifier: &appfakes.FakeNotifierClient{
+					NotifyStub: func(logLine notifier.LogLine) error {
+						if logLine.EventType != notifier.BruteForceDetected {
+							return fmt.Errorf("unexpected event type %s", logLine.EventType)
+						}
+						return nil
+					},
+				},
+				processedLineTracker: &appfakes.FakeProcessedLineTracker{
+					UpdateLastProcessedLineStub: func(int) error {
+						return nil
+					},
+				},
+				stages: []Stage{
+					&appfakes.FakeStage{
+						ProcessStub: func(logLine notifier.LogLine) []notifier.LogLine {
+							return []notifier.LogLine{logLine, {EventType: notifier.BruteForceDetected}}
+						},
+					},
+					watchSettingsRules(config.WatchSettings{
+						FailedLoginInvalidUsername: false,
+					}),
+				},
+			},
+			args: args{
+				line:       "Mar 30 00:00:00 foo sshd[5052]: Invalid user foo from x.x.x.x port xxx",
+				lineNumber: 1,
+			},
+			wantErr: false,
 		},
 		{
 			name: "error updating last processed line",
@@ -375,6 +352,7 @@ func TestApp_processLine(t *testing.T) {
 				watchSettings:        tt.fields.watchSettings,
 				processedLineTracker: tt.fields.processedLineTracker,
 				file:                 tt.fields.file,
+				stages:               tt.fields.stages,
 			}
 			if err := a.processLine(tt.args.line, tt.args.lineNumber); (err != nil) != tt.wantErr {
 				t.Errorf("App.processLine() error = %v, wantErr %v", err, tt.wantErr)
@@ -382,3 +360,150 @@ func TestApp_processLine(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_processLineDeliveryFailure(t *testing.T) {
+	client := &appfakes.FakeNotifierClient{}
+	client.NotifyReturns(fmt.Errorf("webhook unavailable"))
+	tracker := &appfakes.FakeProcessedLineTracker{}
+	a := App{notifier: client, processedLineTracker: tracker}
+
+	err := a.processLine("Mar 30 00:00:00 foo sshd[5052]: Invalid user foo from 1.2.3.4 port 22", 1)
+	if !errors.Is(err, errDelivery) {
+		t.Errorf("App.processLine() error = %v, want a delivery error", err)
+	}
+	if tracker.UpdateLastProcessedLineCallCount() != 0 {
+		t.Error("line marked processed, want it retried")
+	}
+}
+
+func TestApp_runStages(t *testing.T) {
+	duplicate := &appfakes.FakeStage{
+		ProcessStub: func(logLine notifier.LogLine) []notifier.LogLine {
+			return []notifier.LogLine{logLine, logLine}
+		},
+	}
+	tag := &appfakes.FakeStage{
+		ProcessStub: func(logLine notifier.LogLine) []notifier.LogLine {
+			logLine.HostMachine = "tagged"
+			return []notifier.LogLine{logLine}
+		},
+	}
+	a := App{stages: []Stage{duplicate, tag}}
+
+	got := a.runStages(notifier.LogLine{EventType: notifier.LoggedIn})
+	want := []notifier.LogLine{
+		{EventType: notifier.LoggedIn, HostMachine: "tagged"},
+		{EventType: notifier.LoggedIn, HostMachine: "tagged"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("App.runStages() = %v, want %v", got, want)
+	}
+	if tag.ProcessCallCount() != 2 {
+		t.Errorf("second stage called %d times, want 2", tag.ProcessCallCount())
+	}
+}
+
+func TestApp_runStagesKeepsCanaries(t *testing.T) {
+	suppress := &appfakes.FakeStage{}
+	detect := &appfakes.FakeStage{
+		ProcessStub: func(logLine notifier.LogLine) []notifier.LogLine {
+			return []notifier.LogLine{{EventType: notifier.BruteForceDetected}}
+		},
+	}
+	a := App{stages: []Stage{suppress, detect}}
+
+	canary := notifier.LogLine{Username: "oracle", EventType: notifier.FailedLoginAttemptInvalidUsername, Canary: true}
+	got := a.runStages(canary)
+	want := []notifier.LogLine{canary, {EventType: notifier.BruteForceDetected}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("App.runStages() = %v, want %v", got, want)
+	}
+	if got := a.runStages(notifier.LogLine{Username: "bob"}); len(got) != 0 {
+		t.Errorf("App.runStages() = %v, want other events suppressed", got)
+	}
+}
+
+func TestApp_timestamp(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		line     string
+		wantText string
+		want     time.Time
+	}{
+		{
+			name:     "syslog",
+			line:     "Jan  2 10:04:05 web-1 sshd[1]: Failed password for root from 1.2.3.4 port 22 ssh2",
+			wantText: "Jan 2 10:04:05",
+			want:     time.Date(2024, 1, 2, 10, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "syslog of the previous year",
+			line:     "Dec 31 23:59:59 web-1 sshd[1]: Failed password for root from 1.2.3.4 port 22 ssh2",
+			wantText: "Dec 31 23:59:59",
+			want:     time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name:     "rfc 3339",
+			line:     "2023-06-01T10:00:00.123456+02:00 web-1 sshd[1]: Failed password for root from 1.2.3.4 port 22 ssh2",
+			wantText: "2023-06-01T10:00:00.123456+02:00",
+			want:     time.Date(2023, 6, 1, 8, 0, 0, 123456000, time.UTC),
+		},
+		{
+			name:     "unparseable",
+			line:     "Dec 1 10:0:0 fake sshd[0000]: Accepted password for foo",
+			wantText: "Dec 1 10:0:0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := App{now: func() time.Time { return now }}
+			text, got := a.timestamp(tt.line)
+			if text != tt.wantText || !got.Equal(tt.want) {
+				t.Errorf("App.timestamp() = %q, %v, want %q, %v", text, got, tt.wantText, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_DryRun(t *testing.T) {
+	customParser := &appfakes.FakeLineParser{
+		ParseStub: func(line string) (notifier.LogLine, bool) {
+			if line != "Mar 30 00:00:00 foo sshd(pam_duo)[5052]: Duo denied alice" {
+				return notifier.LogLine{}, false
+			}
+			return notifier.LogLine{Username: "alice", EventType: "duo denied", Severity: notifier.SeverityHigh}, true
+		},
+	}
+	now := func() time.Time { return time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC) }
+	a := App{hostMachine: "foo", customParser: customParser, now: now}
+	loggedAt := time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want []notifier.LogLine
+	}{
+		{
+			name: "custom pattern",
+			line: "Mar 30 00:00:00 foo sshd(pam_duo)[5052]: Duo denied alice",
+			want: []notifier.LogLine{{Username: "alice", EventType: "duo denied", Severity: notifier.SeverityHigh, LoginTime: "Mar 30 00:00:00", Time: loggedAt, HostMachine: "foo"}},
+		},
+		{
+			name: "falls back to built-in parser",
+			line: "Mar 30 00:00:00 foo sshd[5052]: Invalid user bob from 1.2.3.4 port 22",
+			want: []notifier.LogLine{{Username: "bob", IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttemptInvalidUsername, Severity: notifier.SeverityLow, LoginTime: "Mar 30 00:00:00", Time: loggedAt, HostMachine: "foo"}},
+		},
+		{
+			name: "not an event",
+			line: "Mar 30 00:00:00 foo cron[1]: job started",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.DryRun(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("App.DryRun() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package appfakes
+
+import (
+	"sync"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+type FakeLineParser struct {
+	ParseStub        func(string) (notifier.LogLine, bool)
+	parseMutex       sync.RWMutex
+	parseArgsForCall []struct {
+		arg1 string
+	}
+	parseReturns struct {
+		result1 notifier.LogLine
+		result2 bool
+	}
+	parseReturnsOnCall map[int]struct {
+		result1 notifier.LogLine
+		result2 bool
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeLineParser) Parse(arg1 string) (notifier.LogLine, bool) {
+	fake.parseMutex.Lock()
+	ret, specificReturn := fake.parseReturnsOnCall[len(fake.parseArgsForCall)]
+	fake.parseArgsForCall = append(fake.parseArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ParseStub
+	fakeReturns := fake.parseReturns
+	fake.recordInvocation("Parse", []interface{}{arg1})
+	fake.parseMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLineParser) ParseCallCount() int {
+	fake.parseMutex.RLock()
+	defer fake.parseMutex.RUnlock()
+	return len(fake.parseArgsForCall)
+}
+
+func (fake *FakeLineParser) ParseCalls(stub func(string) (notifier.LogLine, bool)) {
+	fake.parseMutex.Lock()
+	defer fake.parseMutex.Unlock()
+	fake.ParseStub = stub
+}
+
+func (fake *FakeLineParser) ParseArgsForCall(i int) string {
+	fake.parseMutex.RLock()
+	defer fake.parseMutex.RUnlock()
+	argsForCall := fake.parseArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLineParser) ParseReturns(result1 notifier.LogLine, result2 bool) {
+	fake.parseMutex.Lock()
+	defer fake.parseMutex.Unlock()
+	fake.ParseStub = nil
+	fake.parseReturns = struct {
+		result1 notifier.LogLine
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeLineParser) ParseReturnsOnCall(i int, result1 notifier.LogLine, result2 bool) {
+	fake.parseMutex.Lock()
+	defer fake.parseMutex.Unlock()
+	fake.ParseStub = nil
+	if fake.parseReturnsOnCall == nil {
+		fake.parseReturnsOnCall = make(map[int]struct {
+			result1 notifier.LogLine
+			result2 bool
+		})
+	}
+	fake.parseReturnsOnCall[i] = struct {
+		result1 notifier.LogLine
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeLineParser) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.parseMutex.RLock()
+	defer fake.parseMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeLineParser) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
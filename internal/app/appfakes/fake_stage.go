@@ -0,0 +1,109 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package appfakes
+
+import (
+	"sync"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+type FakeStage struct {
+	ProcessStub        func(notifier.LogLine) []notifier.LogLine
+	processMutex       sync.RWMutex
+	processArgsForCall []struct {
+		arg1 notifier.LogLine
+	}
+	processReturns struct {
+		result1 []notifier.LogLine
+	}
+	processReturnsOnCall map[int]struct {
+		result1 []notifier.LogLine
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeStage) Process(arg1 notifier.LogLine) []notifier.LogLine {
+	fake.processMutex.Lock()
+	ret, specificReturn := fake.processReturnsOnCall[len(fake.processArgsForCall)]
+	fake.processArgsForCall = append(fake.processArgsForCall, struct {
+		arg1 notifier.LogLine
+	}{arg1})
+	stub := fake.ProcessStub
+	fakeReturns := fake.processReturns
+	fake.recordInvocation("Process", []interface{}{arg1})
+	fake.processMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeStage) ProcessCallCount() int {
+	fake.processMutex.RLock()
+	defer fake.processMutex.RUnlock()
+	return len(fake.processArgsForCall)
+}
+
+func (fake *FakeStage) ProcessCalls(stub func(notifier.LogLine) []notifier.LogLine) {
+	fake.processMutex.Lock()
+	defer fake.processMutex.Unlock()
+	fake.ProcessStub = stub
+}
+
+func (fake *FakeStage) ProcessArgsForCall(i int) notifier.LogLine {
+	fake.processMutex.RLock()
+	defer fake.processMutex.RUnlock()
+	argsForCall := fake.processArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeStage) ProcessReturns(result1 []notifier.LogLine) {
+	fake.processMutex.Lock()
+	defer fake.processMutex.Unlock()
+	fake.ProcessStub = nil
+	fake.processReturns = struct {
+		result1 []notifier.LogLine
+	}{result1}
+}
+
+func (fake *FakeStage) ProcessReturnsOnCall(i int, result1 []notifier.LogLine) {
+	fake.processMutex.Lock()
+	defer fake.processMutex.Unlock()
+	fake.ProcessStub = nil
+	if fake.processReturnsOnCall == nil {
+		fake.processReturnsOnCall = make(map[int]struct {
+			result1 []notifier.LogLine
+		})
+	}
+	fake.processReturnsOnCall[i] = struct {
+		result1 []notifier.LogLine
+	}{result1}
+}
+
+func (fake *FakeStage) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.processMutex.RLock()
+	defer fake.processMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeStage) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// NewStagedNotifier creates a notifier passing events through stages before
+// sending them to client. It lets events that do not come from the log file,
+// such as those of the integrity monitor, go through the same severity, rule
+// and silence stages as logged ones.
+func NewStagedNotifier(client notifierClient, stages ...Stage) StagedNotifier {
+	return StagedNotifier{
+		client: client,
+		stages: stages,
+	}
+}
+
+type StagedNotifier struct {
+	client notifierClient
+	stages []Stage
+}
+
+// Notify sends the log lines the stages return for logLine, which may be
+// none. Every log line is sent even if sending another one fails.
+func (s StagedNotifier) Notify(logLine notifier.LogLine) error {
+	var errs []error
+	for _, event := range processStages(s.stages, logLine) {
+		if err := s.client.Notify(event); err != nil {
+			errs = append(errs, fmt.Errorf("error sending notification: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mgla96/ssh-watcher/internal/app/appfakes"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestStagedNotifier_Notify(t *testing.T) {
+	escalate := &appfakes.FakeStage{
+		ProcessStub: func(logLine notifier.LogLine) []notifier.LogLine {
+			logLine.Severity = notifier.SeverityCritical
+			return []notifier.LogLine{logLine}
+		},
+	}
+	silence := &appfakes.FakeStage{
+		ProcessStub: func(logLine notifier.LogLine) []notifier.LogLine {
+			if logLine.HostMachine == "silenced" {
+				return nil
+			}
+			return []notifier.LogLine{logLine}
+		},
+	}
+
+	tests := []struct {
+		name      string
+		logLine   notifier.LogLine
+		notifyErr error
+		wantSent  []notifier.LogLine
+		wantErr   bool
+	}{
+		{
+			name:     "sends the processed event",
+			logLine:  notifier.LogLine{EventType: notifier.SshdConfigChanged, HostMachine: "web-1"},
+			wantSent: []notifier.LogLine{{EventType: notifier.SshdConfigChanged, HostMachine: "web-1", Severity: notifier.SeverityCritical}},
+		},
+		{
+			name:    "sends nothing when a stage suppresses the event",
+			logLine: notifier.LogLine{EventType: notifier.SshdConfigChanged, HostMachine: "silenced"},
+		},
+		{
+			name:      "returns send failures",
+			logLine:   notifier.LogLine{EventType: notifier.SshdConfigChanged, HostMachine: "web-1"},
+			notifyErr: fmt.Errorf("slack unavailable"),
+			wantSent:  []notifier.LogLine{{EventType: notifier.SshdConfigChanged, HostMachine: "web-1", Severity: notifier.SeverityCritical}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &appfakes.FakeNotifierClient{}
+			client.NotifyReturns(tt.notifyErr)
+			s := NewStagedNotifier(client, escalate, silence)
+
+			if err := s.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var sent []notifier.LogLine
+			for i := 0; i < client.NotifyCallCount(); i++ {
+				sent = append(sent, client.NotifyArgsForCall(i))
+			}
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package baseline learns the networks and keys each user logs in from and
+// flags logins from sources the user has not used before.
+package baseline
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// Granularity selects what makes a login source new.
+type Granularity string
+
+const (
+	// GranularityIP flags every IP address not seen before.
+	GranularityIP Granularity = "ip"
+	// GranularityNetwork flags IP addresses outside the networks seen before,
+	// /24 for IPv4 and /64 for IPv6 by default.
+	GranularityNetwork Granularity = "network"
+	// GranularityASN flags IP addresses announced by an autonomous system not
+	// seen before. It falls back to GranularityNetwork when the ASN of an
+	// address is unknown.
+	GranularityASN Granularity = "asn"
+)
+
+// store persists the baseline.
+type store interface {
+	Load() (Users, error)
+	Save(users Users) error
+}
+
+// asnLookup resolves the autonomous system number announcing an IP address.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . asnLookup
+type asnLookup interface {
+	ASN(ip netip.Addr) (uint, bool)
+}
+
+// Settings configures the baseline.
+type Settings struct {
+	// LearningPeriod is how long after the first login of a user sources are
+	// recorded without being flagged.
+	LearningPeriod time.Duration
+	// Retention is how long a source or key is remembered after it was last
+	// seen. Zero keeps entries forever.
+	Retention   time.Duration
+	Granularity Granularity
+	// IPv4PrefixLen and IPv6PrefixLen size the networks of GranularityNetwork.
+	IPv4PrefixLen int
+	IPv6PrefixLen int
+}
+
+// New creates a baseline stage. lookup may be nil when no ASN data is
+// available.
+func New(store store, lookup asnLookup, settings Settings, log zerolog.Logger) *Baseline {
+	return &Baseline{
+		store:    store,
+		lookup:   lookup,
+		settings: settings,
+		now:      time.Now,
+		log:      log,
+	}
+}
+
+// Baseline is an app stage recording the sources and keys of successful
+// logins per user. Logins are passed on, followed by a NewSourceForUser event
+// when the user logged in from a new source or with a new key after its
+// learning period.
+//
+// The baseline is read from and written to the store on every login so
+// changes made with the baseline command apply without a restart.
+type Baseline struct {
+	store    store
+	lookup   asnLookup
+	settings Settings
+	now      func() time.Time
+	log      zerolog.Logger
+	mu       sync.Mutex
+}
+
+// Network returns the network of ip used by GranularityNetwork.
+func (b *Baseline) Network(ip netip.Addr) netip.Prefix {
+	bits := b.settings.IPv4PrefixLen
+	if ip.Is6() {
+		bits = b.settings.IPv6PrefixLen
+	}
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return netip.PrefixFrom(ip, ip.BitLen())
+	}
+	return prefix
+}
+
+// Process implements the app stage interface.
+func (b *Baseline) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.EventType != notifier.LoggedIn || logLine.Username == "" {
+		return []notifier.LogLine{logLine}
+	}
+	ip, err := netip.ParseAddr(logLine.IpAddress)
+	if err != nil {
+		return []notifier.LogLine{logLine}
+	}
+	ip = ip.Unmap()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	users, err := b.store.Load()
+	if err != nil {
+		b.log.Error().Err(err).Msg("failed loading baseline")
+		return []notifier.LogLine{logLine}
+	}
+
+	now := logLine.OccurredAt(b.now())
+	user, ok := users[logLine.Username]
+	if !ok {
+		user = newUser(now)
+		users[logLine.Username] = user
+	}
+	if b.settings.Retention > 0 {
+		user.Prune(now.Add(-b.settings.Retention))
+	}
+
+	novelties := b.record(user, ip, logLine.KeyFingerprint, now)
+	if err := b.store.Save(users); err != nil {
+		b.log.Error().Err(err).Msg("failed saving baseline")
+	}
+
+	if len(novelties) == 0 || now.Before(user.FirstSeen.Add(b.settings.LearningPeriod)) {
+		return []notifier.LogLine{logLine}
+	}
+	newSource := logLine
+	newSource.EventType = notifier.NewSourceForUser
+	newSource.Severity = notifier.DefaultSeverity(notifier.NewSourceForUser)
+	newSource.Reason = strings.Join(novelties, ", ")
+	return []notifier.LogLine{logLine, newSource}
+}
+
+// record marks the source and key of a login as seen at now and returns a
+// description of each that is new at the configured granularity.
+func (b *Baseline) record(user *User, ip netip.Addr, fingerprint string, now time.Time) []string {
+	var novelties []string
+
+	network := b.Network(ip).String()
+	asn, asnKnown := uint(0), false
+	if b.lookup != nil {
+		asn, asnKnown = b.lookup.ASN(ip)
+	}
+	_, ipSeen := user.IPs[ip.String()]
+	_, networkSeen := user.Networks[network]
+	switch {
+	case b.settings.Granularity == GranularityIP && !ipSeen:
+		novelties = append(novelties, fmt.Sprintf("first login from IP %s", ip))
+	case b.settings.Granularity == GranularityASN && asnKnown:
+		if _, seen := user.ASNs[strconv.FormatUint(uint64(asn), 10)]; !seen {
+			novelties = append(novelties, fmt.Sprintf("first login from AS%d", asn))
+		}
+	case b.settings.Granularity != GranularityIP && !networkSeen:
+		novelties = append(novelties, fmt.Sprintf("first login from network %s", network))
+	}
+	if fingerprint != "" {
+		if _, seen := user.KeyFingerprints[fingerprint]; !seen {
+			novelties = append(novelties, fmt.Sprintf("first login with key %s", fingerprint))
+		}
+		user.KeyFingerprints[fingerprint] = now
+	}
+
+	user.IPs[ip.String()] = now
+	user.Networks[network] = now
+	if asnKnown {
+		user.ASNs[strconv.FormatUint(uint64(asn), 10)] = now
+	}
+	return novelties
+}
@@ -0,0 +1,194 @@
+package baseline
+
+import (
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/baseline/baselinefakes"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+var start = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+func login(user, ip, fingerprint string) notifier.LogLine {
+	return notifier.LogLine{
+		Username:       user,
+		IpAddress:      ip,
+		EventType:      notifier.LoggedIn,
+		Severity:       notifier.SeverityMedium,
+		KeyFingerprint: fingerprint,
+	}
+}
+
+func newTestBaseline(t *testing.T, lookup asnLookup, settings Settings) (*Baseline, FileStore) {
+	t.Helper()
+	store := NewFileStore(filepath.Join(t.TempDir(), "baseline.json"))
+	settings.IPv4PrefixLen, settings.IPv6PrefixLen = 24, 64
+	b := New(store, lookup, settings, zerolog.Nop())
+	b.now = func() time.Time { return start }
+	return b, store
+}
+
+// reasons returns the reasons of the NewSourceForUser events in logLines.
+func reasons(logLines []notifier.LogLine) []string {
+	var got []string
+	for _, logLine := range logLines {
+		if logLine.EventType == notifier.NewSourceForUser {
+			got = append(got, logLine.Reason)
+		}
+	}
+	return got
+}
+
+func TestBaseline_Process(t *testing.T) {
+	tests := []struct {
+		name        string
+		granularity Granularity
+		asns        map[string]uint
+		known       []notifier.LogLine
+		login       notifier.LogLine
+		want        []string
+	}{
+		{
+			name:        "same network is not new",
+			granularity: GranularityNetwork,
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "")},
+			login:       login("alice", "1.2.3.99", ""),
+		},
+		{
+			name:        "new network",
+			granularity: GranularityNetwork,
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "")},
+			login:       login("alice", "5.6.7.8", ""),
+			want:        []string{"first login from network 5.6.7.0/24"},
+		},
+		{
+			name:        "ipv6 network",
+			granularity: GranularityNetwork,
+			known:       []notifier.LogLine{login("alice", "2001:db8:0:1::1", "")},
+			login:       login("alice", "2001:db8:0:1::2", ""),
+		},
+		{
+			name:        "new ip",
+			granularity: GranularityIP,
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "")},
+			login:       login("alice", "1.2.3.5", ""),
+			want:        []string{"first login from IP 1.2.3.5"},
+		},
+		{
+			name:        "same asn is not new",
+			granularity: GranularityASN,
+			asns:        map[string]uint{"1.2.3.4": 64500, "9.9.9.9": 64500},
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "")},
+			login:       login("alice", "9.9.9.9", ""),
+		},
+		{
+			name:        "new asn",
+			granularity: GranularityASN,
+			asns:        map[string]uint{"1.2.3.4": 64500, "9.9.9.9": 64501},
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "")},
+			login:       login("alice", "9.9.9.9", ""),
+			want:        []string{"first login from AS64501"},
+		},
+		{
+			name:        "unknown asn falls back to network",
+			granularity: GranularityASN,
+			asns:        map[string]uint{"1.2.3.4": 64500},
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "")},
+			login:       login("alice", "9.9.9.9", ""),
+			want:        []string{"first login from network 9.9.9.0/24"},
+		},
+		{
+			name:        "new key",
+			granularity: GranularityNetwork,
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", "SHA256:old")},
+			login:       login("alice", "1.2.3.4", "SHA256:new"),
+			want:        []string{"first login with key SHA256:new"},
+		},
+		{
+			name:        "sources are per user",
+			granularity: GranularityNetwork,
+			known:       []notifier.LogLine{login("alice", "1.2.3.4", ""), login("bob", "5.6.7.8", "")},
+			login:       login("alice", "5.6.7.8", ""),
+			want:        []string{"first login from network 5.6.7.0/24"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := &baselinefakes.FakeAsnLookup{
+				ASNStub: func(ip netip.Addr) (uint, bool) {
+					asn, ok := tt.asns[ip.String()]
+					return asn, ok
+				},
+			}
+			b, _ := newTestBaseline(t, lookup, Settings{LearningPeriod: time.Hour, Granularity: tt.granularity})
+
+			for _, known := range tt.known {
+				if got := b.Process(known); !reflect.DeepEqual(got, []notifier.LogLine{known}) {
+					t.Fatalf("Process() during learning period = %v, want login passed through", got)
+				}
+			}
+			b.now = func() time.Time { return start.Add(2 * time.Hour) }
+
+			got := b.Process(tt.login)
+			if !reflect.DeepEqual(got[0], tt.login) {
+				t.Errorf("Process() = %v, want login passed through first", got)
+			}
+			if !reflect.DeepEqual(reasons(got), tt.want) {
+				t.Errorf("Process() new sources = %v, want %v", reasons(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseline_ProcessRetention(t *testing.T) {
+	b, store := newTestBaseline(t, nil, Settings{Retention: 30 * 24 * time.Hour, Granularity: GranularityNetwork})
+
+	b.Process(login("alice", "1.2.3.4", ""))
+	b.now = func() time.Time { return start.Add(10 * 24 * time.Hour) }
+	b.Process(login("alice", "5.6.7.8", ""))
+
+	b.now = func() time.Time { return start.Add(35 * 24 * time.Hour) }
+	if got := reasons(b.Process(login("alice", "5.6.7.9", ""))); len(got) != 0 {
+		t.Errorf("Process() recently seen network = %v, want not new", got)
+	}
+	if got := reasons(b.Process(login("alice", "1.2.3.4", ""))); len(got) != 1 {
+		t.Errorf("Process() decayed network = %v, want new", got)
+	}
+
+	users, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := users["alice"].Networks["5.6.7.0/24"]; !ok {
+		t.Errorf("persisted networks = %v, want 5.6.7.0/24", users["alice"].Networks)
+	}
+}
+
+func TestUsers_Prune(t *testing.T) {
+	users := Users{
+		"alice": {
+			FirstSeen: start,
+			IPs:       map[string]time.Time{"1.2.3.4": start, "5.6.7.8": start.Add(time.Hour)},
+			Networks:  map[string]time.Time{"1.2.3.0/24": start},
+		},
+		"bob": {
+			FirstSeen: start,
+			IPs:       map[string]time.Time{"9.9.9.9": start},
+		},
+	}
+
+	if removed := users.Prune(start.Add(time.Minute)); removed != 1 {
+		t.Errorf("Prune() removed = %d, want 1", removed)
+	}
+	if _, ok := users["bob"]; ok {
+		t.Errorf("Prune() kept user without entries")
+	}
+	if want := map[string]time.Time{"5.6.7.8": start.Add(time.Hour)}; !reflect.DeepEqual(users["alice"].IPs, want) {
+		t.Errorf("Prune() IPs = %v, want %v", users["alice"].IPs, want)
+	}
+}
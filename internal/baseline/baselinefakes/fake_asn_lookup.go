@@ -0,0 +1,113 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package baselinefakes
+
+import (
+	"net/netip"
+	"sync"
+)
+
+type FakeAsnLookup struct {
+	ASNStub        func(netip.Addr) (uint, bool)
+	aSNMutex       sync.RWMutex
+	aSNArgsForCall []struct {
+		arg1 netip.Addr
+	}
+	aSNReturns struct {
+		result1 uint
+		result2 bool
+	}
+	aSNReturnsOnCall map[int]struct {
+		result1 uint
+		result2 bool
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeAsnLookup) ASN(arg1 netip.Addr) (uint, bool) {
+	fake.aSNMutex.Lock()
+	ret, specificReturn := fake.aSNReturnsOnCall[len(fake.aSNArgsForCall)]
+	fake.aSNArgsForCall = append(fake.aSNArgsForCall, struct {
+		arg1 netip.Addr
+	}{arg1})
+	stub := fake.ASNStub
+	fakeReturns := fake.aSNReturns
+	fake.recordInvocation("ASN", []interface{}{arg1})
+	fake.aSNMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeAsnLookup) ASNCallCount() int {
+	fake.aSNMutex.RLock()
+	defer fake.aSNMutex.RUnlock()
+	return len(fake.aSNArgsForCall)
+}
+
+func (fake *FakeAsnLookup) ASNCalls(stub func(netip.Addr) (uint, bool)) {
+	fake.aSNMutex.Lock()
+	defer fake.aSNMutex.Unlock()
+	fake.ASNStub = stub
+}
+
+func (fake *FakeAsnLookup) ASNArgsForCall(i int) netip.Addr {
+	fake.aSNMutex.RLock()
+	defer fake.aSNMutex.RUnlock()
+	argsForCall := fake.aSNArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeAsnLookup) ASNReturns(result1 uint, result2 bool) {
+	fake.aSNMutex.Lock()
+	defer fake.aSNMutex.Unlock()
+	fake.ASNStub = nil
+	fake.aSNReturns = struct {
+		result1 uint
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeAsnLookup) ASNReturnsOnCall(i int, result1 uint, result2 bool) {
+	fake.aSNMutex.Lock()
+	defer fake.aSNMutex.Unlock()
+	fake.ASNStub = nil
+	if fake.aSNReturnsOnCall == nil {
+		fake.aSNReturnsOnCall = make(map[int]struct {
+			result1 uint
+			result2 bool
+		})
+	}
+	fake.aSNReturnsOnCall[i] = struct {
+		result1 uint
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeAsnLookup) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.aSNMutex.RLock()
+	defer fake.aSNMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeAsnLookup) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
@@ -0,0 +1,98 @@
+package baseline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+)
+
+// Users maps usernames to their login history.
+type Users map[string]*User
+
+// User is the login history of a user. Every map holds when its key was last
+// seen in a successful login.
+type User struct {
+	// FirstSeen is when the first login of the user was recorded. The user is
+	// in its learning period until FirstSeen plus the learning period.
+	FirstSeen       time.Time            `json:"first_seen"`
+	IPs             map[string]time.Time `json:"ips"`
+	Networks        map[string]time.Time `json:"networks"`
+	ASNs            map[string]time.Time `json:"asns,omitempty"`
+	KeyFingerprints map[string]time.Time `json:"key_fingerprints,omitempty"`
+}
+
+func newUser(now time.Time) *User {
+	return &User{
+		FirstSeen:       now,
+		IPs:             map[string]time.Time{},
+		Networks:        map[string]time.Time{},
+		ASNs:            map[string]time.Time{},
+		KeyFingerprints: map[string]time.Time{},
+	}
+}
+
+// Prune drops entries last seen before cutoff and reports whether the user
+// has no entries left.
+func (u *User) Prune(cutoff time.Time) bool {
+	empty := true
+	for _, seen := range []map[string]time.Time{u.IPs, u.Networks, u.ASNs, u.KeyFingerprints} {
+		for key, lastSeen := range seen {
+			if lastSeen.Before(cutoff) {
+				delete(seen, key)
+			}
+		}
+		if len(seen) > 0 {
+			empty = false
+		}
+	}
+	return empty
+}
+
+// Prune drops entries last seen before cutoff and users left without
+// entries. It returns the number of users removed.
+func (u Users) Prune(cutoff time.Time) int {
+	removed := 0
+	for name, user := range u {
+		if user.Prune(cutoff) {
+			delete(u, name)
+			removed++
+		}
+	}
+	return removed
+}
+
+// FileStore persists the baseline as JSON.
+type FileStore struct {
+	StateFilePath string
+}
+
+func NewFileStore(stateFilePath string) FileStore {
+	return FileStore{
+		StateFilePath: stateFilePath,
+	}
+}
+
+// Load returns the persisted baseline. A missing file is an empty baseline.
+func (f FileStore) Load() (Users, error) {
+	users := Users{}
+	if err := statefile.Load(f.StateFilePath, &users); err != nil {
+		return nil, fmt.Errorf("failed loading baseline: %w", err)
+	}
+	for _, user := range users {
+		for _, seen := range []*map[string]time.Time{&user.IPs, &user.Networks, &user.ASNs, &user.KeyFingerprints} {
+			if *seen == nil {
+				*seen = map[string]time.Time{}
+			}
+		}
+	}
+	return users, nil
+}
+
+// Save replaces the persisted baseline.
+func (f FileStore) Save(users Users) error {
+	if err := statefile.Save(f.StateFilePath, users); err != nil {
+		return fmt.Errorf("failed saving baseline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package canary flags authentication attempts against decoy usernames and
+// keys nobody legitimate uses.
+package canary
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/mgla96/ssh-watcher/internal/lru"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// Settings configures the canaries.
+type Settings struct {
+	// Usernames are the canary usernames.
+	Usernames []string
+	// KeyFingerprints are the fingerprints of canary public keys, e.g.
+	// SHA256:... as logged by sshd.
+	KeyFingerprints []string
+	// ContextLines is how many recent raw log lines mentioning the source IP
+	// address are attached to canary events.
+	ContextLines int
+	// MaxTrackedSources bounds the number of source IP addresses whose recent
+	// log lines are kept.
+	MaxTrackedSources int
+}
+
+// New creates the canary stage.
+func New(settings Settings) *Canary {
+	c := &Canary{
+		settings:     settings,
+		usernames:    map[string]bool{},
+		fingerprints: map[string]bool{},
+		sources:      lru.New[string, *source](settings.MaxTrackedSources),
+	}
+	for _, username := range settings.Usernames {
+		c.usernames[username] = true
+	}
+	for _, fingerprint := range settings.KeyFingerprints {
+		c.fingerprints[fingerprint] = true
+	}
+	return c
+}
+
+// Canary is an app stage marking every authentication attempt against a
+// canary username or key as a critical canary event carrying the recent raw
+// log lines of its source. It observes every raw log line to keep that
+// context.
+type Canary struct {
+	settings     Settings
+	usernames    map[string]bool
+	fingerprints map[string]bool
+	sources      *lru.Cache[string, *source]
+	mu           sync.Mutex
+}
+
+// source holds the recent log lines mentioning an IP address.
+type source struct {
+	lines []string
+}
+
+// Observe records the raw log line as context of the IP addresses it
+// mentions.
+func (c *Canary) Observe(line string) {
+	if c.settings.ContextLines <= 0 {
+		return
+	}
+	ips := ipAddresses(line)
+	if len(ips) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ip := range ips {
+		s := c.sources.GetOrAdd(ip, func() *source { return &source{} })
+		s.lines = append(s.lines, line)
+		if len(s.lines) > c.settings.ContextLines {
+			s.lines = s.lines[len(s.lines)-c.settings.ContextLines:]
+		}
+	}
+}
+
+// Process implements the app stage interface.
+func (c *Canary) Process(logLine notifier.LogLine) []notifier.LogLine {
+	var reason string
+	switch {
+	case c.usernames[logLine.Username]:
+		reason = fmt.Sprintf("canary username %s used", logLine.Username)
+	case logLine.KeyFingerprint != "" && c.fingerprints[logLine.KeyFingerprint]:
+		reason = fmt.Sprintf("canary key %s used", logLine.KeyFingerprint)
+	default:
+		return []notifier.LogLine{logLine}
+	}
+
+	logLine.Canary = true
+	logLine.Severity = notifier.SeverityCritical
+	logLine.Reason = reason
+	if logLine.IpAddress != "" {
+		c.mu.Lock()
+		if s, ok := c.sources.Get(logLine.IpAddress); ok {
+			logLine.RawLines = append([]string(nil), s.lines...)
+		}
+		c.mu.Unlock()
+	}
+	return []notifier.LogLine{logLine}
+}
+
+// ipAddresses returns the IP addresses mentioned in the line.
+func ipAddresses(line string) []string {
+	var ips []string
+	for _, field := range strings.Fields(line) {
+		field = strings.Trim(field, "[](),;")
+		if !strings.ContainsAny(field, ".:") {
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			// e.g. "1.2.3.4:" or "[1.2.3.4]:"
+			if addr, err = netip.ParseAddr(strings.Trim(strings.TrimSuffix(field, ":"), "[]")); err != nil {
+				continue
+			}
+		}
+		ips = append(ips, addr.Unmap().String())
+	}
+	return ips
+}
@@ -0,0 +1,100 @@
+package canary
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestCanary_Process(t *testing.T) {
+	tests := []struct {
+		name       string
+		logLine    notifier.LogLine
+		wantCanary bool
+		wantReason string
+	}{
+		{
+			name:       "canary username",
+			logLine:    notifier.LogLine{Username: "oracle", IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttemptInvalidUsername, Severity: notifier.SeverityLow},
+			wantCanary: true,
+			wantReason: "canary username oracle used",
+		},
+		{
+			name:       "canary key",
+			logLine:    notifier.LogLine{Username: "deploy", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, KeyFingerprint: "SHA256:decoy", Severity: notifier.SeverityMedium},
+			wantCanary: true,
+			wantReason: "canary key SHA256:decoy used",
+		},
+		{
+			name:    "other user",
+			logLine: notifier.LogLine{Username: "deploy", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, KeyFingerprint: "SHA256:known", Severity: notifier.SeverityMedium},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Settings{
+				Usernames:       []string{"oracle", "backup-admin"},
+				KeyFingerprints: []string{"SHA256:decoy"},
+				ContextLines:    2,
+			})
+			c.Observe("Jan  1 03:00:00 web-1 sshd[1]: Connection from 1.2.3.4 port 50000 on 10.0.0.1 port 22")
+			c.Observe("Jan  1 03:00:01 web-1 sshd[1]: Received disconnect from 5.6.7.8 port 50001:11: Bye [preauth]")
+			c.Observe("Jan  1 03:00:02 web-1 sshd[1]: Invalid user oracle from 1.2.3.4 port 50000")
+			c.Observe("Jan  1 03:00:03 web-1 sshd[1]: Connection closed by invalid user oracle 1.2.3.4 port 50000 [preauth]")
+
+			got := c.Process(tt.logLine)
+			if !tt.wantCanary {
+				if !reflect.DeepEqual(got, []notifier.LogLine{tt.logLine}) {
+					t.Errorf("Process() = %v, want log line passed through", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("Process() = %v, want one log line", got)
+			}
+			event := got[0]
+			if !event.Canary || event.Severity != notifier.SeverityCritical || event.Reason != tt.wantReason {
+				t.Errorf("Process() = %+v, want critical canary event with reason %q", event, tt.wantReason)
+			}
+			wantLines := []string{
+				"Jan  1 03:00:02 web-1 sshd[1]: Invalid user oracle from 1.2.3.4 port 50000",
+				"Jan  1 03:00:03 web-1 sshd[1]: Connection closed by invalid user oracle 1.2.3.4 port 50000 [preauth]",
+			}
+			if !reflect.DeepEqual(event.RawLines, wantLines) {
+				t.Errorf("RawLines = %q, want the last 2 lines of 1.2.3.4", event.RawLines)
+			}
+		})
+	}
+}
+
+func TestCanary_ObserveEvictsOldestSource(t *testing.T) {
+	c := New(Settings{Usernames: []string{"oracle"}, ContextLines: 5, MaxTrackedSources: 2})
+	c.Observe("sshd[1]: Connection from 1.1.1.1 port 1")
+	c.Observe("sshd[1]: Connection from 2.2.2.2 port 1")
+	c.Observe("sshd[1]: Connection from 1.1.1.1 port 2")
+	c.Observe("sshd[1]: Connection from 3.3.3.3 port 1")
+
+	if _, ok := c.sources.Get("2.2.2.2"); ok || c.sources.Len() != 2 {
+		t.Errorf("tracked sources = %d, want 2.2.2.2 evicted", c.sources.Len())
+	}
+}
+
+func Test_ipAddresses(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "Accepted publickey for alice from 1.2.3.4 port 22 ssh2", want: []string{"1.2.3.4"}},
+		{line: "Connection from 2001:db8::1 port 50000 on ::1 port 22", want: []string{"2001:db8::1", "::1"}},
+		{line: "Disconnected from authenticating user root [1.2.3.4]: port 22", want: []string{"1.2.3.4"}},
+		{line: "pam_unix(sshd:session): session opened for user alice by (uid=0)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := ipAddresses(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ipAddresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
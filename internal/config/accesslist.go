@@ -0,0 +1,9 @@
+package config
+
+type AccessList struct {
+	// File is the optional location of a JSON file with allow and deny rules.
+	File string
+	// ReloadInterval is how often the file and the lists it references are
+	// checked for changes.
+	ReloadInterval Duration `split_words:"true" default:"30s"`
+}
@@ -0,0 +1,78 @@
+package config
+
+import "fmt"
+
+// ResponderBackend names the firewall the active responder bans IPs with.
+type ResponderBackend string
+
+const (
+	NftablesBackend ResponderBackend = "nftables"
+	IpsetBackend    ResponderBackend = "ipset"
+	IptablesBackend ResponderBackend = "iptables"
+)
+
+type ActiveResponse struct {
+	// Enabled turns on banning the source IP address of trigger events.
+	Enabled bool `default:"false"`
+	// Backend selects the firewall bans are applied with.
+	Backend ResponderBackend `default:"nftables"`
+	// BanDuration is how long an IP address stays banned.
+	BanDuration Duration `split_words:"true" default:"1h"`
+	// Triggers are the event types whose source IP address is banned.
+	Triggers []string `default:"brute force detected"`
+	// TriggerRules are the names of access list rules whose matches are
+	// banned, e.g. a deny rule listing hostile networks.
+	TriggerRules []string `split_words:"true"`
+	// BanDenylisted bans the source of events matching a deny rule of the
+	// access list or listed in a threat feed.
+	BanDenylisted bool `split_words:"true" default:"false"`
+	// Allowlist holds IP addresses and CIDR ranges that are never banned.
+	Allowlist []string
+	// UnbanOnShutdown lifts all bans when ssh-watcher stops. Otherwise bans
+	// are kept and lifted on schedule after the next start.
+	UnbanOnShutdown bool `split_words:"true" default:"false"`
+	// StateFilePath is the location of the file active bans are persisted in.
+	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/bans.json"`
+	// CheckInterval is how often expired bans are lifted.
+	CheckInterval Duration `split_words:"true" default:"30s"`
+	Nftables      Nftables
+	Ipset         Ipset
+	Iptables      Iptables
+}
+
+type Nftables struct {
+	Family string `default:"inet"`
+	Table  string `default:"filter"`
+	// Set and Set6 are the IPv4 and IPv6 address sets banned addresses are
+	// added to. They should have the timeout flag.
+	Set  string `default:"ssh_watcher_banned"`
+	Set6 string `default:"ssh_watcher_banned6"`
+}
+
+type Ipset struct {
+	Set  string `default:"ssh-watcher-banned"`
+	Set6 string `default:"ssh-watcher-banned6"`
+}
+
+type Iptables struct {
+	// Chain is the chain DROP rules for banned addresses are inserted into.
+	Chain string `default:"INPUT"`
+}
+
+func (a ActiveResponse) validate() error {
+	if !a.Enabled {
+		return nil
+	}
+	switch a.Backend {
+	case NftablesBackend, IpsetBackend, IptablesBackend:
+	default:
+		return fmt.Errorf("unknown active response backend %q", a.Backend)
+	}
+	if a.BanDuration.Duration <= 0 {
+		return fmt.Errorf("%s_ACTIVE_RESPONSE_BAN_DURATION must be positive", ServicePrefix)
+	}
+	if a.CheckInterval.Duration <= 0 {
+		return fmt.Errorf("%s_ACTIVE_RESPONSE_CHECK_INTERVAL must be positive", ServicePrefix)
+	}
+	return nil
+}
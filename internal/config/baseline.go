@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Baseline struct {
+	// Enabled turns on recording the sources of logins per user and flagging
+	// logins from new sources.
+	Enabled bool `default:"false"`
+	// StateFilePath is the location of the file the baseline is persisted in.
+	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/baseline.json"`
+	// LearningPeriod is how long after the first login of a user its sources
+	// are recorded without being flagged.
+	LearningPeriod Duration `split_words:"true" default:"336h"`
+	// Retention is how long a source or key is remembered after it was last
+	// seen. Zero keeps entries forever.
+	Retention Duration `default:"2160h"`
+	// Granularity is one of ip, network or asn and selects what makes a
+	// source new.
+	Granularity string `default:"network"`
+	// Ipv4PrefixLen and Ipv6PrefixLen size the networks compared with the
+	// network granularity.
+	Ipv4PrefixLen int `split_words:"true" default:"24"`
+	Ipv6PrefixLen int `split_words:"true" default:"64"`
+}
+
+// NewBaseline loads only the baseline settings, for commands that do not
+// need a notifier.
+func NewBaseline() (*Baseline, error) {
+	cfg := Baseline{}
+	if err := envconfig.Process(ServicePrefix+"_BASELINE", &cfg); err != nil {
+		return nil, fmt.Errorf("failed processing baseline config: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (b Baseline) validate() error {
+	switch b.Granularity {
+	case "ip", "network", "asn":
+	default:
+		return fmt.Errorf("unknown baseline granularity %q", b.Granularity)
+	}
+	if b.Ipv4PrefixLen < 0 || b.Ipv4PrefixLen > 32 || b.Ipv6PrefixLen < 0 || b.Ipv6PrefixLen > 128 {
+		return fmt.Errorf("invalid baseline prefix length")
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package config
+
+type Canary struct {
+	// Usernames are decoy usernames nobody legitimate uses. Any
+	// authentication attempt against them is a critical canary event.
+	Usernames []string
+	// KeyFingerprints are the fingerprints of decoy public keys, e.g.
+	// SHA256:..., whose use is a critical canary event.
+	KeyFingerprints []string `split_words:"true"`
+	// ContextLines is how many recent log lines mentioning the source IP
+	// address are attached to canary events.
+	ContextLines int `split_words:"true" default:"50"`
+	// MaxTrackedSources bounds the number of source IP addresses whose recent
+	// log lines are kept.
+	MaxTrackedSources int `split_words:"true" default:"10000"`
+}
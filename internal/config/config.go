@@ -9,23 +9,76 @@ import (
 // ServicePrefix - app specific env vars have this prefix.
 const ServicePrefix = "WR"
 
-type Slack struct {
-	WebhookUrl string `split_words:"true" required:"true"`
-	Channel    string `split_words:"true"  default:"#ssh-alerts"`
-	Username   string `split_words:"true"  default:"poe-ssh-bot"`
-	Icon       string `split_words:"true"  default:":ghost:"`
-}
-
 type Config struct {
 	HostMachineName string `split_words:"true" required:"true"`
-	Slack           *Slack
-	WatchSettings   WatchSettings `split_words:"true"`
+	// Notifier selects the backend alerts are sent to.
+	Notifier Notifier `default:"slack"`
+	// MinSeverity is the lowest severity of events sent to the notifier
+	// selected with Notifier. Empty sends events of every severity.
+	MinSeverity  string `split_words:"true"`
+	Slack        *Slack
+	PagerDuty    *PagerDuty `split_words:"true"`
+	Opsgenie     *Opsgenie
+	Alertmanager *Alertmanager
+	Telegram     *Telegram
+	Matrix       *Matrix
+	Ntfy         *Ntfy
+	Gotify       *Gotify
+	Exec         *Exec
+	// RoutingFile is the optional location of a JSON file defining additional
+	// sinks and the rules routing events to them.
+	RoutingFile   string        `split_words:"true"`
+	WatchSettings WatchSettings `split_words:"true"`
+	// Digest sends events as a periodic summary instead of one by one.
+	Digest Digest
+	// Dedup and RateLimit limit the notifications sent.
+	Dedup     Dedup
+	RateLimit RateLimit `split_words:"true"`
+	// Patterns parse log lines the built-in parser does not know.
+	Patterns Patterns
+	// Geoip enriches events with the location of their source.
+	Geoip Geoip
+	// ThreatIntel tags events whose source is listed in threat feeds.
+	ThreatIntel ThreatIntel `split_words:"true"`
+	// KeyIndex attributes public key logins to the owner of the key.
+	KeyIndex KeyIndex `split_words:"true"`
+	// Canary flags authentication attempts against decoy usernames and keys.
+	Canary Canary
+	// AccessList suppresses or escalates events of listed users and sources.
+	AccessList         AccessList         `split_words:"true"`
+	BruteForce         BruteForce         `split_words:"true"`
+	PasswordSpray      PasswordSpray      `split_words:"true"`
+	DistributedAttack  DistributedAttack  `split_words:"true"`
+	FailureCorrelation FailureCorrelation `split_words:"true"`
+	ImpossibleTravel   ImpossibleTravel   `split_words:"true"`
+	CountryPolicy      CountryPolicy      `split_words:"true"`
+	// Schedule flags logins outside the hours users are expected to log in.
+	Schedule Schedule
+	// Severity configures the default severity of event types and when it is
+	// escalated.
+	Severity Severity
+	// Rules decide which events are sent where.
+	Rules Rules
+	// Baseline flags logins from sources new to the user.
+	Baseline Baseline
+	// Silence mutes events matching silences, e.g. during maintenance.
+	Silence Silence
+	// Integrity reports changes to authorized_keys files and the sshd
+	// configuration.
+	Integrity Integrity
+	// Posture audits the sshd configuration against a hardening policy.
+	Posture Posture
+	// ActiveResponse bans the source of attacks at the host firewall.
+	ActiveResponse ActiveResponse `split_words:"true"`
 	// StateFilePath is location of file that keeps track of the last processed line
 	// by ssh watcher so restarts of the service do not reprocess all ssh history.
 	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/authlog-state"`
 }
 
 type WatchSettings struct {
+	// AcceptedLogins, FailedLogins and FailedLoginInvalidUsername are ignored
+	// when a rules file is configured.
+	//
 	// AcceptedLogins is a flag to watch for successful logins
 	AcceptedLogins bool `default:"true" split_words:"true"`
 	// FailedLogins is a flag to watch for failed logins
@@ -44,5 +97,61 @@ func New() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed processing config: %w", err)
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Baseline.validate(); err != nil {
+		return nil, fmt.Errorf("invalid baseline config: %w", err)
+	}
+	if err := cfg.ActiveResponse.validate(); err != nil {
+		return nil, fmt.Errorf("invalid active response config: %w", err)
+	}
+	if err := cfg.Silence.validate(); err != nil {
+		return nil, fmt.Errorf("invalid silence config: %w", err)
+	}
 	return &cfg, nil
 }
+
+func (c Config) validate() error {
+	switch c.Notifier {
+	case SlackNotifier:
+		if c.Slack.WebhookUrl == "" {
+			return fmt.Errorf("%s_SLACK_WEBHOOK_URL is required for the %s notifier", ServicePrefix, c.Notifier)
+		}
+	case PagerDutyNotifier:
+		if c.PagerDuty.RoutingKey == "" {
+			return fmt.Errorf("%s_PAGER_DUTY_ROUTING_KEY is required for the %s notifier", ServicePrefix, c.Notifier)
+		}
+	case OpsgenieNotifier:
+		if c.Opsgenie.ApiKey == "" {
+			return fmt.Errorf("%s_OPSGENIE_API_KEY is required for the %s notifier", ServicePrefix, c.Notifier)
+		}
+	case AlertmanagerNotifier:
+		if c.Alertmanager.Url == "" {
+			return fmt.Errorf("%s_ALERTMANAGER_URL is required for the %s notifier", ServicePrefix, c.Notifier)
+		}
+	case TelegramNotifier:
+		if c.Telegram.BotToken == "" || c.Telegram.ChatId == "" {
+			return fmt.Errorf("%s_TELEGRAM_BOT_TOKEN and %s_TELEGRAM_CHAT_ID are required for the %s notifier", ServicePrefix, ServicePrefix, c.Notifier)
+		}
+	case MatrixNotifier:
+		if c.Matrix.HomeserverUrl == "" || c.Matrix.AccessToken == "" || c.Matrix.RoomId == "" {
+			return fmt.Errorf("%s_MATRIX_HOMESERVER_URL, %s_MATRIX_ACCESS_TOKEN and %s_MATRIX_ROOM_ID are required for the %s notifier", ServicePrefix, ServicePrefix, ServicePrefix, c.Notifier)
+		}
+	case NtfyNotifier:
+		if c.Ntfy.Topic == "" {
+			return fmt.Errorf("%s_NTFY_TOPIC is required for the %s notifier", ServicePrefix, c.Notifier)
+		}
+	case GotifyNotifier:
+		if c.Gotify.ServerUrl == "" || c.Gotify.AppToken == "" {
+			return fmt.Errorf("%s_GOTIFY_SERVER_URL and %s_GOTIFY_APP_TOKEN are required for the %s notifier", ServicePrefix, ServicePrefix, c.Notifier)
+		}
+	case ExecNotifier:
+		if c.Exec.Command == "" {
+			return fmt.Errorf("%s_EXEC_COMMAND is required for the %s notifier", ServicePrefix, c.Notifier)
+		}
+	default:
+		return fmt.Errorf("unknown notifier %q", c.Notifier)
+	}
+	return nil
+}
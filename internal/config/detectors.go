@@ -0,0 +1,62 @@
+package config
+
+type BruteForce struct {
+	// Enabled turns on brute force detection.
+	Enabled bool `default:"false"`
+	// Window is the sliding window failed logins are counted in.
+	Window Duration `default:"10m"`
+	// IpThreshold is the number of failed logins from one IP address within
+	// Window that is reported as a brute force attack. Zero disables it.
+	IpThreshold int `split_words:"true" default:"10"`
+	// UserThreshold is the number of failed logins for one username within
+	// Window that is reported as a brute force attack. Zero disables it.
+	UserThreshold int `split_words:"true" default:"20"`
+	// Cooldown is how long individual failed logins from a detected IP
+	// address are suppressed.
+	Cooldown Duration `default:"30m"`
+	// MaxTrackedSources bounds the number of IP addresses and usernames held
+	// in memory; the least recently seen are evicted first.
+	MaxTrackedSources int `split_words:"true" default:"10000"`
+}
+
+type PasswordSpray struct {
+	// Enabled turns on detection of one IP address trying many usernames.
+	Enabled bool `default:"false"`
+	// Window is the sliding window distinct usernames are counted in.
+	Window Duration `default:"1h"`
+	// Threshold is the number of distinct usernames tried by one IP address
+	// within Window that is reported as a password spray.
+	Threshold int `default:"20"`
+	// Cooldown is how long no new detection is emitted for the same IP address.
+	Cooldown Duration `default:"1h"`
+	// MaxTrackedSources bounds the number of IP addresses held in memory.
+	MaxTrackedSources int `split_words:"true" default:"10000"`
+}
+
+type DistributedAttack struct {
+	// Enabled turns on detection of many IP addresses trying one username.
+	Enabled bool `default:"false"`
+	// Window is the sliding window distinct IP addresses are counted in.
+	Window Duration `default:"1h"`
+	// Threshold is the number of distinct IP addresses trying one username
+	// within Window that is reported as a distributed attack.
+	Threshold int `default:"50"`
+	// Cooldown is how long no new detection is emitted for the same username.
+	Cooldown Duration `default:"1h"`
+	// MaxTrackedUsers bounds the number of usernames held in memory.
+	MaxTrackedUsers int `split_words:"true" default:"10000"`
+}
+
+type FailureCorrelation struct {
+	// Enabled turns on flagging successful logins that follow failed logins
+	// for the same username from the same IP address.
+	Enabled bool `default:"false"`
+	// Window is how long failed logins are remembered.
+	Window Duration `default:"1h"`
+	// MinFailures is the number of failed logins within Window after which a
+	// successful login is reported as suspicious.
+	MinFailures int `split_words:"true" default:"3"`
+	// MaxTrackedPairs bounds the number of username and IP address pairs held
+	// in memory.
+	MaxTrackedPairs int `split_words:"true" default:"10000"`
+}
@@ -0,0 +1,24 @@
+package config
+
+type Digest struct {
+	// Enabled buffers the events of the sink selected with WR_NOTIFIER and
+	// sends them as a digest every Interval.
+	Enabled bool `default:"false"`
+	// EventTypes are the event types buffered. Other events are sent
+	// immediately. Empty buffers all events.
+	EventTypes []string `split_words:"true"`
+	// Interval is how often digests are sent. It is also the default of
+	// digest sinks of the routing file.
+	Interval Duration `default:"1h"`
+	// StateDir is the directory the buffered events of each digest are
+	// persisted in.
+	StateDir string `split_words:"true" default:"/var/lib/ssh-watcher/digests"`
+}
+
+// SinkDigest turns a sink of the routing file into a digest sink.
+type SinkDigest struct {
+	// EventTypes are the event types buffered. Empty buffers all events.
+	EventTypes []string `json:"event_types"`
+	// Interval defaults to WR_DIGEST_INTERVAL.
+	Interval Duration `json:"interval"`
+}
@@ -0,0 +1,12 @@
+package config
+
+type Geoip struct {
+	// CityDatabasePath is the location of a GeoLite2-City or DB-IP City Lite
+	// mmdb file used to add the country, city and coordinates to events.
+	CityDatabasePath string `split_words:"true"`
+	// AsnDatabasePath is the location of a GeoLite2-ASN or DB-IP ASN Lite
+	// mmdb file used to add the autonomous system to events.
+	AsnDatabasePath string `split_words:"true"`
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval Duration `split_words:"true" default:"1m"`
+}
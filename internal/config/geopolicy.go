@@ -0,0 +1,26 @@
+package config
+
+type ImpossibleTravel struct {
+	// Enabled turns on flagging logins of a user from two locations too far
+	// apart for the time between them. Requires a GeoIP city database.
+	Enabled bool `default:"false"`
+	// MaxSpeedKmh is the highest plausible travel speed between two logins.
+	MaxSpeedKmh float64 `split_words:"true" default:"1000"`
+	// IgnoreRadiusKm is the distance below which logins are never flagged.
+	IgnoreRadiusKm float64 `split_words:"true" default:"100"`
+	// VpnAsns are autonomous systems of VPN providers whose logins are never
+	// flagged.
+	VpnAsns []uint `split_words:"true"`
+	// StateFilePath is the location of the file the last login of each user
+	// is persisted in.
+	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/travel.json"`
+}
+
+type CountryPolicy struct {
+	// AllowedCountries are the ISO 3166-1 alpha-2 codes of the countries
+	// users may log in from. Empty allows every country.
+	AllowedCountries []string `split_words:"true"`
+	// UserAllowedCountries overrides AllowedCountries per user, with the
+	// countries of a user separated by spaces, e.g. "alice:DE FR,bob:US".
+	UserAllowedCountries map[string]string `split_words:"true"`
+}
@@ -0,0 +1,16 @@
+package config
+
+type Integrity struct {
+	// Enabled turns on reporting changes to authorized_keys files and the
+	// sshd configuration.
+	Enabled bool `default:"false"`
+	// AuthorizedKeys are glob patterns of the authorized_keys files watched.
+	AuthorizedKeys []string `split_words:"true" default:"/root/.ssh/authorized_keys,/home/*/.ssh/authorized_keys"`
+	// SshdConfig are glob patterns of the sshd configuration files watched.
+	SshdConfig []string `split_words:"true" default:"/etc/ssh/sshd_config,/etc/ssh/sshd_config.d/*"`
+	// CheckInterval is how often the files are compared to the last snapshot.
+	CheckInterval Duration `split_words:"true" default:"1m"`
+	// StateFilePath is the location of the file the last snapshot is
+	// persisted in.
+	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/integrity.json"`
+}
@@ -0,0 +1,11 @@
+package config
+
+type KeyIndex struct {
+	// Paths are glob patterns of authorized_keys files, e.g.
+	// /home/*/.ssh/authorized_keys, and of files capturing the output of an
+	// AuthorizedKeysCommand. Public key logins are attributed to the owner
+	// of the key in them.
+	Paths []string
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval Duration `split_words:"true" default:"30s"`
+}
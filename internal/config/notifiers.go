@@ -0,0 +1,84 @@
+package config
+
+// Notifier names the notification backend alerts are delivered to.
+type Notifier string
+
+const (
+	SlackNotifier        Notifier = "slack"
+	PagerDutyNotifier    Notifier = "pagerduty"
+	OpsgenieNotifier     Notifier = "opsgenie"
+	AlertmanagerNotifier Notifier = "alertmanager"
+	TelegramNotifier     Notifier = "telegram"
+	MatrixNotifier       Notifier = "matrix"
+	NtfyNotifier         Notifier = "ntfy"
+	GotifyNotifier       Notifier = "gotify"
+	ExecNotifier         Notifier = "exec"
+)
+
+type Slack struct {
+	WebhookUrl string `split_words:"true" json:"webhook_url"`
+	Channel    string `split_words:"true"  default:"#ssh-alerts" json:"channel"`
+	Username   string `split_words:"true"  default:"poe-ssh-bot" json:"username"`
+	Icon       string `split_words:"true"  default:":ghost:" json:"icon"`
+}
+
+type PagerDuty struct {
+	// RoutingKey is the integration key of the PagerDuty Events API v2 service.
+	RoutingKey string `split_words:"true" json:"routing_key"`
+	EventsUrl  string `split_words:"true" default:"https://events.pagerduty.com/v2/enqueue" json:"events_url"`
+}
+
+type Opsgenie struct {
+	ApiKey string `split_words:"true" json:"api_key"`
+	// ApiUrl is the Opsgenie API base URL, use https://api.eu.opsgenie.com for EU accounts.
+	ApiUrl string   `split_words:"true" default:"https://api.opsgenie.com" json:"api_url"`
+	Tags   []string `split_words:"true" json:"tags"`
+}
+
+type Alertmanager struct {
+	// Url is the base URL of Alertmanager, e.g. http://alertmanager:9093.
+	Url string `split_words:"true" json:"url"`
+	// ResolveTimeout is how long alerts stay firing before Alertmanager auto resolves them.
+	ResolveTimeout Duration          `split_words:"true" default:"30m" json:"resolve_timeout"`
+	Labels         map[string]string `split_words:"true" json:"labels"`
+}
+
+type Telegram struct {
+	BotToken string `split_words:"true" json:"bot_token"`
+	// ChatId is the chat, group or channel id (or @channelusername) to post to.
+	ChatId string `split_words:"true" json:"chat_id"`
+	ApiUrl string `split_words:"true" default:"https://api.telegram.org" json:"api_url"`
+}
+
+type Matrix struct {
+	HomeserverUrl string `split_words:"true" json:"homeserver_url"`
+	AccessToken   string `split_words:"true" json:"access_token"`
+	// RoomId is the id of the room messages are sent to, e.g. !abcdef:matrix.org.
+	RoomId string `split_words:"true" json:"room_id"`
+}
+
+type Ntfy struct {
+	ServerUrl string `split_words:"true" default:"https://ntfy.sh" json:"server_url"`
+	Topic     string `json:"topic"`
+	// AccessToken is only needed for protected topics.
+	AccessToken string `split_words:"true" json:"access_token"`
+	// Priorities overrides the priority (1-5) per event type, e.g. "logged in:5".
+	Priorities map[string]int `json:"priorities"`
+}
+
+type Gotify struct {
+	ServerUrl string `split_words:"true" json:"server_url"`
+	AppToken  string `split_words:"true" json:"app_token"`
+	// Priorities overrides the priority (0-10) per event type, e.g. "logged in:10".
+	Priorities map[string]int `json:"priorities"`
+}
+
+type Exec struct {
+	// Command is the path of the executable run for every event.
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Timeout is the maximum run time of the command before it is killed.
+	Timeout Duration `default:"30s" json:"timeout"`
+	// MaxConcurrent limits how many commands run at the same time.
+	MaxConcurrent int `split_words:"true" default:"4" json:"max_concurrent"`
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Patterns struct {
+	// File is the optional location of a JSON file with patterns parsing log
+	// lines the built-in parser does not know.
+	File string
+}
+
+// NewPatterns loads only the patterns settings, for commands that do not
+// need a notifier.
+func NewPatterns() (*Patterns, error) {
+	cfg := Patterns{}
+	if err := envconfig.Process(ServicePrefix+"_PATTERNS", &cfg); err != nil {
+		return nil, fmt.Errorf("failed processing patterns config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Posture struct {
+	// Enabled turns on auditing the sshd configuration against the posture
+	// policy on startup and every CheckInterval.
+	Enabled bool `default:"false"`
+	// SshdConfig is the location of the main sshd configuration file. The
+	// files it includes are audited too.
+	SshdConfig string `split_words:"true" default:"/etc/ssh/sshd_config"`
+	// CheckInterval is how often the configuration is audited.
+	CheckInterval Duration `split_words:"true" default:"1h"`
+	// StateFilePath is the location of the file the findings of the last
+	// audit are persisted in.
+	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/posture.json"`
+}
+
+// NewPosture loads only the posture settings, for commands that do not need
+// a notifier.
+func NewPosture() (*Posture, error) {
+	cfg := Posture{}
+	if err := envconfig.Process(ServicePrefix+"_POSTURE", &cfg); err != nil {
+		return nil, fmt.Errorf("failed processing posture config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Routing is the content of the routing file. It defines named sinks in
+// addition to the one selected with WR_NOTIFIER and the ordered routes that
+// decide which sinks receive an event.
+type Routing struct {
+	Sinks  []Sink  `json:"sinks"`
+	Routes []Route `json:"routes"`
+	// DefaultSinks receive events that match no route. When empty the sink
+	// selected with WR_NOTIFIER is used.
+	DefaultSinks []string `json:"default_sinks"`
+}
+
+// Sink is a named notification destination. Settings hold the notifier
+// specific options, e.g. {"channel": "#ssh-noise"} for a slack sink, and
+// override the values configured through environment variables.
+type Sink struct {
+	Name string   `json:"name"`
+	Type Notifier `json:"type"`
+	// MinSeverity is the lowest severity of events sent to the sink. Empty
+	// sends events of every severity.
+	MinSeverity string          `json:"min_severity"`
+	Settings    json.RawMessage `json:"settings"`
+	// Digest sends the events of the sink as a periodic summary.
+	Digest *SinkDigest `json:"digest"`
+	// Dedup collapses repeated events of the sink.
+	Dedup *SinkDedup `json:"dedup"`
+	// RateLimit limits how many events per minute the sink receives.
+	RateLimit *SinkRateLimit `json:"rate_limit"`
+}
+
+// Route sends events matching Match to Sinks. Routes are evaluated in order
+// and evaluation stops at the first matching route unless Continue is set.
+type Route struct {
+	Name     string   `json:"name"`
+	Match    Match    `json:"match"`
+	Sinks    []string `json:"sinks"`
+	Continue bool     `json:"continue"`
+}
+
+// Match selects events. Every non empty field must match the event and a
+// field matches when any of its values does. An empty Match matches all events.
+type Match struct {
+	EventTypes []string `json:"event_types"`
+	Users      []string `json:"users"`
+	// IPs holds IP addresses or CIDR ranges such as 10.0.0.0/8.
+	IPs        []string `json:"ips"`
+	Hosts      []string `json:"hosts"`
+	Severities []string `json:"severities"`
+}
+
+// LoadRouting reads and validates the routing file at path.
+func LoadRouting(path string) (*Routing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading routing file: %w", err)
+	}
+
+	routing := Routing{}
+	if err := decodeStrict(data, &routing); err != nil {
+		return nil, fmt.Errorf("failed parsing routing file %s: %w", path, err)
+	}
+
+	names := map[string]bool{}
+	for _, sink := range routing.Sinks {
+		if sink.Name == "" {
+			return nil, fmt.Errorf("sink of type %q has no name", sink.Type)
+		}
+		if names[sink.Name] {
+			return nil, fmt.Errorf("duplicate sink name %q", sink.Name)
+		}
+		names[sink.Name] = true
+	}
+	return &routing, nil
+}
+
+// SinkConfig returns a copy of c with the sink settings applied on top of the
+// notifier configuration of the sink type.
+func (c Config) SinkConfig(sink Sink) (*Config, error) {
+	sinkCfg := c
+	sinkCfg.Notifier = sink.Type
+
+	var target any
+	switch sink.Type {
+	case SlackNotifier:
+		slack := *c.Slack
+		sinkCfg.Slack, target = &slack, &slack
+	case PagerDutyNotifier:
+		pagerDuty := *c.PagerDuty
+		sinkCfg.PagerDuty, target = &pagerDuty, &pagerDuty
+	case OpsgenieNotifier:
+		opsgenie := *c.Opsgenie
+		sinkCfg.Opsgenie, target = &opsgenie, &opsgenie
+	case AlertmanagerNotifier:
+		alertmanager := *c.Alertmanager
+		sinkCfg.Alertmanager, target = &alertmanager, &alertmanager
+	case TelegramNotifier:
+		telegram := *c.Telegram
+		sinkCfg.Telegram, target = &telegram, &telegram
+	case MatrixNotifier:
+		matrix := *c.Matrix
+		sinkCfg.Matrix, target = &matrix, &matrix
+	case NtfyNotifier:
+		ntfy := *c.Ntfy
+		sinkCfg.Ntfy, target = &ntfy, &ntfy
+	case GotifyNotifier:
+		gotify := *c.Gotify
+		sinkCfg.Gotify, target = &gotify, &gotify
+	case ExecNotifier:
+		exec := *c.Exec
+		sinkCfg.Exec, target = &exec, &exec
+	default:
+		return nil, fmt.Errorf("sink %q has unknown type %q", sink.Name, sink.Type)
+	}
+
+	if len(sink.Settings) > 0 {
+		if err := decodeStrict(sink.Settings, target); err != nil {
+			return nil, fmt.Errorf("failed parsing settings of sink %q: %w", sink.Name, err)
+		}
+	}
+	if err := sinkCfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", sink.Name, err)
+	}
+	return &sinkCfg, nil
+}
+
+// decodeStrict decodes the JSON data into v and rejects unknown fields, so
+// misspelled options are not silently ignored.
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Rules struct {
+	// File is the optional location of a JSON file with the rules applied to
+	// events before they are sent. Without it the AcceptedLogins,
+	// FailedLogins and FailedLoginInvalidUsername watch settings decide which
+	// events are sent.
+	File string
+}
+
+// NewRules loads only the rules settings, for commands that do not need a
+// notifier.
+func NewRules() (*Rules, error) {
+	cfg := Rules{}
+	if err := envconfig.Process(ServicePrefix+"_RULES", &cfg); err != nil {
+		return nil, fmt.Errorf("failed processing rules config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,9 @@
+package config
+
+type Schedule struct {
+	// File is the optional location of a JSON file with schedules and the
+	// users and groups they apply to.
+	File string
+	// ReloadInterval is how often the file is checked for changes.
+	ReloadInterval Duration `split_words:"true" default:"30s"`
+}
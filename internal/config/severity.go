@@ -0,0 +1,17 @@
+package config
+
+type Severity struct {
+	// Defaults overrides the severity of event types before escalation, e.g.
+	// "failed login attempt:info,logged in:high".
+	Defaults map[string]string
+	// PrivilegedUsers are the users whose logins are escalated to
+	// PrivilegedLogin.
+	PrivilegedUsers []string `split_words:"true" default:"root"`
+	PrivilegedLogin string   `split_words:"true" default:"critical"`
+	// Denylisted is the severity events matching a deny rule of the access
+	// list are escalated to.
+	Denylisted string `default:"critical"`
+	// OutOfHours is the severity logins outside the user's schedule are
+	// escalated to.
+	OutOfHours string `split_words:"true" default:"high"`
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Silence struct {
+	// StateFilePath is the location of the file silences are persisted in.
+	// It is shared by ssh-watcher and the silence command.
+	StateFilePath string `split_words:"true" default:"/var/lib/ssh-watcher/silences.json"`
+	// ReloadInterval is how often the file is checked for silences added by
+	// the silence command.
+	ReloadInterval Duration `split_words:"true" default:"10s"`
+	// ListenAddress is the optional address the silences API and metrics are
+	// served on, e.g. 127.0.0.1:9595. The API is disabled without it.
+	ListenAddress string `split_words:"true"`
+	// ApiToken is the bearer token required by the silences API. Without it
+	// the API is only served on loopback addresses.
+	ApiToken string `split_words:"true"`
+}
+
+// NewSilence loads only the silence settings, for commands that do not need
+// a notifier.
+func NewSilence() (*Silence, error) {
+	cfg := Silence{}
+	if err := envconfig.Process(ServicePrefix+"_SILENCE", &cfg); err != nil {
+		return nil, fmt.Errorf("failed processing silence config: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (s Silence) validate() error {
+	if s.ListenAddress == "" || s.ApiToken != "" || loopback(s.ListenAddress) {
+		return nil
+	}
+	return fmt.Errorf("%s_SILENCE_API_TOKEN is required unless %s_SILENCE_LISTEN_ADDRESS is a loopback address", ServicePrefix, ServicePrefix)
+}
+
+// loopback reports whether the listen address only accepts connections from
+// the host itself.
+func loopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
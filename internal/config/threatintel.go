@@ -0,0 +1,9 @@
+package config
+
+type ThreatIntel struct {
+	// Feeds maps feed names to local plain text or CSV files listing IP
+	// addresses and CIDR ranges, e.g. "tor:/var/lib/feeds/tor-exits.txt".
+	Feeds map[string]string
+	// ReloadInterval is how often the feed files are checked for changes.
+	ReloadInterval Duration `split_words:"true" default:"5m"`
+}
@@ -0,0 +1,34 @@
+package config
+
+type Dedup struct {
+	// Window collapses events of the sink selected with WR_NOTIFIER with the
+	// same Fields within it into one notification and a follow-up with the
+	// number of repeats. Zero disables deduplication.
+	Window Duration
+	// Fields are the event fields identifying repeats. They are also the
+	// default of dedup sinks of the routing file.
+	Fields []string `default:"event_type,ip_address,username"`
+}
+
+type RateLimit struct {
+	// PerMinute limits how many events per minute the sink selected with
+	// WR_NOTIFIER receives. Zero disables rate limiting.
+	PerMinute float64 `split_words:"true"`
+	// Burst is how many events are sent at once after a quiet period. It is
+	// also the default of rate limited sinks of the routing file.
+	Burst int `default:"10"`
+}
+
+// SinkDedup deduplicates the events of a sink of the routing file.
+type SinkDedup struct {
+	Window Duration `json:"window"`
+	// Fields default to WR_DEDUP_FIELDS.
+	Fields []string `json:"fields"`
+}
+
+// SinkRateLimit rate limits a sink of the routing file.
+type SinkRateLimit struct {
+	PerMinute float64 `json:"per_minute"`
+	// Burst defaults to WR_RATE_LIMIT_BURST.
+	Burst int `json:"burst"`
+}
@@ -0,0 +1,142 @@
+package detector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/lru"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// BruteForceSettings configures the brute force detector.
+type BruteForceSettings struct {
+	// Window is the sliding window failures are counted in.
+	Window time.Duration
+	// IpThreshold is the number of failures from one IP address within
+	// Window that triggers a detection. Zero disables per IP detection.
+	IpThreshold int
+	// UserThreshold is the number of failures for one username within Window
+	// that triggers a detection. Zero disables per user detection.
+	UserThreshold int
+	// Cooldown is how long individual failures from a detected IP address
+	// are suppressed and no new detection is emitted for the same source.
+	Cooldown time.Duration
+	// MaxTracked bounds the number of IP addresses and usernames tracked.
+	MaxTracked int
+}
+
+// NewBruteForce creates a brute force detector.
+func NewBruteForce(settings BruteForceSettings) *BruteForce {
+	return &BruteForce{
+		settings: settings,
+		ips:      lru.New[string, *failures](settings.MaxTracked),
+		users:    lru.New[string, *failures](settings.MaxTracked),
+		now:      time.Now,
+	}
+}
+
+// BruteForce counts failed logins per source IP address and per username in
+// a sliding window and replaces them with a single BruteForceDetected event
+// once a threshold is crossed.
+type BruteForce struct {
+	settings BruteForceSettings
+	ips      *lru.Cache[string, *failures]
+	users    *lru.Cache[string, *failures]
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// failures tracks the failed logins of one source or username.
+type failures struct {
+	attempts      []attempt
+	cooldownUntil time.Time
+}
+
+// attempt is a failed login. related is the username tried by an IP address,
+// or the IP address that tried a username.
+type attempt struct {
+	at      time.Time
+	related string
+}
+
+func newFailures() *failures {
+	return &failures{}
+}
+
+// add records a failure at now and drops failures older than window.
+func (f *failures) add(now time.Time, window time.Duration, related string) {
+	f.attempts = append(f.attempts, attempt{at: now, related: related})
+	f.prune(now, window)
+}
+
+// prune drops failures older than window.
+func (f *failures) prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(f.attempts) && f.attempts[i].at.Before(cutoff) {
+		i++
+	}
+	f.attempts = f.attempts[i:]
+}
+
+// relatedCounts counts the failures in the window per related value.
+func (f *failures) relatedCounts() map[string]int {
+	counts := map[string]int{}
+	for _, a := range f.attempts {
+		if a.related != "" {
+			counts[a.related]++
+		}
+	}
+	return counts
+}
+
+// Process implements the app stage interface.
+func (b *BruteForce) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if !isFailure(logLine.EventType) {
+		return []notifier.LogLine{logLine}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := logLine.OccurredAt(b.now())
+	var detections []notifier.LogLine
+	suppress := false
+
+	if b.settings.IpThreshold > 0 && logLine.IpAddress != "" {
+		source := b.ips.GetOrAdd(logLine.IpAddress, newFailures)
+		if now.Before(source.cooldownUntil) {
+			suppress = true
+		} else {
+			source.add(now, b.settings.Window, logLine.Username)
+			if len(source.attempts) >= b.settings.IpThreshold {
+				detection := detectionEvent(logLine, notifier.BruteForceDetected, len(source.attempts))
+				detection.Username = ""
+				detection.Usernames = topKeys(source.relatedCounts(), maxSummaryEntries)
+				detections = append(detections, detection)
+				b.ips.Add(logLine.IpAddress, &failures{cooldownUntil: now.Add(b.settings.Cooldown)})
+				suppress = true
+			}
+		}
+	}
+
+	if b.settings.UserThreshold > 0 && logLine.Username != "" {
+		user := b.users.GetOrAdd(logLine.Username, newFailures)
+		user.add(now, b.settings.Window, logLine.IpAddress)
+		if !now.Before(user.cooldownUntil) && len(user.attempts) >= b.settings.UserThreshold {
+			detection := detectionEvent(logLine, notifier.BruteForceDetected, len(user.attempts))
+			detection.IpAddress = ""
+			detection.Location = nil
+			detection.ThreatFeeds = nil
+			detection.Denylisted = false
+			detection.IpAddresses = topKeys(user.relatedCounts(), maxSummaryEntries)
+			detections = append(detections, detection)
+			b.users.Add(logLine.Username, &failures{cooldownUntil: now.Add(b.settings.Cooldown)})
+		}
+	}
+
+	if suppress {
+		return detections
+	}
+	return append([]notifier.LogLine{logLine}, detections...)
+}
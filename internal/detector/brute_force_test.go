@@ -0,0 +1,181 @@
+package detector
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func failedLogin(user, ip string) notifier.LogLine {
+	return notifier.LogLine{
+		Username:  user,
+		IpAddress: ip,
+		EventType: notifier.FailedLoginAttempt,
+		Severity:  notifier.SeverityLow,
+	}
+}
+
+// clock is a controllable time source for detectors.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newClock() *clock {
+	return &clock{t: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)}
+}
+
+func TestBruteForce_ProcessIpThreshold(t *testing.T) {
+	c := newClock()
+	b := NewBruteForce(BruteForceSettings{
+		Window:      time.Minute,
+		IpThreshold: 3,
+		Cooldown:    10 * time.Minute,
+		MaxTracked:  100,
+	})
+	b.now = c.now
+
+	users := []string{"admin", "root", "admin"}
+	for i, user := range users[:2] {
+		got := b.Process(failedLogin(user, "1.2.3.4"))
+		if len(got) != 1 || got[0].EventType != notifier.FailedLoginAttempt {
+			t.Fatalf("Process() failure %d = %v, want failure passed through", i, got)
+		}
+		c.advance(time.Second)
+	}
+
+	got := b.Process(failedLogin(users[2], "1.2.3.4"))
+	want := []notifier.LogLine{
+		{
+			IpAddress: "1.2.3.4",
+			EventType: notifier.BruteForceDetected,
+			Severity:  notifier.SeverityHigh,
+			Count:     3,
+			Usernames: []string{"admin", "root"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Process() at threshold = %v, want %v", got, want)
+	}
+
+	c.advance(time.Minute)
+	if got := b.Process(failedLogin("admin", "1.2.3.4")); len(got) != 0 {
+		t.Errorf("Process() during cooldown = %v, want suppressed", got)
+	}
+	if got := b.Process(failedLogin("admin", "5.6.7.8")); len(got) != 1 {
+		t.Errorf("Process() other source during cooldown = %v, want passed through", got)
+	}
+
+	c.advance(10 * time.Minute)
+	if got := b.Process(failedLogin("admin", "1.2.3.4")); len(got) != 1 || got[0].EventType != notifier.FailedLoginAttempt {
+		t.Errorf("Process() after cooldown = %v, want failure passed through", got)
+	}
+}
+
+func TestBruteForce_ProcessSlidingWindow(t *testing.T) {
+	c := newClock()
+	b := NewBruteForce(BruteForceSettings{
+		Window:      time.Minute,
+		IpThreshold: 3,
+		Cooldown:    time.Minute,
+		MaxTracked:  100,
+	})
+	b.now = c.now
+
+	for i := 0; i < 10; i++ {
+		for _, event := range b.Process(failedLogin("admin", "1.2.3.4")) {
+			if event.EventType == notifier.BruteForceDetected {
+				t.Fatalf("Process() detected brute force for failures spread outside the window")
+			}
+		}
+		c.advance(31 * time.Second)
+	}
+}
+
+func TestBruteForce_ProcessReplay(t *testing.T) {
+	// Replaying auth.log processes days of lines at once; failures are counted
+	// at the time they were logged.
+	c := newClock()
+	b := NewBruteForce(BruteForceSettings{
+		Window:      10 * time.Minute,
+		IpThreshold: 3,
+		Cooldown:    30 * time.Minute,
+		MaxTracked:  100,
+	})
+	b.now = c.now
+
+	loggedAt := c.t.Add(-72 * time.Hour)
+	for i := 0; i < 5; i++ {
+		failure := failedLogin("root", "1.2.3.4")
+		failure.Time = loggedAt.Add(time.Duration(i) * time.Hour)
+		if got := b.Process(failure); len(got) != 1 || got[0].EventType != notifier.FailedLoginAttempt {
+			t.Fatalf("Process() failure %d logged an hour apart = %v, want failure passed through", i, got)
+		}
+	}
+
+	var got []notifier.LogLine
+	for i := 0; i < 3; i++ {
+		failure := failedLogin("root", "5.6.7.8")
+		failure.Time = loggedAt.Add(time.Duration(i) * time.Second)
+		got = b.Process(failure)
+	}
+	if len(got) != 1 || got[0].EventType != notifier.BruteForceDetected || !got[0].Time.Equal(loggedAt.Add(2*time.Second)) {
+		t.Errorf("Process() failures logged a second apart = %v, want a detection at the time of the last failure", got)
+	}
+}
+
+func TestBruteForce_ProcessUserThreshold(t *testing.T) {
+	c := newClock()
+	b := NewBruteForce(BruteForceSettings{
+		Window:        time.Minute,
+		UserThreshold: 3,
+		Cooldown:      time.Minute,
+		MaxTracked:    100,
+	})
+	b.now = c.now
+
+	var detections []notifier.LogLine
+	for i := 0; i < 4; i++ {
+		for _, event := range b.Process(failedLogin("root", fmt.Sprintf("10.0.0.%d", i))) {
+			if event.EventType == notifier.BruteForceDetected {
+				detections = append(detections, event)
+			}
+		}
+	}
+
+	if len(detections) != 1 {
+		t.Fatalf("Process() detections = %v, want exactly one", detections)
+	}
+	want := []string{"10.0.0.0", "10.0.0.1", "10.0.0.2"}
+	if detections[0].Username != "root" || !reflect.DeepEqual(detections[0].IpAddresses, want) {
+		t.Errorf("Process() detection = %v, want root with IPs %v", detections[0], want)
+	}
+}
+
+func TestBruteForce_ProcessIgnoresOtherEvents(t *testing.T) {
+	b := NewBruteForce(BruteForceSettings{Window: time.Minute, IpThreshold: 1, MaxTracked: 100})
+	logLine := notifier.LogLine{Username: "root", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn}
+	if got := b.Process(logLine); !reflect.DeepEqual(got, []notifier.LogLine{logLine}) {
+		t.Errorf("Process() = %v, want log line passed through", got)
+	}
+}
+
+func TestBruteForce_ProcessBoundsTrackedSources(t *testing.T) {
+	b := NewBruteForce(BruteForceSettings{Window: time.Minute, IpThreshold: 100, MaxTracked: 10})
+	for i := 0; i < 50; i++ {
+		b.Process(failedLogin("admin", fmt.Sprintf("10.0.0.%d", i)))
+	}
+	if b.ips.Len() != 10 {
+		t.Errorf("tracked sources = %d, want 10", b.ips.Len())
+	}
+}
@@ -0,0 +1,75 @@
+package detector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/lru"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// CorrelatorSettings configures the failure correlator.
+type CorrelatorSettings struct {
+	// Window is how long failed logins are remembered.
+	Window time.Duration
+	// MinFailures is the number of failed logins for a username and IP address
+	// within Window after which a successful login is suspicious.
+	MinFailures int
+	// MaxTracked bounds the number of username and IP address pairs tracked.
+	MaxTracked int
+}
+
+// NewCorrelator creates a failure correlator.
+func NewCorrelator(settings CorrelatorSettings) *Correlator {
+	return &Correlator{
+		settings: settings,
+		pairs:    lru.New[string, *failures](settings.MaxTracked),
+		now:      time.Now,
+	}
+}
+
+// Correlator remembers failed logins per username and IP address and replaces
+// a successful login following a run of failures from the same pair with a
+// SuspiciousLoginAfterFailures event.
+type Correlator struct {
+	settings CorrelatorSettings
+	pairs    *lru.Cache[string, *failures]
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+func pairKey(logLine notifier.LogLine) string {
+	return logLine.Username + "@" + logLine.IpAddress
+}
+
+// Process implements the app stage interface.
+func (c *Correlator) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.Username == "" || logLine.IpAddress == "" {
+		return []notifier.LogLine{logLine}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := logLine.OccurredAt(c.now())
+	switch {
+	case isFailure(logLine.EventType):
+		c.pairs.GetOrAdd(pairKey(logLine), newFailures).add(now, c.settings.Window, "")
+	case logLine.EventType == notifier.LoggedIn:
+		pair, ok := c.pairs.Get(pairKey(logLine))
+		if !ok {
+			break
+		}
+		pair.prune(now, c.settings.Window)
+		count := len(pair.attempts)
+		if count == 0 || count < c.settings.MinFailures {
+			break
+		}
+		suspicious := detectionEvent(logLine, notifier.SuspiciousLoginAfterFailures, count)
+		suspicious.Elapsed = now.Sub(pair.attempts[0].at).Round(time.Second).String()
+		// The pair starts over so the next login is not flagged again.
+		c.pairs.Add(pairKey(logLine), newFailures())
+		return []notifier.LogLine{suspicious}
+	}
+	return []notifier.LogLine{logLine}
+}
@@ -0,0 +1,120 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func login(user, ip string) notifier.LogLine {
+	return notifier.LogLine{
+		Username:  user,
+		IpAddress: ip,
+		EventType: notifier.LoggedIn,
+		Severity:  notifier.SeverityMedium,
+	}
+}
+
+func TestCorrelator_Process(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []notifier.LogLine
+		// wait is the time between the last failure and the login.
+		wait time.Duration
+		want []notifier.LogLine
+	}{
+		{
+			name:     "login after failures is suspicious",
+			failures: []notifier.LogLine{failedLogin("root", "1.2.3.4"), failedLogin("root", "1.2.3.4"), failedLogin("root", "1.2.3.4")},
+			wait:     time.Minute,
+			want: []notifier.LogLine{
+				{
+					Username:  "root",
+					IpAddress: "1.2.3.4",
+					EventType: notifier.SuspiciousLoginAfterFailures,
+					Severity:  notifier.SeverityCritical,
+					Count:     3,
+					Elapsed:   "4m0s",
+				},
+			},
+		},
+		{
+			name:     "too few failures",
+			failures: []notifier.LogLine{failedLogin("root", "1.2.3.4"), failedLogin("root", "1.2.3.4")},
+			want:     []notifier.LogLine{login("root", "1.2.3.4")},
+		},
+		{
+			name:     "failures from another IP address",
+			failures: []notifier.LogLine{failedLogin("root", "5.6.7.8"), failedLogin("root", "5.6.7.8"), failedLogin("root", "5.6.7.8")},
+			want:     []notifier.LogLine{login("root", "1.2.3.4")},
+		},
+		{
+			name:     "failures outside the window",
+			failures: []notifier.LogLine{failedLogin("root", "1.2.3.4"), failedLogin("root", "1.2.3.4"), failedLogin("root", "1.2.3.4")},
+			wait:     2 * time.Hour,
+			want:     []notifier.LogLine{login("root", "1.2.3.4")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClock()
+			correlator := NewCorrelator(CorrelatorSettings{Window: time.Hour, MinFailures: 3, MaxTracked: 100})
+			correlator.now = c.now
+
+			for _, failure := range tt.failures {
+				if got := correlator.Process(failure); !reflect.DeepEqual(got, []notifier.LogLine{failure}) {
+					t.Fatalf("Process() failure = %v, want passed through", got)
+				}
+				c.advance(time.Minute)
+			}
+			c.advance(tt.wait)
+			if got := correlator.Process(login("root", "1.2.3.4")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Process() login = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrelator_ProcessFlagsOnce(t *testing.T) {
+	correlator := NewCorrelator(CorrelatorSettings{Window: time.Hour, MinFailures: 1, MaxTracked: 100})
+	correlator.Process(failedLogin("root", "1.2.3.4"))
+
+	if got := correlator.Process(login("root", "1.2.3.4")); got[0].EventType != notifier.SuspiciousLoginAfterFailures {
+		t.Fatalf("Process() first login = %v, want suspicious", got)
+	}
+	if got := correlator.Process(login("root", "1.2.3.4")); got[0].EventType != notifier.LoggedIn {
+		t.Errorf("Process() second login = %v, want routine login", got)
+	}
+}
+
+func TestCorrelator_ProcessReplay(t *testing.T) {
+	c := newClock()
+	correlator := NewCorrelator(CorrelatorSettings{Window: time.Hour, MinFailures: 3, MaxTracked: 100})
+	correlator.now = c.now
+
+	loggedAt := c.t.Add(-48 * time.Hour)
+	for i := 0; i < 3; i++ {
+		failure := failedLogin("root", "1.2.3.4")
+		failure.Time = loggedAt.Add(time.Duration(i) * time.Minute)
+		correlator.Process(failure)
+	}
+	success := login("root", "1.2.3.4")
+	success.Time = loggedAt.Add(10 * time.Minute)
+	got := correlator.Process(success)
+	if len(got) != 1 || got[0].EventType != notifier.SuspiciousLoginAfterFailures || got[0].Elapsed != "10m0s" {
+		t.Errorf("Process() replayed login = %v, want suspicious login 10m0s after the first failure", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		failure := failedLogin("admin", "1.2.3.4")
+		failure.Time = loggedAt.Add(time.Duration(i) * time.Minute)
+		correlator.Process(failure)
+	}
+	success = login("admin", "1.2.3.4")
+	success.Time = loggedAt.Add(24 * time.Hour)
+	if got := correlator.Process(success); !reflect.DeepEqual(got, []notifier.LogLine{success}) {
+		t.Errorf("Process() replayed login a day after the failures = %v, want passed through", got)
+	}
+}
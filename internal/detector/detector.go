@@ -0,0 +1,54 @@
+// Package detector holds stages that correlate log lines over time and emit
+// detection events such as brute force attacks.
+package detector
+
+import (
+	"sort"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// maxSummaryEntries caps the number of usernames or IP addresses listed in a
+// detection event.
+const maxSummaryEntries = 10
+
+// detectionEvent derives a detection event of eventType from the log line
+// that triggered it.
+func detectionEvent(logLine notifier.LogLine, eventType notifier.EventType, count int) notifier.LogLine {
+	return notifier.LogLine{
+		Username:    logLine.Username,
+		IpAddress:   logLine.IpAddress,
+		Location:    logLine.Location,
+		ThreatFeeds: logLine.ThreatFeeds,
+		Denylisted:  logLine.Denylisted,
+		LoginTime:   logLine.LoginTime,
+		Time:        logLine.Time,
+		EventType:   eventType,
+		HostMachine: logLine.HostMachine,
+		Severity:    notifier.DefaultSeverity(eventType),
+		Count:       count,
+	}
+}
+
+func isFailure(eventType notifier.EventType) bool {
+	return eventType == notifier.FailedLoginAttempt || eventType == notifier.FailedLoginAttemptInvalidUsername
+}
+
+// topKeys returns up to n keys of counts ordered by descending count and then
+// by key.
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
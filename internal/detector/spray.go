@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/lru"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// SpraySettings configures the password spray and distributed attack
+// detectors.
+type SpraySettings struct {
+	// Window is the sliding window distinct usernames or sources are counted in.
+	Window time.Duration
+	// Threshold is the number of distinct usernames tried by one source, or
+	// distinct sources trying one username, within Window that triggers a
+	// detection.
+	Threshold int
+	// Cooldown is how long no new detection is emitted for the same source or
+	// username.
+	Cooldown time.Duration
+	// MaxTracked bounds the number of sources or usernames tracked.
+	MaxTracked int
+}
+
+// NewPasswordSpray creates a detector emitting PasswordSprayDetected when one
+// source IP address tries many distinct usernames, however few attempts it
+// makes per username.
+func NewPasswordSpray(settings SpraySettings) *Spray {
+	return newSpray(settings, notifier.PasswordSprayDetected,
+		func(l notifier.LogLine) (string, string) { return l.IpAddress, l.Username })
+}
+
+// NewDistributedAttack creates a detector emitting DistributedAttackDetected
+// when many distinct source IP addresses try one username, however few
+// attempts each source makes.
+func NewDistributedAttack(settings SpraySettings) *Spray {
+	return newSpray(settings, notifier.DistributedAttackDetected,
+		func(l notifier.LogLine) (string, string) { return l.Username, l.IpAddress })
+}
+
+func newSpray(settings SpraySettings, eventType notifier.EventType, keys func(notifier.LogLine) (string, string)) *Spray {
+	return &Spray{
+		settings:  settings,
+		eventType: eventType,
+		keys:      keys,
+		tracked:   lru.New[string, *failures](settings.MaxTracked),
+		now:       time.Now,
+	}
+}
+
+// Spray counts the distinct values related to a key, such as the usernames
+// tried by a source IP address, in a sliding window of failed logins and
+// emits a detection listing the top related values once a threshold is
+// crossed. Failed logins are passed on.
+type Spray struct {
+	settings  SpraySettings
+	eventType notifier.EventType
+	// keys returns the value failures are tracked by and the related value
+	// whose distinct occurrences are counted.
+	keys    func(notifier.LogLine) (key string, related string)
+	tracked *lru.Cache[string, *failures]
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// Process implements the app stage interface.
+func (s *Spray) Process(logLine notifier.LogLine) []notifier.LogLine {
+	key, related := s.keys(logLine)
+	if !isFailure(logLine.EventType) || s.settings.Threshold <= 0 || key == "" || related == "" {
+		return []notifier.LogLine{logLine}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := logLine.OccurredAt(s.now())
+	entry := s.tracked.GetOrAdd(key, newFailures)
+	if now.Before(entry.cooldownUntil) {
+		return []notifier.LogLine{logLine}
+	}
+	entry.add(now, s.settings.Window, related)
+	counts := entry.relatedCounts()
+	if len(counts) < s.settings.Threshold {
+		return []notifier.LogLine{logLine}
+	}
+
+	detection := detectionEvent(logLine, s.eventType, len(entry.attempts))
+	if s.eventType == notifier.PasswordSprayDetected {
+		detection.Username = ""
+		detection.Usernames = topKeys(counts, maxSummaryEntries)
+	} else {
+		detection.IpAddress = ""
+		detection.Location = nil
+		detection.ThreatFeeds = nil
+		detection.Denylisted = false
+		detection.IpAddresses = topKeys(counts, maxSummaryEntries)
+	}
+	s.tracked.Add(key, &failures{cooldownUntil: now.Add(s.settings.Cooldown)})
+	return []notifier.LogLine{logLine, detection}
+}
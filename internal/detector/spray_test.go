@@ -0,0 +1,97 @@
+package detector
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func detections(logLines []notifier.LogLine, eventType notifier.EventType) []notifier.LogLine {
+	var found []notifier.LogLine
+	for _, logLine := range logLines {
+		if logLine.EventType == eventType {
+			found = append(found, logLine)
+		}
+	}
+	return found
+}
+
+func TestPasswordSpray_Process(t *testing.T) {
+	c := newClock()
+	s := NewPasswordSpray(SpraySettings{Window: time.Hour, Threshold: 3, Cooldown: time.Hour, MaxTracked: 100})
+	s.now = c.now
+
+	var got []notifier.LogLine
+	for _, user := range []string{"admin", "admin", "oracle", "admin", "postgres", "test"} {
+		out := s.Process(failedLogin(user, "1.2.3.4"))
+		if out[0].EventType != notifier.FailedLoginAttempt {
+			t.Fatalf("Process() = %v, want failure passed through first", out)
+		}
+		got = append(got, detections(out, notifier.PasswordSprayDetected)...)
+		c.advance(5 * time.Minute)
+	}
+
+	want := []notifier.LogLine{
+		{
+			IpAddress: "1.2.3.4",
+			EventType: notifier.PasswordSprayDetected,
+			Severity:  notifier.SeverityHigh,
+			Count:     5,
+			Usernames: []string{"admin", "oracle", "postgres"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Process() detections = %v, want %v", got, want)
+	}
+}
+
+func TestPasswordSpray_ProcessSlidingWindow(t *testing.T) {
+	c := newClock()
+	s := NewPasswordSpray(SpraySettings{Window: time.Minute, Threshold: 3, Cooldown: time.Hour, MaxTracked: 100})
+	s.now = c.now
+
+	for i := 0; i < 10; i++ {
+		if got := detections(s.Process(failedLogin(fmt.Sprintf("user%d", i), "1.2.3.4")), notifier.PasswordSprayDetected); len(got) > 0 {
+			t.Fatalf("Process() detected spray for usernames spread outside the window")
+		}
+		c.advance(31 * time.Second)
+	}
+}
+
+func TestDistributedAttack_Process(t *testing.T) {
+	c := newClock()
+	s := NewDistributedAttack(SpraySettings{Window: time.Hour, Threshold: 4, Cooldown: time.Hour, MaxTracked: 100})
+	s.now = c.now
+
+	var got []notifier.LogLine
+	for i := 0; i < 8; i++ {
+		got = append(got, detections(s.Process(failedLogin("root", fmt.Sprintf("10.0.0.%d", i%5))), notifier.DistributedAttackDetected)...)
+		got = append(got, detections(s.Process(failedLogin("alice", "10.0.0.1")), notifier.DistributedAttackDetected)...)
+		c.advance(time.Minute)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("Process() detections = %v, want exactly one", got)
+	}
+	want := notifier.LogLine{
+		Username:    "root",
+		EventType:   notifier.DistributedAttackDetected,
+		Severity:    notifier.SeverityHigh,
+		Count:       4,
+		IpAddresses: []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"},
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Process() detection = %v, want %v", got[0], want)
+	}
+}
+
+func TestSpray_ProcessIgnoresOtherEvents(t *testing.T) {
+	s := NewDistributedAttack(SpraySettings{Window: time.Minute, Threshold: 1, MaxTracked: 100})
+	logLine := notifier.LogLine{Username: "root", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn}
+	if got := s.Process(logLine); !reflect.DeepEqual(got, []notifier.LogLine{logLine}) {
+		t.Errorf("Process() = %v, want log line passed through", got)
+	}
+}
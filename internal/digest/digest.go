@@ -0,0 +1,167 @@
+// Package digest buffers events sent to a sink and delivers them as a
+// periodic summary instead.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+	"github.com/rs/zerolog"
+)
+
+// persistInterval is how often buffered events are persisted, so at most
+// this much is lost if ssh-watcher is killed.
+const persistInterval = 10 * time.Second
+
+// notifierClient is an interface for sending notifications
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . notifierClient
+type notifierClient interface {
+	Notify(LogLine notifier.LogLine) error
+}
+
+// Settings configures a digest.
+type Settings struct {
+	// HostMachine is reported as the host of digests.
+	HostMachine string
+	// Interval is how often the buffered events are summarized.
+	Interval time.Duration
+	// EventTypes are the event types buffered. Other events are delivered
+	// immediately. Empty buffers all events.
+	EventTypes []notifier.EventType
+}
+
+// New creates a digest delivering to next and restores the events buffered
+// at path before a restart.
+func New(next notifierClient, path string, settings Settings, log zerolog.Logger) (*Digester, error) {
+	d := &Digester{
+		next:       next,
+		path:       path,
+		settings:   settings,
+		eventTypes: map[notifier.EventType]bool{},
+		now:        time.Now,
+		log:        log,
+	}
+	for _, eventType := range settings.EventTypes {
+		d.eventTypes[eventType] = true
+	}
+	if err := statefile.Load(path, &d.digest); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Digester is a notifierClient buffering the events of its event types and
+// sending them to the wrapped notifier as a digest every interval.
+type Digester struct {
+	next       notifierClient
+	path       string
+	settings   Settings
+	eventTypes map[notifier.EventType]bool
+	digest     notifier.Digest
+	// dirty is set when digest changed since it was persisted.
+	dirty bool
+	now   func() time.Time
+	log   zerolog.Logger
+	mu    sync.Mutex
+}
+
+// Notify buffers the log line if its event type is digested and delivers it
+// immediately otherwise. Canary events are always delivered immediately.
+func (d *Digester) Notify(logLine notifier.LogLine) error {
+	if logLine.Canary || len(d.eventTypes) > 0 && !d.eventTypes[logLine.EventType] {
+		return d.next.Notify(logLine)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.digest.Total() == 0 {
+		d.digest = notifier.Digest{Start: d.now()}
+	}
+	d.digest.Add(logLine)
+	d.dirty = true
+	return nil
+}
+
+// persist saves the buffered events if they changed since they were last
+// saved. The events are buffered in memory either way, so a failure only
+// risks losing them on restart.
+func (d *Digester) persist() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.dirty {
+		return
+	}
+	if err := statefile.Save(d.path, d.digest); err != nil {
+		d.log.Error().Err(err).Msg("failed persisting digest")
+		return
+	}
+	d.dirty = false
+}
+
+// Flush sends the buffered events as a digest. Nothing is sent without
+// buffered events. The events stay buffered when sending fails. Events
+// arriving while the digest is sent are buffered for the next one.
+func (d *Digester) Flush() error {
+	d.mu.Lock()
+	if d.digest.Total() == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	digest := d.digest
+	d.digest = notifier.Digest{}
+	end := d.now()
+	d.mu.Unlock()
+
+	sent := digest
+	sent.End = end
+	logLine := notifier.LogLine{
+		LoginTime:   end.Format(time.RFC3339),
+		EventType:   notifier.DigestSummary,
+		HostMachine: d.settings.HostMachine,
+		Severity:    notifier.DefaultSeverity(notifier.DigestSummary),
+		Count:       sent.Total(),
+		Digest:      &sent,
+	}
+	err := d.next.Notify(logLine)
+
+	d.mu.Lock()
+	if err != nil {
+		digest.Merge(d.digest)
+		d.digest = digest
+	}
+	d.dirty = true
+	d.mu.Unlock()
+	d.persist()
+	if err != nil {
+		return fmt.Errorf("failed sending digest: %w", err)
+	}
+	return nil
+}
+
+// Run sends a digest every interval and persists the buffered events every
+// persistInterval until ctx is done. Events buffered on shutdown are persisted
+// and sent after the next start.
+func (d *Digester) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.settings.Interval)
+	defer ticker.Stop()
+	persistTicker := time.NewTicker(persistInterval)
+	defer persistTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.persist()
+			return
+		case <-persistTicker.C:
+			d.persist()
+		case <-ticker.C:
+			if err := d.Flush(); err != nil {
+				d.log.Error().Err(err).Msg("failed flushing digest")
+			}
+		}
+	}
+}
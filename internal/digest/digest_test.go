@@ -0,0 +1,173 @@
+package digest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/digest/digestfakes"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+var start = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func newTestDigester(t *testing.T, path string, next *digestfakes.FakeNotifierClient, eventTypes ...notifier.EventType) *Digester {
+	t.Helper()
+	d, err := New(next, path, Settings{HostMachine: "web-1", Interval: time.Hour, EventTypes: eventTypes}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	d.now = func() time.Time { return start }
+	return d
+}
+
+func failedLogin(username, ip string) notifier.LogLine {
+	return notifier.LogLine{Username: username, IpAddress: ip, EventType: notifier.FailedLoginAttempt}
+}
+
+func TestDigester_Notify(t *testing.T) {
+	tests := []struct {
+		name         string
+		eventTypes   []notifier.EventType
+		logLine      notifier.LogLine
+		wantSent     bool
+		wantBuffered int
+	}{
+		{
+			name:         "buffers all events without event types",
+			logLine:      failedLogin("root", "1.2.3.4"),
+			wantBuffered: 1,
+		},
+		{
+			name:         "buffers digested event types",
+			eventTypes:   []notifier.EventType{notifier.FailedLoginAttempt},
+			logLine:      failedLogin("root", "1.2.3.4"),
+			wantBuffered: 1,
+		},
+		{
+			name:     "sends canary events immediately",
+			logLine:  notifier.LogLine{Username: "oracle", EventType: notifier.FailedLoginAttemptInvalidUsername, Canary: true},
+			wantSent: true,
+		},
+		{
+			name:       "sends other event types immediately",
+			eventTypes: []notifier.EventType{notifier.FailedLoginAttempt},
+			logLine:    notifier.LogLine{Username: "root", EventType: notifier.LoggedIn},
+			wantSent:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &digestfakes.FakeNotifierClient{}
+			d := newTestDigester(t, filepath.Join(t.TempDir(), "slack.json"), next, tt.eventTypes...)
+
+			if err := d.Notify(tt.logLine); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if sent := next.NotifyCallCount() == 1; sent != tt.wantSent {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if got := d.digest.Total(); got != tt.wantBuffered {
+				t.Errorf("buffered = %d, want %d", got, tt.wantBuffered)
+			}
+		})
+	}
+}
+
+func TestDigester_Flush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slack.json")
+	next := &digestfakes.FakeNotifierClient{}
+	d := newTestDigester(t, path, next)
+
+	if err := d.Flush(); err != nil || next.NotifyCallCount() != 0 {
+		t.Fatalf("Flush() without events = %v, sent %d, want nothing sent", err, next.NotifyCallCount())
+	}
+
+	for _, logLine := range []notifier.LogLine{
+		failedLogin("root", "1.2.3.4"),
+		failedLogin("admin", "1.2.3.4"),
+		{Username: "alice", IpAddress: "5.6.7.8", EventType: notifier.LoggedIn, HostMachine: "web-1"},
+	} {
+		if err := d.Notify(logLine); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+
+	// Buffered events are persisted periodically rather than per event and
+	// survive a restart once persisted.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want the digest not persisted by Notify", err)
+	}
+	d.persist()
+	restarted := newTestDigester(t, path, next)
+	restarted.now = func() time.Time { return start.Add(time.Hour) }
+
+	next.NotifyReturnsOnCall(0, fmt.Errorf("slack unavailable"))
+	if err := restarted.Flush(); err == nil {
+		t.Fatal("Flush() error = nil, want delivery failure")
+	}
+	if err := restarted.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if next.NotifyCallCount() != 2 {
+		t.Fatalf("Notify() calls = %d, want the failed and the retried digest", next.NotifyCallCount())
+	}
+
+	logLine := next.NotifyArgsForCall(1)
+	if logLine.EventType != notifier.DigestSummary || logLine.HostMachine != "web-1" || logLine.Digest == nil {
+		t.Fatalf("sent %+v, want a digest of web-1", logLine)
+	}
+	digest := logLine.Digest
+	if digest.Total() != 3 || digest.Counts[notifier.FailedLoginAttempt] != 2 || len(digest.AcceptedLogins) != 1 {
+		t.Errorf("digest = %+v, want 2 failed logins and 1 accepted login", digest)
+	}
+	if !digest.Start.Equal(start) || !digest.End.Equal(start.Add(time.Hour)) {
+		t.Errorf("digest period = %v - %v, want %v - %v", digest.Start, digest.End, start, start.Add(time.Hour))
+	}
+
+	// The buffer is emptied once the digest is sent.
+	if err := restarted.Flush(); err != nil || next.NotifyCallCount() != 2 {
+		t.Errorf("Flush() after sending = %v, sent %d, want nothing sent", err, next.NotifyCallCount())
+	}
+	if reloaded := newTestDigester(t, path, next); reloaded.digest.Total() != 0 {
+		t.Errorf("persisted digest = %+v, want empty", reloaded.digest)
+	}
+}
+
+func TestDigester_FlushWhileNotified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slack.json")
+	next := &digestfakes.FakeNotifierClient{}
+	d := newTestDigester(t, path, next)
+	if err := d.Notify(failedLogin("root", "1.2.3.4")); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	// Events arriving while a digest is sent do not wait for it and are kept
+	// for the next digest, also when sending fails.
+	next.NotifyCalls(func(notifier.LogLine) error {
+		if err := d.Notify(failedLogin("admin", "5.6.7.8")); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		return fmt.Errorf("slack unavailable")
+	})
+	if err := d.Flush(); err == nil {
+		t.Fatal("Flush() error = nil, want delivery failure")
+	}
+	if got := d.digest.Total(); got != 2 {
+		t.Errorf("buffered = %d, want the unsent and the new event", got)
+	}
+	if !d.digest.Start.Equal(start) {
+		t.Errorf("digest start = %v, want %v of the unsent event", d.digest.Start, start)
+	}
+
+	next.NotifyCalls(nil)
+	if err := d.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := next.NotifyArgsForCall(1).Digest.Total(); got != 2 {
+		t.Errorf("sent digest of %d events, want 2", got)
+	}
+}
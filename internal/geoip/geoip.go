@@ -0,0 +1,218 @@
+// Package geoip enriches events with the location and autonomous system of
+// their source IP address from local MaxMind or DB-IP mmdb files.
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/rs/zerolog"
+)
+
+// database is an open mmdb file.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . database
+type database interface {
+	Lookup(ip net.IP, result any) error
+	Close() error
+}
+
+// cityRecord holds the fields read from GeoLite2-City and DB-IP City Lite
+// databases.
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		AccuracyRadius uint     `maxminddb:"accuracy_radius"`
+		Latitude       *float64 `maxminddb:"latitude"`
+		Longitude      *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// asnRecord holds the fields read from GeoLite2-ASN and DB-IP ASN Lite
+// databases.
+type asnRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// dbFile is a database file that is reopened when it changes on disk.
+type dbFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+	db      database
+}
+
+func openDatabase(path string) (database, error) {
+	return maxminddb.Open(path)
+}
+
+// New opens the city and ASN databases. Either path may be empty to skip that
+// database. The files are checked for changes at most every reloadInterval.
+func New(cityPath, asnPath string, reloadInterval time.Duration, log zerolog.Logger) (*GeoIP, error) {
+	g := &GeoIP{
+		reloadInterval: reloadInterval,
+		open:           openDatabase,
+		now:            time.Now,
+		log:            log,
+	}
+	for _, file := range []struct {
+		path   string
+		target **dbFile
+	}{
+		{path: cityPath, target: &g.city},
+		{path: asnPath, target: &g.asn},
+	} {
+		if file.path == "" {
+			continue
+		}
+		f := &dbFile{path: file.path}
+		if err := g.reload(f); err != nil {
+			g.Close()
+			return nil, err
+		}
+		*file.target = f
+	}
+	g.lastCheck = g.now()
+	return g, nil
+}
+
+// GeoIP is an app stage adding the location and autonomous system of the
+// source IP address to events. No network calls are made.
+type GeoIP struct {
+	city           *dbFile
+	asn            *dbFile
+	reloadInterval time.Duration
+	lastCheck      time.Time
+	open           func(path string) (database, error)
+	now            func() time.Time
+	log            zerolog.Logger
+	mu             sync.Mutex
+}
+
+// reload opens f.path if it changed since it was last opened and closes the
+// database it replaces.
+func (g *GeoIP) reload(f *dbFile) error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("failed reading database %s: %w", f.path, err)
+	}
+	if f.db != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+	db, err := g.open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed opening database %s: %w", f.path, err)
+	}
+	if f.db != nil {
+		f.db.Close()
+		g.log.Info().Msg(fmt.Sprintf("reloaded database %s", f.path))
+	}
+	f.db, f.modTime, f.size = db, info.ModTime(), info.Size()
+	return nil
+}
+
+// reloadChanged reloads the databases changed on disk once reloadInterval
+// passed since the last check. A database that fails to reload stays in use.
+// g.mu must be held.
+func (g *GeoIP) reloadChanged() {
+	now := g.now()
+	if now.Sub(g.lastCheck) < g.reloadInterval {
+		return
+	}
+	g.lastCheck = now
+	for _, f := range []*dbFile{g.city, g.asn} {
+		if f == nil {
+			continue
+		}
+		if err := g.reload(f); err != nil {
+			g.log.Error().Err(err).Msg("failed reloading database")
+		}
+	}
+}
+
+// Lookup returns the location of ip, or nil if it is in neither database.
+func (g *GeoIP) Lookup(ip netip.Addr) *notifier.Location {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reloadChanged()
+
+	location := notifier.Location{}
+	found := false
+	netIP := net.IP(ip.Unmap().AsSlice())
+	if g.city != nil {
+		record := cityRecord{}
+		if err := g.city.db.Lookup(netIP, &record); err != nil {
+			g.log.Warn().Err(err).Msg("failed looking up city")
+		} else if record.Country.IsoCode != "" || record.Location.Latitude != nil {
+			found = true
+			location.Country = record.Country.IsoCode
+			location.CountryName = record.Country.Names["en"]
+			location.City = record.City.Names["en"]
+			if record.Location.Latitude != nil && record.Location.Longitude != nil {
+				location.Coordinates = &notifier.Coordinates{
+					Latitude:         *record.Location.Latitude,
+					Longitude:        *record.Location.Longitude,
+					AccuracyRadiusKm: record.Location.AccuracyRadius,
+				}
+			}
+		}
+	}
+	if g.asn != nil {
+		record := asnRecord{}
+		if err := g.asn.db.Lookup(netIP, &record); err != nil {
+			g.log.Warn().Err(err).Msg("failed looking up ASN")
+		} else if record.AutonomousSystemNumber != 0 {
+			found = true
+			location.Asn = record.AutonomousSystemNumber
+			location.AsnOrg = record.AutonomousSystemOrganization
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &location
+}
+
+// ASN returns the autonomous system number announcing ip.
+func (g *GeoIP) ASN(ip netip.Addr) (uint, bool) {
+	location := g.Lookup(ip)
+	if location == nil || location.Asn == 0 {
+		return 0, false
+	}
+	return location.Asn, true
+}
+
+// Process implements the app stage interface.
+func (g *GeoIP) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.Location == nil {
+		if ip, err := netip.ParseAddr(logLine.IpAddress); err == nil {
+			logLine.Location = g.Lookup(ip)
+		}
+	}
+	return []notifier.LogLine{logLine}
+}
+
+// Close closes the databases.
+func (g *GeoIP) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, f := range []*dbFile{g.city, g.asn} {
+		if f != nil && f.db != nil {
+			f.db.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package geoip
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/geoip/geoipfakes"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+// cityDatabase returns a fake city database knowing 1.2.3.4 as Berlin.
+func cityDatabase() *geoipfakes.FakeDatabase {
+	return &geoipfakes.FakeDatabase{
+		LookupStub: func(ip net.IP, result any) error {
+			if !ip.Equal(net.ParseIP("1.2.3.4")) {
+				return nil
+			}
+			record := result.(*cityRecord)
+			record.City.Names = map[string]string{"en": "Berlin"}
+			record.Country.IsoCode = "DE"
+			record.Country.Names = map[string]string{"en": "Germany"}
+			record.Location.Latitude = float(52.52)
+			record.Location.Longitude = float(13.4)
+			record.Location.AccuracyRadius = 20
+			return nil
+		},
+	}
+}
+
+// asnDatabase returns a fake ASN database announcing every address from asn.
+func asnDatabase(asn uint) *geoipfakes.FakeDatabase {
+	return &geoipfakes.FakeDatabase{
+		LookupStub: func(ip net.IP, result any) error {
+			record := result.(*asnRecord)
+			record.AutonomousSystemNumber = asn
+			record.AutonomousSystemOrganization = "Example Networks"
+			return nil
+		},
+	}
+}
+
+// newTestGeoIP creates a GeoIP opening the fakes in databases by path.
+func newTestGeoIP(t *testing.T, databases map[string]*geoipfakes.FakeDatabase) (*GeoIP, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cityPath, asnPath := filepath.Join(dir, "city.mmdb"), filepath.Join(dir, "asn.mmdb")
+	for _, path := range []string{cityPath, asnPath} {
+		if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &GeoIP{
+		reloadInterval: time.Minute,
+		open: func(path string) (database, error) {
+			return databases[filepath.Base(path)], nil
+		},
+		now: time.Now,
+		log: zerolog.Nop(),
+	}
+	g.city, g.asn = &dbFile{path: cityPath}, &dbFile{path: asnPath}
+	for _, f := range []*dbFile{g.city, g.asn} {
+		if err := g.reload(f); err != nil {
+			t.Fatalf("reload() error = %v", err)
+		}
+	}
+	g.lastCheck = g.now()
+	return g, cityPath, asnPath
+}
+
+func TestGeoIP_Process(t *testing.T) {
+	g, _, _ := newTestGeoIP(t, map[string]*geoipfakes.FakeDatabase{
+		"city.mmdb": cityDatabase(),
+		"asn.mmdb":  asnDatabase(64500),
+	})
+
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    *notifier.Location
+	}{
+		{
+			name:    "city and asn",
+			logLine: notifier.LogLine{IpAddress: "1.2.3.4", EventType: notifier.LoggedIn},
+			want: &notifier.Location{
+				Country:     "DE",
+				CountryName: "Germany",
+				City:        "Berlin",
+				Coordinates: &notifier.Coordinates{Latitude: 52.52, Longitude: 13.4, AccuracyRadiusKm: 20},
+				Asn:         64500,
+				AsnOrg:      "Example Networks",
+			},
+		},
+		{
+			name:    "asn only",
+			logLine: notifier.LogLine{IpAddress: "5.6.7.8", EventType: notifier.LoggedIn},
+			want:    &notifier.Location{Asn: 64500, AsnOrg: "Example Networks"},
+		},
+		{
+			name:    "no ip address",
+			logLine: notifier.LogLine{Username: "root", EventType: notifier.BruteForceDetected},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.Process(tt.logLine)
+			if len(got) != 1 || !reflect.DeepEqual(got[0].Location, tt.want) {
+				t.Errorf("Process() = %v, want location %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoIP_LookupNotFound(t *testing.T) {
+	g, _, _ := newTestGeoIP(t, map[string]*geoipfakes.FakeDatabase{
+		"city.mmdb": cityDatabase(),
+		"asn.mmdb":  asnDatabase(0),
+	})
+	if got := g.Lookup(netip.MustParseAddr("5.6.7.8")); got != nil {
+		t.Errorf("Lookup() = %v, want nil", got)
+	}
+}
+
+func TestGeoIP_Reload(t *testing.T) {
+	oldASN, newASN := asnDatabase(64500), asnDatabase(64501)
+	databases := map[string]*geoipfakes.FakeDatabase{
+		"city.mmdb": cityDatabase(),
+		"asn.mmdb":  oldASN,
+	}
+	g, _, asnPath := newTestGeoIP(t, databases)
+	now := time.Now()
+	g.now = func() time.Time { return now }
+
+	databases["asn.mmdb"] = newASN
+	if err := os.WriteFile(asnPath, []byte("v2 with more data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ip := netip.MustParseAddr("5.6.7.8")
+	if asn, _ := g.ASN(ip); asn != 64500 {
+		t.Errorf("ASN() before reload interval = %d, want 64500", asn)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if asn, _ := g.ASN(ip); asn != 64501 {
+		t.Errorf("ASN() after reload = %d, want 64501", asn)
+	}
+	if oldASN.CloseCallCount() != 1 {
+		t.Errorf("old database closed %d times, want 1", oldASN.CloseCallCount())
+	}
+	if databases["city.mmdb"].CloseCallCount() != 0 {
+		t.Errorf("unchanged database was closed")
+	}
+}
@@ -0,0 +1,60 @@
+package geopolicy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// NewCountryPolicy creates a country policy. Users with an entry in
+// userCountries may log in from those countries, all other users from
+// countries. An empty list allows every country. Countries are ISO 3166-1
+// alpha-2 codes.
+func NewCountryPolicy(countries []string, userCountries map[string][]string) CountryPolicy {
+	c := CountryPolicy{
+		countries:     countrySet(countries),
+		userCountries: map[string]map[string]bool{},
+	}
+	for user, allowed := range userCountries {
+		c.userCountries[user] = countrySet(allowed)
+	}
+	return c
+}
+
+// CountryPolicy is an app stage emitting a DisallowedCountryLogin event after
+// a successful login from a country the user is not allowed to log in from.
+// Logins from an unknown country are not flagged.
+type CountryPolicy struct {
+	countries     map[string]bool
+	userCountries map[string]map[string]bool
+}
+
+func countrySet(countries []string) map[string]bool {
+	set := make(map[string]bool, len(countries))
+	for _, country := range countries {
+		set[strings.ToUpper(strings.TrimSpace(country))] = true
+	}
+	return set
+}
+
+// Process implements the app stage interface.
+func (c CountryPolicy) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.EventType != notifier.LoggedIn || logLine.Location == nil || logLine.Location.Country == "" {
+		return []notifier.LogLine{logLine}
+	}
+
+	allowed, ok := c.userCountries[logLine.Username]
+	if !ok {
+		allowed = c.countries
+	}
+	if len(allowed) == 0 || allowed[logLine.Location.Country] {
+		return []notifier.LogLine{logLine}
+	}
+
+	disallowed := logLine
+	disallowed.EventType = notifier.DisallowedCountryLogin
+	disallowed.Severity = notifier.DefaultSeverity(notifier.DisallowedCountryLogin)
+	disallowed.Reason = fmt.Sprintf("login from %s is outside the allowed countries", logLine.Location.Country)
+	return []notifier.LogLine{logLine, disallowed}
+}
@@ -0,0 +1,50 @@
+package geopolicy
+
+import (
+	"testing"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestCountryPolicy_Process(t *testing.T) {
+	policy := NewCountryPolicy([]string{"de", "FR"}, map[string][]string{"bob": {"US"}})
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    bool
+	}{
+		{
+			name:    "allowed country",
+			logLine: locatedLogin("alice", "1.1.1.1", berlin),
+			want:    false,
+		},
+		{
+			name:    "disallowed country",
+			logLine: locatedLogin("alice", "2.2.2.2", sydney),
+			want:    true,
+		},
+		{
+			name:    "user override",
+			logLine: locatedLogin("bob", "1.1.1.1", berlin),
+			want:    true,
+		},
+		{
+			name:    "unknown country",
+			logLine: locatedLogin("alice", "3.3.3.3", notifier.Location{Asn: 64500}),
+			want:    false,
+		},
+		{
+			name:    "failed login",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.FailedLoginAttempt, Location: &sydney},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Process(tt.logLine)
+			if flagged := len(got) == 2 && got[1].EventType == notifier.DisallowedCountryLogin; flagged != tt.want {
+				t.Errorf("Process() = %v, want disallowed %v", got, tt.want)
+			}
+		})
+	}
+}
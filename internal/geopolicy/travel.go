@@ -0,0 +1,142 @@
+// Package geopolicy flags logins whose location is implausible or not
+// allowed. It relies on events enriched with a location.
+package geopolicy
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+	"github.com/rs/zerolog"
+)
+
+// earthRadiusKm is the mean radius of the earth.
+const earthRadiusKm = 6371.0
+
+// TravelSettings configures impossible travel detection.
+type TravelSettings struct {
+	// MaxSpeedKmh is the highest plausible travel speed between two logins.
+	MaxSpeedKmh float64
+	// IgnoreRadiusKm is the distance below which logins are never flagged,
+	// absorbing geolocation inaccuracy.
+	IgnoreRadiusKm float64
+	// VpnAsns are autonomous systems of VPN or proxy providers. Logins from
+	// or to them are never flagged since their location is meaningless.
+	VpnAsns []uint
+	// StateFilePath is where the last login of each user is persisted.
+	StateFilePath string
+}
+
+// lastLogin is the most recent located login of a user.
+type lastLogin struct {
+	At        time.Time `json:"at"`
+	IpAddress string    `json:"ip_address"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	// AccuracyRadiusKm is the geolocation accuracy of the login.
+	AccuracyRadiusKm uint   `json:"accuracy_radius_km,omitempty"`
+	Asn              uint   `json:"asn,omitempty"`
+	Place            string `json:"place,omitempty"`
+}
+
+// NewImpossibleTravel creates an impossible travel detector and loads the last
+// logins persisted in settings.StateFilePath.
+func NewImpossibleTravel(settings TravelSettings, log zerolog.Logger) (*ImpossibleTravel, error) {
+	t := &ImpossibleTravel{
+		settings: settings,
+		vpnAsns:  map[uint]bool{},
+		logins:   map[string]lastLogin{},
+		now:      time.Now,
+		log:      log,
+	}
+	for _, asn := range settings.VpnAsns {
+		t.vpnAsns[asn] = true
+	}
+	if err := statefile.Load(settings.StateFilePath, &t.logins); err != nil {
+		return nil, fmt.Errorf("failed loading last logins: %w", err)
+	}
+	return t, nil
+}
+
+// ImpossibleTravel is an app stage emitting an ImpossibleTravel event after a
+// successful login when reaching its location from the previous login of the
+// user would have required travelling faster than MaxSpeedKmh.
+type ImpossibleTravel struct {
+	settings TravelSettings
+	vpnAsns  map[uint]bool
+	logins   map[string]lastLogin
+	now      func() time.Time
+	log      zerolog.Logger
+	mu       sync.Mutex
+}
+
+// Process implements the app stage interface.
+func (t *ImpossibleTravel) Process(logLine notifier.LogLine) []notifier.LogLine {
+	location := logLine.Location
+	if logLine.EventType != notifier.LoggedIn || logLine.Username == "" || location == nil || location.Coordinates == nil {
+		return []notifier.LogLine{logLine}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := logLine.OccurredAt(t.now())
+	current := lastLogin{
+		At:               now,
+		IpAddress:        logLine.IpAddress,
+		Latitude:         location.Coordinates.Latitude,
+		Longitude:        location.Coordinates.Longitude,
+		AccuracyRadiusKm: location.Coordinates.AccuracyRadiusKm,
+		Asn:              location.Asn,
+		Place:            location.String(),
+	}
+	previous, ok := t.logins[logLine.Username]
+	// Logins replayed after a restart may be older than the persisted one.
+	if ok && now.Before(previous.At) {
+		return []notifier.LogLine{logLine}
+	}
+	t.logins[logLine.Username] = current
+	if err := statefile.Save(t.settings.StateFilePath, t.logins); err != nil {
+		t.log.Error().Err(err).Msg("failed saving last logins")
+	}
+	if !ok || t.vpnAsns[current.Asn] || t.vpnAsns[previous.Asn] {
+		return []notifier.LogLine{logLine}
+	}
+
+	distance := haversineKm(previous.Latitude, previous.Longitude, current.Latitude, current.Longitude)
+	// Both locations may be off by their accuracy radius.
+	distance -= float64(previous.AccuracyRadiusKm + current.AccuracyRadiusKm)
+	if distance <= t.settings.IgnoreRadiusKm {
+		return []notifier.LogLine{logLine}
+	}
+	elapsed := now.Sub(previous.At)
+	speed := math.Inf(1)
+	if elapsed > 0 {
+		speed = distance / elapsed.Hours()
+	}
+	if speed <= t.settings.MaxSpeedKmh {
+		return []notifier.LogLine{logLine}
+	}
+
+	travel := logLine
+	travel.EventType = notifier.ImpossibleTravel
+	travel.Severity = notifier.DefaultSeverity(notifier.ImpossibleTravel)
+	travel.IpAddresses = []string{previous.IpAddress, logLine.IpAddress}
+	travel.Elapsed = elapsed.Round(time.Second).String()
+	travel.Reason = fmt.Sprintf("%.0f km from the previous login from %s (%s) %s earlier, %.0f km/h",
+		distance, previous.IpAddress, previous.Place, travel.Elapsed, speed)
+	return []notifier.LogLine{logLine, travel}
+}
+
+// haversineKm returns the great circle distance between two coordinates.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
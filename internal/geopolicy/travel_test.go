@@ -0,0 +1,168 @@
+package geopolicy
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+var (
+	berlin  = notifier.Location{Country: "DE", City: "Berlin", Coordinates: &notifier.Coordinates{Latitude: 52.52, Longitude: 13.405}}
+	potsdam = notifier.Location{Country: "DE", City: "Potsdam", Coordinates: &notifier.Coordinates{Latitude: 52.39, Longitude: 13.065}}
+	sydney  = notifier.Location{Country: "AU", City: "Sydney", Coordinates: &notifier.Coordinates{Latitude: -33.87, Longitude: 151.21}}
+	vpn     = notifier.Location{Country: "AU", City: "Sydney", Coordinates: &notifier.Coordinates{Latitude: -33.87, Longitude: 151.21}, Asn: 64512}
+)
+
+func locatedLogin(user, ip string, location notifier.Location) notifier.LogLine {
+	return notifier.LogLine{
+		Username:  user,
+		IpAddress: ip,
+		EventType: notifier.LoggedIn,
+		Severity:  notifier.SeverityMedium,
+		Location:  &location,
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	// Berlin to Sydney is about 16,000 km.
+	if got := haversineKm(52.52, 13.405, -33.87, 151.21); math.Abs(got-16090) > 50 {
+		t.Errorf("haversineKm() = %.0f, want about 16090", got)
+	}
+	if got := haversineKm(52.52, 13.405, 52.52, 13.405); got != 0 {
+		t.Errorf("haversineKm() same point = %f, want 0", got)
+	}
+}
+
+func TestImpossibleTravel_Process(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		first   notifier.LogLine
+		second  notifier.LogLine
+		elapsed time.Duration
+		want    bool
+	}{
+		{
+			name:    "other side of the world within an hour",
+			first:   locatedLogin("alice", "1.1.1.1", berlin),
+			second:  locatedLogin("alice", "2.2.2.2", sydney),
+			elapsed: time.Hour,
+			want:    true,
+		},
+		{
+			name:    "other side of the world after a day",
+			first:   locatedLogin("alice", "1.1.1.1", berlin),
+			second:  locatedLogin("alice", "2.2.2.2", sydney),
+			elapsed: 24 * time.Hour,
+			want:    false,
+		},
+		{
+			name:    "within the ignore radius",
+			first:   locatedLogin("alice", "1.1.1.1", berlin),
+			second:  locatedLogin("alice", "2.2.2.2", potsdam),
+			elapsed: time.Minute,
+			want:    false,
+		},
+		{
+			name:    "vpn asn",
+			first:   locatedLogin("alice", "1.1.1.1", berlin),
+			second:  locatedLogin("alice", "2.2.2.2", vpn),
+			elapsed: time.Minute,
+			want:    false,
+		},
+		{
+			name:    "different users",
+			first:   locatedLogin("alice", "1.1.1.1", berlin),
+			second:  locatedLogin("bob", "2.2.2.2", sydney),
+			elapsed: time.Minute,
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			travel, err := NewImpossibleTravel(TravelSettings{
+				MaxSpeedKmh:    1000,
+				IgnoreRadiusKm: 100,
+				VpnAsns:        []uint{64512},
+				StateFilePath:  filepath.Join(t.TempDir(), "travel.json"),
+			}, zerolog.Nop())
+			if err != nil {
+				t.Fatalf("NewImpossibleTravel() error = %v", err)
+			}
+			now := start
+			travel.now = func() time.Time { return now }
+
+			if got := travel.Process(tt.first); len(got) != 1 {
+				t.Fatalf("Process() first login = %v, want passed through", got)
+			}
+			now = now.Add(tt.elapsed)
+			got := travel.Process(tt.second)
+			if got[0].EventType != notifier.LoggedIn {
+				t.Errorf("Process() = %v, want login passed through first", got)
+			}
+			if flagged := len(got) == 2 && got[1].EventType == notifier.ImpossibleTravel; flagged != tt.want {
+				t.Errorf("Process() = %v, want impossible travel %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpossibleTravel_PersistsLastLogins(t *testing.T) {
+	settings := TravelSettings{
+		MaxSpeedKmh:   1000,
+		StateFilePath: filepath.Join(t.TempDir(), "travel.json"),
+	}
+	first, err := NewImpossibleTravel(settings, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewImpossibleTravel() error = %v", err)
+	}
+	first.Process(locatedLogin("alice", "1.1.1.1", berlin))
+
+	restarted, err := NewImpossibleTravel(settings, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewImpossibleTravel() error = %v", err)
+	}
+	got := restarted.Process(locatedLogin("alice", "2.2.2.2", sydney))
+	if len(got) != 2 || got[1].EventType != notifier.ImpossibleTravel {
+		t.Fatalf("Process() after restart = %v, want impossible travel", got)
+	}
+	if want := []string{"1.1.1.1", "2.2.2.2"}; got[1].IpAddresses[0] != want[0] || got[1].IpAddresses[1] != want[1] {
+		t.Errorf("Process() IP addresses = %v, want %v", got[1].IpAddresses, want)
+	}
+}
+
+func TestImpossibleTravel_ProcessReplay(t *testing.T) {
+	travel, err := NewImpossibleTravel(TravelSettings{
+		MaxSpeedKmh:   1000,
+		StateFilePath: filepath.Join(t.TempDir(), "travel.json"),
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewImpossibleTravel() error = %v", err)
+	}
+	// Every line is processed at the same time; elapsed time comes from the
+	// timestamps of the lines.
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	travel.now = func() time.Time { return start.Add(72 * time.Hour) }
+	at := func(login notifier.LogLine, elapsed time.Duration) notifier.LogLine {
+		login.Time = start.Add(elapsed)
+		return login
+	}
+
+	travel.Process(at(locatedLogin("alice", "1.1.1.1", berlin), 0))
+	if got := travel.Process(at(locatedLogin("alice", "2.2.2.2", sydney), 48*time.Hour)); len(got) != 1 {
+		t.Errorf("Process() login two days later = %v, want passed through", got)
+	}
+	// An older line replayed after a restart is not compared to the newer
+	// login.
+	if got := travel.Process(at(locatedLogin("alice", "1.1.1.1", berlin), time.Hour)); len(got) != 1 {
+		t.Errorf("Process() older login = %v, want passed through", got)
+	}
+	got := travel.Process(at(locatedLogin("alice", "1.1.1.1", berlin), 49*time.Hour))
+	if len(got) != 2 || got[1].EventType != notifier.ImpossibleTravel || got[1].Elapsed != "1h0m0s" {
+		t.Errorf("Process() login an hour later = %v, want impossible travel after 1h0m0s", got)
+	}
+}
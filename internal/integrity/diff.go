@@ -0,0 +1,39 @@
+package integrity
+
+// diffLines returns the lines removed from before, prefixed with "- ", and
+// added in after, prefixed with "+ ", in the order of the files. Unchanged
+// lines are left out.
+func diffLines(before, after []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// before[i:] and after[j:].
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			i++
+			j++
+		case j < len(after) && (i == len(before) || lcs[i][j+1] >= lcs[i+1][j]):
+			diff = append(diff, "+ "+after[j])
+			j++
+		default:
+			diff = append(diff, "- "+before[i])
+			i++
+		}
+	}
+	return diff
+}
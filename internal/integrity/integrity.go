@@ -0,0 +1,249 @@
+// Package integrity detects changes to the files controlling who can log in
+// over SSH: authorized_keys files and the sshd configuration.
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+	"github.com/rs/zerolog"
+)
+
+// notifierClient is an interface for sending notifications
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . notifierClient
+type notifierClient interface {
+	Notify(LogLine notifier.LogLine) error
+}
+
+// Settings configures the monitor.
+type Settings struct {
+	// HostMachine is reported as the host of change events.
+	HostMachine string
+	// AuthorizedKeys are glob patterns of authorized_keys files.
+	AuthorizedKeys []string
+	// SshdConfig are glob patterns of sshd configuration files.
+	SshdConfig []string
+	// StateFilePath is the location of the file the last snapshot is
+	// persisted in.
+	StateFilePath string
+}
+
+// New creates a monitor comparing the files against the snapshot persisted
+// at settings.StateFilePath and reporting changes through client.
+func New(client notifierClient, settings Settings, log zerolog.Logger) (*Monitor, error) {
+	patterns := map[Kind][]string{
+		AuthorizedKeys: settings.AuthorizedKeys,
+		SshdConfig:     settings.SshdConfig,
+	}
+	for _, kindPatterns := range patterns {
+		for _, pattern := range kindPatterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid integrity pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	m := &Monitor{
+		notifier: client,
+		settings: settings,
+		patterns: patterns,
+		now:      time.Now,
+		log:      log,
+	}
+	if err := statefile.Load(settings.StateFilePath, &m.snapshot); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Monitor snapshots the monitored files and reports the keys added and
+// removed and the sshd configuration changes since the previous snapshot.
+type Monitor struct {
+	notifier notifierClient
+	settings Settings
+	patterns map[Kind][]string
+	snapshot Snapshot
+	now      func() time.Time
+	log      zerolog.Logger
+	mu       sync.Mutex
+}
+
+// Check takes a new snapshot, reports the changes since the previous one and
+// persists it. The first snapshot is only recorded.
+func (m *Monitor) Check() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, errs := take(m.patterns)
+	for _, err := range errs {
+		m.log.Error().Err(err).Msg("failed snapshotting file")
+	}
+	// Files that could not be read keep their previous state so they are
+	// not reported as removed.
+	for path := range errs {
+		if file, ok := m.snapshot[path]; ok {
+			current[path] = file
+		}
+	}
+	if m.snapshot != nil {
+		for _, event := range m.changes(m.snapshot, current) {
+			if err := m.notifier.Notify(event); err != nil {
+				m.log.Error().Err(err).Msg(fmt.Sprintf("failed reporting %s", event.EventType))
+			}
+		}
+	}
+	m.snapshot = current
+	if err := statefile.Save(m.settings.StateFilePath, m.snapshot); err != nil {
+		m.log.Error().Err(err).Msg("failed persisting integrity snapshot")
+	}
+}
+
+// Run checks the files now and then every interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	m.Check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// changes returns the events describing the changes from before to after.
+func (m *Monitor) changes(before, after Snapshot) []notifier.LogLine {
+	var paths []string
+	for path := range before {
+		paths = append(paths, path)
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var events []notifier.LogLine
+	for _, path := range paths {
+		previous, existed := before[path]
+		file, exists := after[path]
+		if existed && exists && previous.Hash == file.Hash {
+			continue
+		}
+		kind := file.Kind
+		if !exists {
+			kind = previous.Kind
+		}
+		switch kind {
+		case AuthorizedKeys:
+			events = append(events, m.keyChanges(path, previous.Keys, file.Keys)...)
+		case SshdConfig:
+			if event, ok := m.configChange(path, previous.Lines, file.Lines, existed, exists); ok {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// keyChanges reports the keys added to and removed from an authorized_keys
+// file, and keys whose options changed.
+func (m *Monitor) keyChanges(path string, before, after []Key) []notifier.LogLine {
+	count := func(keys []Key) map[Key]int {
+		counts := map[Key]int{}
+		for _, key := range keys {
+			counts[key]++
+		}
+		return counts
+	}
+	previous, current := count(before), count(after)
+
+	var added, removed []Key
+	for _, key := range after {
+		if previous[key] > 0 {
+			previous[key]--
+			continue
+		}
+		added = append(added, key)
+	}
+	for _, key := range before {
+		if current[key] > 0 {
+			current[key]--
+			continue
+		}
+		removed = append(removed, key)
+	}
+
+	var events []notifier.LogLine
+	for _, key := range added {
+		// A removed key with the same fingerprint is the same key with
+		// other options or another comment.
+		i := slices.IndexFunc(removed, func(r Key) bool { return r.Fingerprint == key.Fingerprint })
+		if i < 0 {
+			events = append(events, m.keyEvent(notifier.AuthorizedKeyAdded, path, key, "+ "+key.String()))
+			continue
+		}
+		events = append(events, m.keyEvent(notifier.AuthorizedKeyChanged, path, key, "- "+removed[i].String()+"\n+ "+key.String()))
+		removed = slices.Delete(removed, i, i+1)
+	}
+	for _, key := range removed {
+		events = append(events, m.keyEvent(notifier.AuthorizedKeyRemoved, path, key, "- "+key.String()))
+	}
+	return events
+}
+
+func (m *Monitor) keyEvent(eventType notifier.EventType, path string, key Key, diff string) notifier.LogLine {
+	action := "added to"
+	switch eventType {
+	case notifier.AuthorizedKeyRemoved:
+		action = "removed from"
+	case notifier.AuthorizedKeyChanged:
+		action = "changed in"
+	}
+	return notifier.LogLine{
+		LoginTime:      m.now().Format(time.RFC3339),
+		EventType:      eventType,
+		HostMachine:    m.settings.HostMachine,
+		Severity:       notifier.DefaultSeverity(eventType),
+		KeyFingerprint: key.Fingerprint,
+		KeyOwner:       key.Comment,
+		Reason:         fmt.Sprintf("key %s %s", action, path),
+		Diff:           diff,
+	}
+}
+
+// configChange reports a change to an sshd configuration file. Changes only
+// to comments or blank lines are not reported.
+func (m *Monitor) configChange(path string, before, after []string, existed, exists bool) (notifier.LogLine, bool) {
+	diff := diffLines(before, after)
+	var reason string
+	switch {
+	case !existed:
+		reason = fmt.Sprintf("%s created", path)
+	case !exists:
+		reason = fmt.Sprintf("%s removed", path)
+	case len(diff) == 0:
+		return notifier.LogLine{}, false
+	default:
+		reason = fmt.Sprintf("%s changed", path)
+	}
+	return notifier.LogLine{
+		LoginTime:   m.now().Format(time.RFC3339),
+		EventType:   notifier.SshdConfigChanged,
+		HostMachine: m.settings.HostMachine,
+		Severity:    notifier.DefaultSeverity(notifier.SshdConfigChanged),
+		Reason:      reason,
+		Diff:        strings.Join(diff, "\n"),
+	}, true
+}
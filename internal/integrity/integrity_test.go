@@ -0,0 +1,173 @@
+package integrity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/integrity/integrityfakes"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+const (
+	aliceKey         = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFfazeth0HwI8bg3Ar8JFC8WbyAK0bn8chJsdEgjZqQT alice@example.com"
+	aliceFingerprint = "SHA256:EyPbA3Vk4amzEU1gc/bbvZplJ5gNs5HQmFDfWHI8AcY"
+)
+
+var start = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reported returns the event types, reasons and diffs reported through client.
+func reported(client *integrityfakes.FakeNotifierClient) []string {
+	var events []string
+	for i := 0; i < client.NotifyCallCount(); i++ {
+		logLine := client.NotifyArgsForCall(i)
+		events = append(events, fmt.Sprintf("%s: %s\n%s", logLine.EventType, logLine.Reason, logLine.Diff))
+	}
+	return events
+}
+
+func newTestMonitor(t *testing.T, dir string, client *integrityfakes.FakeNotifierClient) *Monitor {
+	t.Helper()
+	m, err := New(client, Settings{
+		HostMachine:    "web-1",
+		AuthorizedKeys: []string{filepath.Join(dir, "home", "*", ".ssh", "authorized_keys")},
+		SshdConfig:     []string{filepath.Join(dir, "ssh", "sshd_config"), filepath.Join(dir, "ssh", "sshd_config.d", "*")},
+		StateFilePath:  filepath.Join(dir, "state", "integrity.json"),
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	m.now = func() time.Time { return start }
+	return m
+}
+
+func TestMonitor_Check(t *testing.T) {
+	dir := t.TempDir()
+	deployKeys := filepath.Join(dir, "home", "deploy", ".ssh", "authorized_keys")
+	sshdConfig := filepath.Join(dir, "ssh", "sshd_config")
+	writeFile(t, deployKeys, "")
+	writeFile(t, sshdConfig, "# hardened\nPermitRootLogin no\nPasswordAuthentication no\n")
+
+	client := &integrityfakes.FakeNotifierClient{}
+	m := newTestMonitor(t, dir, client)
+	m.Check()
+	if client.NotifyCallCount() != 0 {
+		t.Fatalf("reported = %v, want the first snapshot only recorded", reported(client))
+	}
+
+	writeFile(t, deployKeys, aliceKey+"\n")
+	writeFile(t, sshdConfig, "# hardened, really\nPermitRootLogin yes\nPasswordAuthentication no\n")
+	writeFile(t, filepath.Join(dir, "ssh", "sshd_config.d", "50-cloud.conf"), "PasswordAuthentication yes\n")
+
+	// A restarted monitor compares against the persisted snapshot.
+	m = newTestMonitor(t, dir, client)
+	m.Check()
+	want := []string{
+		"authorized key added: key added to " + deployKeys + "\n+ ssh-ed25519 " + aliceFingerprint + " alice@example.com",
+		"sshd config changed: " + sshdConfig + " changed\n+ PermitRootLogin yes\n- PermitRootLogin no",
+		"sshd config changed: " + filepath.Join(dir, "ssh", "sshd_config.d", "50-cloud.conf") + " created\n+ PasswordAuthentication yes",
+	}
+	if got := reported(client); !reflect.DeepEqual(got, want) {
+		t.Errorf("reported = %q, want %q", got, want)
+	}
+	added := client.NotifyArgsForCall(0)
+	if added.KeyFingerprint != aliceFingerprint || added.KeyOwner != "alice@example.com" || added.HostMachine != "web-1" || added.Severity != notifier.SeverityHigh {
+		t.Errorf("added event = %+v, want the fingerprint and owner of the key", added)
+	}
+
+	// Comment only changes and unchanged files are not reported.
+	writeFile(t, sshdConfig, "# hardened again\nPermitRootLogin yes\nPasswordAuthentication no\n")
+	m.Check()
+	if got := client.NotifyCallCount(); got != 3 {
+		t.Fatalf("reported = %v, want no new events", reported(client))
+	}
+
+	if err := os.Remove(deployKeys); err != nil {
+		t.Fatal(err)
+	}
+	m.Check()
+	wantRemoved := "authorized key removed: key removed from " + deployKeys + "\n- ssh-ed25519 " + aliceFingerprint + " alice@example.com"
+	if got := reported(client); len(got) != 4 || got[3] != wantRemoved {
+		t.Errorf("reported = %q, want %q last", got, wantRemoved)
+	}
+}
+
+func TestMonitor_CheckKeyOptions(t *testing.T) {
+	dir := t.TempDir()
+	deployKeys := filepath.Join(dir, "home", "deploy", ".ssh", "authorized_keys")
+	writeFile(t, deployKeys, `from="10.0.0.0/8" `+aliceKey+"\n")
+
+	client := &integrityfakes.FakeNotifierClient{}
+	m := newTestMonitor(t, dir, client)
+	m.Check()
+
+	// Dropping the source restriction of a key is reported as a change.
+	writeFile(t, deployKeys, aliceKey+"\n")
+	m.Check()
+	want := []string{
+		"authorized key changed: key changed in " + deployKeys + "\n" +
+			`- from="10.0.0.0/8" ssh-ed25519 ` + aliceFingerprint + " alice@example.com\n" +
+			"+ ssh-ed25519 " + aliceFingerprint + " alice@example.com",
+	}
+	if got := reported(client); !reflect.DeepEqual(got, want) {
+		t.Errorf("reported = %q, want %q", got, want)
+	}
+	if got := client.NotifyArgsForCall(0).Severity; got != notifier.SeverityHigh {
+		t.Errorf("severity = %s, want high", got)
+	}
+}
+
+func TestMonitor_CheckKeepsUnreadableFiles(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read files without permission")
+	}
+	dir := t.TempDir()
+	deployKeys := filepath.Join(dir, "home", "deploy", ".ssh", "authorized_keys")
+	writeFile(t, deployKeys, aliceKey+"\n")
+
+	client := &integrityfakes.FakeNotifierClient{}
+	m := newTestMonitor(t, dir, client)
+	m.Check()
+	if err := os.Chmod(deployKeys, 0); err != nil {
+		t.Fatal(err)
+	}
+	m.Check()
+	if client.NotifyCallCount() != 0 {
+		t.Errorf("reported = %v, want no removal for an unreadable file", reported(client))
+	}
+}
+
+func Test_diffLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []string
+		want          []string
+	}{
+		{name: "unchanged", before: []string{"a", "b"}, after: []string{"a", "b"}},
+		{name: "added", before: []string{"a", "c"}, after: []string{"a", "b", "c"}, want: []string{"+ b"}},
+		{name: "removed", before: []string{"a", "b", "c"}, after: []string{"a", "c"}, want: []string{"- b"}},
+		{name: "replaced", before: []string{"a", "b"}, after: []string{"a", "x"}, want: []string{"+ x", "- b"}},
+		{name: "created", after: []string{"a"}, want: []string{"+ a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
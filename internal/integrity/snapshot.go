@@ -0,0 +1,113 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mgla96/ssh-watcher/internal/keyindex"
+)
+
+// Kind is the kind of a monitored file.
+type Kind string
+
+const (
+	AuthorizedKeys Kind = "authorized_keys"
+	SshdConfig     Kind = "sshd_config"
+)
+
+// Snapshot maps the paths of the monitored files to their state.
+type Snapshot map[string]File
+
+// File is the state of a monitored file.
+type File struct {
+	Kind Kind `json:"kind"`
+	// Hash is the SHA256 hash of the file content.
+	Hash string `json:"hash"`
+	// Keys are the keys of an authorized_keys file.
+	Keys []Key `json:"keys,omitempty"`
+	// Lines are the lines of an sshd config file without blank lines and
+	// comments.
+	Lines []string `json:"lines,omitempty"`
+}
+
+// Key is a key listed in an authorized_keys file.
+type Key struct {
+	// Options restrict the key, e.g. from="10.0.0.0/8".
+	Options     string `json:"options,omitempty"`
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+func (k Key) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s %s", k.Options, k.Type, k.Fingerprint, k.Comment))
+}
+
+// take snapshots the files matching the glob patterns of each kind. Files
+// that cannot be read are left out and returned with their error.
+func take(patterns map[Kind][]string) (Snapshot, map[string]error) {
+	snapshot := Snapshot{}
+	errs := map[string]error{}
+	for _, kind := range []Kind{AuthorizedKeys, SshdConfig} {
+		for _, pattern := range patterns[kind] {
+			// The patterns were validated in New.
+			matches, _ := filepath.Glob(pattern)
+			for _, path := range matches {
+				if _, ok := snapshot[path]; ok {
+					continue
+				}
+				file, err := snapshotFile(kind, path)
+				if err != nil {
+					errs[path] = err
+					continue
+				}
+				if file != nil {
+					snapshot[path] = *file
+				}
+			}
+		}
+	}
+	return snapshot, errs
+}
+
+// snapshotFile returns the state of the file at path, or nil for a
+// directory.
+func snapshotFile(kind Kind, path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s: %w", path, err)
+	}
+	sum := sha256.Sum256(content)
+	file := &File{Kind: kind, Hash: hex.EncodeToString(sum[:])}
+
+	switch kind {
+	case AuthorizedKeys:
+		keys, err := keyindex.ReadKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			file.Keys = append(file.Keys, Key{Options: key.Options, Type: key.Type, Fingerprint: key.Fingerprints[0], Comment: key.Comment})
+		}
+	case SshdConfig:
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			file.Lines = append(file.Lines, line)
+		}
+	}
+	return file, nil
+}
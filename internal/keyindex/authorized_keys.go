@@ -0,0 +1,139 @@
+package keyindex
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Key is a public key listed in an authorized_keys file.
+type Key struct {
+	// Options are the options preceding the key, e.g. from="10.0.0.0/8".
+	Options string
+	// Type is the key type, e.g. ssh-ed25519.
+	Type string
+	// Comment is the comment following the key, usually naming its owner.
+	Comment string
+	// Path is the file listing the key.
+	Path string
+	// Fingerprints are the SHA256 and MD5 fingerprints of the key in the
+	// format sshd logs them, e.g. SHA256:....
+	Fingerprints []string
+}
+
+// Owner returns who the key belongs to: its comment, or the file listing it
+// for keys without one.
+func (k Key) Owner() string {
+	if k.Comment != "" {
+		return k.Comment
+	}
+	return k.Path
+}
+
+// fingerprints returns the SHA256 and MD5 fingerprints of a key blob.
+func fingerprints(blob []byte) []string {
+	sha := sha256.Sum256(blob)
+	sum := md5.Sum(blob)
+	md5Hex := hex.EncodeToString(sum[:])
+	var pairs []string
+	for i := 0; i < len(md5Hex); i += 2 {
+		pairs = append(pairs, md5Hex[i:i+2])
+	}
+	return []string{
+		"SHA256:" + base64.RawStdEncoding.EncodeToString(sha[:]),
+		"MD5:" + strings.Join(pairs, ":"),
+	}
+}
+
+// isKeyType reports whether field names a public key type.
+func isKeyType(field string) bool {
+	for _, prefix := range []string{"ssh-", "ecdsa-sha2-", "sk-ssh-", "sk-ecdsa-sha2-"} {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFields splits an authorized_keys line at unquoted whitespace, so
+// options such as command="a b" stay one field.
+func splitFields(line string) []string {
+	var fields []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// parseKey parses an authorized_keys line with optional leading options.
+// Comments, blank lines and lines without a valid key are skipped.
+func parseKey(line string) (Key, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Key{}, false
+	}
+	fields := splitFields(line)
+	for i := 0; i+1 < len(fields); i++ {
+		if !isKeyType(fields[i]) {
+			continue
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+		if err != nil {
+			continue
+		}
+		return Key{
+			Options:      strings.Join(fields[:i], " "),
+			Type:         fields[i],
+			Comment:      strings.Join(fields[i+2:], " "),
+			Fingerprints: fingerprints(blob),
+		}, true
+	}
+	return Key{}, false
+}
+
+// ReadKeys returns the keys listed in the authorized_keys file at path.
+func ReadKeys(path string) ([]Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading authorized keys: %w", err)
+	}
+	defer f.Close()
+
+	var keys []Key
+	scanner := bufio.NewScanner(f)
+	// Keys with many options or large RSA keys exceed the default limit.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		key, ok := parseKey(scanner.Text())
+		if !ok {
+			continue
+		}
+		key.Path = path
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading authorized keys %s: %w", path, err)
+	}
+	return keys, nil
+}
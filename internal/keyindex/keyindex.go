@@ -0,0 +1,151 @@
+// Package keyindex attributes public key logins to the owner of the key by
+// indexing authorized_keys files.
+package keyindex
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// New indexes the authorized_keys files matching the glob patterns. The
+// files are checked for changes at most every reloadInterval.
+func New(patterns []string, reloadInterval time.Duration, log zerolog.Logger) (*Index, error) {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid authorized keys pattern %q: %w", pattern, err)
+		}
+	}
+	i := &Index{
+		patterns:       patterns,
+		reloadInterval: reloadInterval,
+		now:            time.Now,
+		log:            log,
+	}
+	i.load()
+	i.lastCheck = i.now()
+	return i, nil
+}
+
+// Index is an app stage attaching the owner of the key used to public key
+// logins. Logins with a key missing from every file raise an unknown key used
+// event.
+type Index struct {
+	patterns       []string
+	keys           map[string]Key
+	modTimes       map[string]time.Time
+	reloadInterval time.Duration
+	lastCheck      time.Time
+	now            func() time.Time
+	log            zerolog.Logger
+	mu             sync.Mutex
+}
+
+// paths returns the files matching the patterns with their modification time.
+func (i *Index) paths() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	for _, pattern := range i.patterns {
+		// The patterns were validated in New.
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// load rebuilds the index. Files that cannot be read are logged and skipped.
+// i.mu must be held.
+func (i *Index) load() {
+	modTimes := i.paths()
+	paths := make([]string, 0, len(modTimes))
+	for path := range modTimes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	keys := map[string]Key{}
+	for _, path := range paths {
+		fileKeys, err := ReadKeys(path)
+		if err != nil {
+			i.log.Error().Err(err).Msg("skipping authorized keys file")
+			continue
+		}
+		for _, key := range fileKeys {
+			for _, fingerprint := range key.Fingerprints {
+				if _, ok := keys[fingerprint]; !ok {
+					keys[fingerprint] = key
+				}
+			}
+		}
+	}
+	i.keys, i.modTimes = keys, modTimes
+	i.log.Info().Msg(fmt.Sprintf("indexed %d authorized keys files", len(paths)))
+}
+
+// reloadChanged rebuilds the index once reloadInterval passed since the last
+// check if a file changed, appeared or disappeared. i.mu must be held.
+func (i *Index) reloadChanged() {
+	now := i.now()
+	if now.Sub(i.lastCheck) < i.reloadInterval {
+		return
+	}
+	i.lastCheck = now
+
+	modTimes := i.paths()
+	changed := len(modTimes) != len(i.modTimes)
+	for path, modTime := range modTimes {
+		if previous, ok := i.modTimes[path]; !ok || !previous.Equal(modTime) {
+			changed = true
+			break
+		}
+	}
+	if changed {
+		i.load()
+	}
+}
+
+// Lookup returns the key with the fingerprint.
+func (i *Index) Lookup(fingerprint string) (Key, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.reloadChanged()
+	key, ok := i.keys[fingerprint]
+	return key, ok
+}
+
+// Process implements the app stage interface.
+func (i *Index) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.EventType != notifier.LoggedIn || logLine.KeyFingerprint == "" {
+		return []notifier.LogLine{logLine}
+	}
+	if key, ok := i.Lookup(logLine.KeyFingerprint); ok {
+		logLine.KeyOwner = key.Owner()
+		return []notifier.LogLine{logLine}
+	}
+
+	unknown := notifier.LogLine{
+		Username:       logLine.Username,
+		IpAddress:      logLine.IpAddress,
+		Location:       logLine.Location,
+		ThreatFeeds:    logLine.ThreatFeeds,
+		LoginTime:      logLine.LoginTime,
+		EventType:      notifier.UnknownKeyUsed,
+		HostMachine:    logLine.HostMachine,
+		Severity:       notifier.DefaultSeverity(notifier.UnknownKeyUsed),
+		AuthMethod:     logLine.AuthMethod,
+		KeyFingerprint: logLine.KeyFingerprint,
+		Reason:         "key not listed in any indexed authorized keys file",
+	}
+	return []notifier.LogLine{logLine, unknown}
+}
@@ -0,0 +1,172 @@
+package keyindex
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+const (
+	aliceKey         = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFfazeth0HwI8bg3Ar8JFC8WbyAK0bn8chJsdEgjZqQT alice@example.com"
+	aliceFingerprint = "SHA256:EyPbA3Vk4amzEU1gc/bbvZplJ5gNs5HQmFDfWHI8AcY"
+	aliceMD5         = "MD5:7a:02:78:c2:cb:0e:c0:41:7b:fc:98:85:50:f8:42:14"
+)
+
+var start = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_parseKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantOk      bool
+		wantOptions string
+		wantType    string
+		wantComment string
+	}{
+		{name: "key with comment", line: aliceKey, wantOk: true, wantType: "ssh-ed25519", wantComment: "alice@example.com"},
+		{
+			name:        "options with quoted spaces",
+			line:        `from="10.0.0.0/8",command="/usr/bin/rsync --server" ` + aliceKey,
+			wantOk:      true,
+			wantOptions: `from="10.0.0.0/8",command="/usr/bin/rsync --server"`,
+			wantType:    "ssh-ed25519",
+			wantComment: "alice@example.com",
+		},
+		{name: "comment line", line: "# " + aliceKey},
+		{name: "invalid key", line: "ssh-ed25519 not-base64! bob"},
+		{name: "blank line", line: "   "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := parseKey(tt.line)
+			if ok != tt.wantOk {
+				t.Fatalf("parseKey() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if key.Options != tt.wantOptions || key.Type != tt.wantType || key.Comment != tt.wantComment {
+				t.Errorf("parseKey() = %+v, want options %s, type %s and comment %s", key, tt.wantOptions, tt.wantType, tt.wantComment)
+			}
+			if want := []string{aliceFingerprint, aliceMD5}; !reflect.DeepEqual(key.Fingerprints, want) {
+				t.Errorf("Fingerprints = %v, want %v", key.Fingerprints, want)
+			}
+		})
+	}
+}
+
+func TestIndex_Process(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "home", "deploy", ".ssh", "authorized_keys"), "# deploy keys\n"+aliceKey+"\n")
+
+	index, err := New([]string{filepath.Join(dir, "home", "*", ".ssh", "authorized_keys")}, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    []notifier.LogLine
+	}{
+		{
+			name:    "known key",
+			logLine: notifier.LogLine{Username: "deploy", EventType: notifier.LoggedIn, KeyFingerprint: aliceFingerprint},
+			want:    []notifier.LogLine{{Username: "deploy", EventType: notifier.LoggedIn, KeyFingerprint: aliceFingerprint, KeyOwner: "alice@example.com"}},
+		},
+		{
+			name:    "known key by MD5 fingerprint",
+			logLine: notifier.LogLine{Username: "deploy", EventType: notifier.LoggedIn, KeyFingerprint: aliceMD5},
+			want:    []notifier.LogLine{{Username: "deploy", EventType: notifier.LoggedIn, KeyFingerprint: aliceMD5, KeyOwner: "alice@example.com"}},
+		},
+		{
+			name:    "unknown key",
+			logLine: notifier.LogLine{Username: "deploy", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, AuthMethod: "publickey", KeyFingerprint: "SHA256:unknown"},
+			want: []notifier.LogLine{
+				{Username: "deploy", IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, AuthMethod: "publickey", KeyFingerprint: "SHA256:unknown"},
+				{
+					Username:       "deploy",
+					IpAddress:      "1.2.3.4",
+					EventType:      notifier.UnknownKeyUsed,
+					Severity:       notifier.SeverityHigh,
+					AuthMethod:     "publickey",
+					KeyFingerprint: "SHA256:unknown",
+					Reason:         "key not listed in any indexed authorized keys file",
+				},
+			},
+		},
+		{
+			name:    "password login",
+			logLine: notifier.LogLine{Username: "deploy", EventType: notifier.LoggedIn, AuthMethod: "password"},
+			want:    []notifier.LogLine{{Username: "deploy", EventType: notifier.LoggedIn, AuthMethod: "password"}},
+		},
+		{
+			name:    "failed login",
+			logLine: notifier.LogLine{Username: "deploy", EventType: notifier.FailedLoginAttempt, KeyFingerprint: "SHA256:unknown"},
+			want:    []notifier.LogLine{{Username: "deploy", EventType: notifier.FailedLoginAttempt, KeyFingerprint: "SHA256:unknown"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := index.Process(tt.logLine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Process() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_ReloadsChanges(t *testing.T) {
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "*", "authorized_keys")
+	index, err := New([]string{pattern}, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := start
+	index.now = func() time.Time { return now }
+	index.lastCheck = now
+
+	if _, ok := index.Lookup(aliceFingerprint); ok {
+		t.Fatal("Lookup() found key before any file exists")
+	}
+
+	// A new file is picked up once the reload interval passed.
+	writeFile(t, filepath.Join(dir, "deploy", "authorized_keys"), aliceKey+"\n")
+	if _, ok := index.Lookup(aliceFingerprint); ok {
+		t.Error("Lookup() found key before the reload interval passed")
+	}
+	now = start.Add(time.Minute)
+	if key, ok := index.Lookup(aliceFingerprint); !ok || key.Owner() != "alice@example.com" {
+		t.Errorf("Lookup() = %+v, %v, want the key of alice", key, ok)
+	}
+
+	// A removed file drops its keys.
+	if err := os.Remove(filepath.Join(dir, "deploy", "authorized_keys")); err != nil {
+		t.Fatal(err)
+	}
+	now = start.Add(2 * time.Minute)
+	if _, ok := index.Lookup(aliceFingerprint); ok {
+		t.Error("Lookup() found key of a removed file")
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New([]string{"/home/[/authorized_keys"}, time.Minute, zerolog.Nop()); err == nil {
+		t.Error("New() error = nil, want invalid pattern")
+	}
+}
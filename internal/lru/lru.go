@@ -0,0 +1,72 @@
+// Package lru implements a fixed capacity map evicting the least recently
+// used entry.
+package lru
+
+import "container/list"
+
+// Cache is a fixed capacity map that evicts the least recently used entry when
+// full. It bounds the memory used to track sources during an attack. A Cache
+// is not safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	capacity int
+	order    *list.List
+	items    map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New creates a cache holding at most capacity entries, or any number if
+// capacity is not positive.
+func New[K comparable, V any](capacity int) *Cache[K, V] {
+	return &Cache[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[K]*list.Element{},
+	}
+}
+
+// Get returns the value stored for key and marks it as recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*entry[K, V]).value, true
+}
+
+// Add stores value for key, evicting the least recently used entry if the
+// cache is full.
+func (c *Cache[K, V]) Add(key K, value V) {
+	if element, ok := c.items[key]; ok {
+		element.Value.(*entry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// GetOrAdd returns the value stored for key, storing the result of create
+// first if there is none.
+func (c *Cache[K, V]) GetOrAdd(key K, create func() V) V {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+	value := create()
+	c.Add(key, value)
+	return value
+}
+
+// Len returns the number of entries.
+func (c *Cache[K, V]) Len() int {
+	return c.order.Len()
+}
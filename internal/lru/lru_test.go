@@ -0,0 +1,27 @@
+package lru
+
+import "testing"
+
+func TestCache(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(a) missing")
+	}
+	// b is now the least recently used entry and is evicted.
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) found evicted entry")
+	}
+	if got, _ := c.Get("a"); got != 1 {
+		t.Errorf("Get(a) = %d, want 1", got)
+	}
+	if got := c.GetOrAdd("c", func() int { return 4 }); got != 3 {
+		t.Errorf("GetOrAdd(c) = %d, want 3", got)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultAlertmanagerResolveTimeout is how long an alert stays firing in
+// Alertmanager before it auto resolves when no new event refreshes it.
+const defaultAlertmanagerResolveTimeout = 30 * time.Minute
+
+// AlertmanagerAlert is a single alert in the Alertmanager /api/v2/alerts API.
+type AlertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+func NewAlertmanagerNotifier(url string, resolveTimeout time.Duration, labels map[string]string, log zerolog.Logger) AlertmanagerNotifier {
+	if resolveTimeout <= 0 {
+		resolveTimeout = defaultAlertmanagerResolveTimeout
+	}
+	return AlertmanagerNotifier{
+		URL:            strings.TrimRight(url, "/"),
+		ResolveTimeout: resolveTimeout,
+		Labels:         labels,
+		HttpClient:     &http.Client{},
+		now:            time.Now,
+		log:            log,
+	}
+}
+
+type AlertmanagerNotifier struct {
+	// URL is the base URL of Alertmanager, e.g. http://alertmanager:9093.
+	URL string
+	// ResolveTimeout is added to the time of the event to compute endsAt.
+	ResolveTimeout time.Duration
+	// Labels are static labels added to every alert.
+	Labels     map[string]string
+	HttpClient hTTPClient
+	now        func() time.Time
+	log        zerolog.Logger
+}
+
+// Notify posts the log line as a firing alert to Alertmanager. Alertmanager
+// groups alerts with identical labels, so repeats of the same event refresh
+// the existing alert and extend its endsAt.
+func (a AlertmanagerNotifier) Notify(logLine LogLine) error {
+	now := a.now()
+	return a.send(logLine, now, now.Add(a.ResolveTimeout))
+}
+
+// Resolve marks the alert for the log line as resolved.
+func (a AlertmanagerNotifier) Resolve(logLine LogLine) error {
+	now := a.now()
+	return a.send(logLine, now, now)
+}
+
+func (a AlertmanagerNotifier) send(logLine LogLine, startsAt, endsAt time.Time) error {
+	alerts := []AlertmanagerAlert{
+		{
+			Labels: a.labels(logLine),
+			Annotations: map[string]string{
+				"summary":    logLine.Summary(),
+				"login_time": logLine.LoginTime,
+			},
+			StartsAt: startsAt,
+			EndsAt:   endsAt,
+		},
+	}
+
+	a.log.Info().Msg(fmt.Sprintf("sending alert to alertmanager: %s", logLine.Summary()))
+	bodyBytes, err := postJSON(a.HttpClient, a.URL+"/api/v2/alerts", nil, alerts)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed sending alertmanager alert")
+		return fmt.Errorf("error sending Alertmanager alert: %w", err)
+	}
+	a.log.Info().Msg(string(bodyBytes))
+
+	return nil
+}
+
+// labels builds the alert labels from the static labels and the event fields.
+// Empty event fields are omitted as Alertmanager rejects empty label values.
+func (a AlertmanagerNotifier) labels(logLine LogLine) map[string]string {
+	labels := map[string]string{}
+	for name, value := range a.Labels {
+		labels[name] = value
+	}
+
+	labels["alertname"] = alertName(logLine.EventType)
+	fields := map[string]string{
+		"event_type":   string(logLine.EventType),
+		"username":     logLine.Username,
+		"ip_address":   logLine.IpAddress,
+		"host_machine": logLine.HostMachine,
+	}
+	for name, value := range fields {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// alertName converts an event type such as "failed login attempt" into an
+// Alertmanager alert name such as "SSHFailedLoginAttempt".
+func alertName(eventType EventType) string {
+	var b strings.Builder
+	b.WriteString("SSH")
+	for _, word := range strings.Fields(string(eventType)) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestAlertmanagerNotifier_Notify(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		status     int
+		labels     map[string]string
+		logLine    LogLine
+		wantLabels map[string]string
+		wantErr    bool
+	}{
+		{
+			name:   "labels from event fields",
+			status: http.StatusOK,
+			labels: map[string]string{"team": "infra"},
+			logLine: LogLine{
+				Username:    "root",
+				IpAddress:   "1.2.3.4",
+				EventType:   LoggedIn,
+				HostMachine: "foobar",
+			},
+			wantLabels: map[string]string{
+				"team":         "infra",
+				"alertname":    "SSHLoggedIn",
+				"event_type":   "logged in",
+				"username":     "root",
+				"ip_address":   "1.2.3.4",
+				"host_machine": "foobar",
+			},
+			wantErr: false,
+		},
+		{
+			name:   "empty fields are omitted",
+			status: http.StatusOK,
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: FailedLoginAttemptInvalidUsername,
+			},
+			wantLabels: map[string]string{
+				"alertname":  "SSHFailedLoginAttemptWithInvalidUsername",
+				"event_type": "failed login attempt with invalid username",
+				"ip_address": "1.2.3.4",
+			},
+			wantErr: false,
+		},
+		{
+			name:   "rejected by alertmanager",
+			status: http.StatusBadRequest,
+			logLine: LogLine{
+				EventType: FailedLoginAttempt,
+			},
+			wantLabels: map[string]string{
+				"alertname":  "SSHFailedLoginAttempt",
+				"event_type": "failed login attempt",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []AlertmanagerAlert
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v2/alerts" {
+					t.Errorf("unexpected request path %s", r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			a := NewAlertmanagerNotifier(server.URL+"/", time.Hour, tt.labels, zerolog.Nop())
+			a.now = func() time.Time { return now }
+			if err := a.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("AlertmanagerNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != 1 {
+				t.Fatalf("AlertmanagerNotifier.Notify() sent %d alerts, want 1", len(got))
+			}
+			if !reflect.DeepEqual(got[0].Labels, tt.wantLabels) {
+				t.Errorf("AlertmanagerNotifier.Notify() labels = %v, want %v", got[0].Labels, tt.wantLabels)
+			}
+			if !got[0].EndsAt.Equal(now.Add(time.Hour)) {
+				t.Errorf("AlertmanagerNotifier.Notify() endsAt = %v, want %v", got[0].EndsAt, now.Add(time.Hour))
+			}
+		})
+	}
+}
+
+func TestAlertmanagerNotifier_Resolve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	var got []AlertmanagerAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed decoding request body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	a := NewAlertmanagerNotifier(server.URL, 0, nil, zerolog.Nop())
+	a.now = func() time.Time { return now }
+	if err := a.Resolve(LogLine{EventType: LoggedIn}); err != nil {
+		t.Fatalf("AlertmanagerNotifier.Resolve() error = %v", err)
+	}
+	if len(got) != 1 || !got[0].EndsAt.Equal(now) {
+		t.Errorf("AlertmanagerNotifier.Resolve() alerts = %v, want endsAt %v", got, now)
+	}
+}
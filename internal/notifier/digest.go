@@ -0,0 +1,274 @@
+package notifier
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// digestTop is how many IP addresses and usernames a digest ranks.
+	digestTop = 5
+	// digestMaxLogins is how many logins of each list a digest keeps and
+	// shows. Further logins are only counted.
+	digestMaxLogins = 20
+	// digestMaxTracked is how many distinct IP addresses and usernames a
+	// digest counts events of, bounding its size during floods.
+	digestMaxTracked = 10000
+)
+
+// Digest summarizes the events a digest sink buffered between Start and End.
+type Digest struct {
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end,omitempty"`
+	Counts map[EventType]int `json:"counts"`
+	// IpAddresses and Usernames count the events per source and user, up to
+	// digestMaxTracked of each.
+	IpAddresses map[string]int `json:"ip_addresses"`
+	Usernames   map[string]int `json:"usernames"`
+	// NewSources are the first new source for user events.
+	NewSources []DigestLogin `json:"new_sources,omitempty"`
+	// AcceptedLogins are the first successful logins.
+	AcceptedLogins []DigestLogin `json:"accepted_logins,omitempty"`
+}
+
+// DigestLogin is a login listed in a digest.
+type DigestLogin struct {
+	Username    string `json:"username"`
+	IpAddress   string `json:"ip_address"`
+	LoginTime   string `json:"login_time"`
+	HostMachine string `json:"host_machine"`
+	Location    string `json:"location,omitempty"`
+}
+
+func (l DigestLogin) String() string {
+	s := fmt.Sprintf("%s from %s", l.Username, l.IpAddress)
+	if l.Location != "" {
+		s += fmt.Sprintf(" (%s)", l.Location)
+	}
+	return s + fmt.Sprintf(" at %s on %s", l.LoginTime, l.HostMachine)
+}
+
+// Ranked is a value and how often it occurred.
+type Ranked struct {
+	Value string
+	Count int
+}
+
+// init allocates the maps of an empty digest.
+func (d *Digest) init() {
+	if d.Counts == nil {
+		d.Counts = map[EventType]int{}
+		d.IpAddresses = map[string]int{}
+		d.Usernames = map[string]int{}
+	}
+}
+
+// countTracked adds n to the count of value unless counts already tracks
+// digestMaxTracked other values.
+func countTracked(counts map[string]int, value string, n int) {
+	if _, ok := counts[value]; ok || len(counts) < digestMaxTracked {
+		counts[value] += n
+	}
+}
+
+// appendLogins appends logins to list up to digestMaxLogins.
+func appendLogins(list []DigestLogin, logins ...DigestLogin) []DigestLogin {
+	for _, login := range logins {
+		if len(list) == digestMaxLogins {
+			break
+		}
+		list = append(list, login)
+	}
+	return list
+}
+
+// Add records the log line in the digest.
+func (d *Digest) Add(logLine LogLine) {
+	d.init()
+	d.Counts[logLine.EventType]++
+	if logLine.IpAddress != "" {
+		countTracked(d.IpAddresses, logLine.IpAddress, 1)
+	}
+	if logLine.Username != "" {
+		countTracked(d.Usernames, logLine.Username, 1)
+	}
+
+	login := DigestLogin{
+		Username:    logLine.Username,
+		IpAddress:   logLine.IpAddress,
+		LoginTime:   logLine.LoginTime,
+		HostMachine: logLine.HostMachine,
+	}
+	if logLine.Location != nil {
+		login.Location = logLine.Location.String()
+	}
+	switch logLine.EventType {
+	case LoggedIn:
+		d.AcceptedLogins = appendLogins(d.AcceptedLogins, login)
+	case NewSourceForUser:
+		d.NewSources = appendLogins(d.NewSources, login)
+	}
+}
+
+// Merge adds the events of other, which were buffered after those of d.
+func (d *Digest) Merge(other Digest) {
+	if other.Total() == 0 {
+		return
+	}
+	if d.Total() == 0 {
+		d.Start = other.Start
+	}
+	d.init()
+	for eventType, n := range other.Counts {
+		d.Counts[eventType] += n
+	}
+	for ip, n := range other.IpAddresses {
+		countTracked(d.IpAddresses, ip, n)
+	}
+	for username, n := range other.Usernames {
+		countTracked(d.Usernames, username, n)
+	}
+	d.NewSources = appendLogins(d.NewSources, other.NewSources...)
+	d.AcceptedLogins = appendLogins(d.AcceptedLogins, other.AcceptedLogins...)
+}
+
+// Total returns the number of events in the digest.
+func (d Digest) Total() int {
+	total := 0
+	for _, count := range d.Counts {
+		total += count
+	}
+	return total
+}
+
+// Title returns the headline of the digest.
+func (d Digest) Title() string {
+	return fmt.Sprintf("SSH digest: %d events from %s to %s", d.Total(), d.Start.Format(time.RFC3339), d.End.Format(time.RFC3339))
+}
+
+// EventCounts returns the number of events per type, most frequent first.
+func (d Digest) EventCounts() []Ranked {
+	counts := make(map[string]int, len(d.Counts))
+	for eventType, count := range d.Counts {
+		counts[string(eventType)] = count
+	}
+	return rank(counts, len(counts))
+}
+
+// TopIpAddresses returns the source IP addresses with the most events.
+func (d Digest) TopIpAddresses() []Ranked {
+	return rank(d.IpAddresses, digestTop)
+}
+
+// TopUsernames returns the usernames with the most events.
+func (d Digest) TopUsernames() []Ranked {
+	return rank(d.Usernames, digestTop)
+}
+
+// rank returns the n most frequent values of counts, ties ordered by value.
+func rank(counts map[string]int, n int) []Ranked {
+	ranked := make([]Ranked, 0, len(counts))
+	for value, count := range counts {
+		ranked = append(ranked, Ranked{Value: value, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Value < ranked[j].Value
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// digestSection is a titled list of lines of a digest.
+type digestSection struct {
+	title string
+	lines []string
+}
+
+// sections returns the parts of the digest that have content.
+func (d Digest) sections() []digestSection {
+	rankedLines := func(ranked []Ranked) []string {
+		lines := make([]string, 0, len(ranked))
+		for _, r := range ranked {
+			lines = append(lines, fmt.Sprintf("%s: %d", r.Value, r.Count))
+		}
+		return lines
+	}
+	loginLines := func(logins []DigestLogin, total int) []string {
+		var lines []string
+		for _, login := range logins {
+			lines = append(lines, login.String())
+		}
+		if more := total - len(logins); more > 0 && len(logins) > 0 {
+			lines = append(lines, fmt.Sprintf("and %d more", more))
+		}
+		return lines
+	}
+
+	var sections []digestSection
+	for _, section := range []digestSection{
+		{title: "Events", lines: rankedLines(d.EventCounts())},
+		{title: "Top IP addresses", lines: rankedLines(d.TopIpAddresses())},
+		{title: "Top usernames", lines: rankedLines(d.TopUsernames())},
+		{title: "New sources", lines: loginLines(d.NewSources, d.Counts[NewSourceForUser])},
+		{title: "Accepted logins", lines: loginLines(d.AcceptedLogins, d.Counts[LoggedIn])},
+	} {
+		if len(section.lines) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// Text renders the digest as plain text.
+func (d Digest) Text() string {
+	var b strings.Builder
+	b.WriteString(d.Title())
+	for _, section := range d.sections() {
+		fmt.Fprintf(&b, "\n\n%s:", section.title)
+		for _, line := range section.lines {
+			fmt.Fprintf(&b, "\n- %s", line)
+		}
+	}
+	return b.String()
+}
+
+// Slack renders the digest sections as Slack mrkdwn.
+func (d Digest) Slack() string {
+	var parts []string
+	for _, section := range d.sections() {
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%s*", section.title)
+		for _, line := range section.lines {
+			fmt.Fprintf(&b, "\n• %s", escapeSlack(line))
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Email renders the digest as the subject and HTML body of an email.
+func (d Digest) Email() (subject, body string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(d.Title()))
+	for _, section := range d.sections() {
+		fmt.Fprintf(&b, "<h3>%s</h3>\n<ul>\n", html.EscapeString(section.title))
+		for _, line := range section.lines {
+			fmt.Fprintf(&b, "<li>%s</li>\n", html.EscapeString(line))
+		}
+		b.WriteString("</ul>\n")
+	}
+	return d.Title(), b.String()
+}
+
+// escapeSlack escapes the characters Slack treats as control characters.
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDigest() Digest {
+	d := Digest{
+		Start: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 1, 1, 4, 0, 0, 0, time.UTC),
+	}
+	for _, logLine := range []LogLine{
+		{Username: "root", IpAddress: "1.2.3.4", EventType: FailedLoginAttempt},
+		{Username: "admin", IpAddress: "1.2.3.4", EventType: FailedLoginAttempt},
+		{Username: "oracle", IpAddress: "5.6.7.8", EventType: FailedLoginAttemptInvalidUsername},
+		{Username: "alice", IpAddress: "9.9.9.9", EventType: LoggedIn, LoginTime: "Jan  1 03:10:00", HostMachine: "web-1", Location: &Location{Country: "DE"}},
+		{Username: "alice", IpAddress: "9.9.9.9", EventType: NewSourceForUser, LoginTime: "Jan  1 03:10:00", HostMachine: "web-1"},
+	} {
+		d.Add(logLine)
+	}
+	return d
+}
+
+func TestDigest_Ranking(t *testing.T) {
+	d := testDigest()
+	if got := d.Total(); got != 5 {
+		t.Errorf("Total() = %d, want 5", got)
+	}
+	wantIPs := []Ranked{{Value: "1.2.3.4", Count: 2}, {Value: "9.9.9.9", Count: 2}, {Value: "5.6.7.8", Count: 1}}
+	if got := d.TopIpAddresses(); !reflect.DeepEqual(got, wantIPs) {
+		t.Errorf("TopIpAddresses() = %v, want %v", got, wantIPs)
+	}
+	wantEvents := []Ranked{{Value: string(FailedLoginAttempt), Count: 2}}
+	if got := d.EventCounts(); !reflect.DeepEqual(got[:1], wantEvents) {
+		t.Errorf("EventCounts() = %v, want %v first", got, wantEvents)
+	}
+
+	for i := 0; i < 10; i++ {
+		d.Add(LogLine{Username: string(rune('a' + i)), EventType: FailedLoginAttempt})
+	}
+	if got := len(d.TopUsernames()); got != digestTop {
+		t.Errorf("len(TopUsernames()) = %d, want %d", got, digestTop)
+	}
+}
+
+func TestDigest_Render(t *testing.T) {
+	d := testDigest()
+	text := d.Text()
+	for _, want := range []string{
+		"SSH digest: 5 events from 2024-01-01T03:00:00Z to 2024-01-01T04:00:00Z",
+		"Events:\n- failed login attempt: 2",
+		"Top IP addresses:\n- 1.2.3.4: 2",
+		"New sources:\n- alice from 9.9.9.9 at Jan  1 03:10:00 on web-1",
+		"Accepted logins:\n- alice from 9.9.9.9 (DE) at Jan  1 03:10:00 on web-1",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, want it to contain %q", text, want)
+		}
+	}
+	if got := (LogLine{EventType: DigestSummary, Digest: &d}).Summary(); got != text {
+		t.Errorf("Summary() = %q, want the digest text", got)
+	}
+
+	if slack := d.Slack(); !strings.Contains(slack, "*Top usernames*\n• alice: 2") {
+		t.Errorf("Slack() = %q, want a bold section per part", slack)
+	}
+
+	subject, body := d.Email()
+	if subject != d.Title() || !strings.Contains(body, "<h3>Accepted logins</h3>") {
+		t.Errorf("Email() = %q, %q, want the title as subject and a section per part", subject, body)
+	}
+}
+
+func TestDigest_RenderTruncatesLogins(t *testing.T) {
+	d := Digest{}
+	for i := 0; i < digestMaxLogins+3; i++ {
+		d.Add(LogLine{Username: "alice", IpAddress: "9.9.9.9", EventType: LoggedIn})
+	}
+	if len(d.AcceptedLogins) != digestMaxLogins {
+		t.Errorf("kept %d logins, want %d", len(d.AcceptedLogins), digestMaxLogins)
+	}
+	if text := d.Text(); !strings.Contains(text, "- and 3 more") {
+		t.Errorf("Text() = %q, want the logins beyond the limit summarized", text)
+	}
+}
+
+func TestDigest_Merge(t *testing.T) {
+	start := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	d := Digest{Start: start}
+	d.Add(LogLine{Username: "root", IpAddress: "1.2.3.4", EventType: FailedLoginAttempt})
+	later := Digest{Start: start.Add(time.Minute)}
+	later.Add(LogLine{Username: "root", IpAddress: "5.6.7.8", EventType: FailedLoginAttempt})
+	later.Add(LogLine{Username: "alice", IpAddress: "5.6.7.8", EventType: LoggedIn})
+
+	d.Merge(later)
+	if !d.Start.Equal(start) || d.Total() != 3 || d.Usernames["root"] != 2 || d.IpAddresses["5.6.7.8"] != 2 || len(d.AcceptedLogins) != 1 {
+		t.Errorf("merged digest = %+v, want 3 events since %v", d, start)
+	}
+
+	empty := Digest{}
+	empty.Merge(later)
+	if !empty.Start.Equal(later.Start) || empty.Total() != 2 {
+		t.Errorf("merged into empty digest = %+v, want the events of the other digest", empty)
+	}
+}
@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultExecTimeout       = 30 * time.Second
+	defaultExecMaxConcurrent = 4
+	// execWaitDelay bounds how long we wait for the output pipes to close after
+	// a timed out command was killed, in case it spawned children that keep
+	// them open.
+	execWaitDelay = time.Second
+	// execEnvPrefix prefixes the environment variables holding event fields.
+	execEnvPrefix = "SSHW_"
+)
+
+// NewExecNotifier creates a notifier that runs command with args once per
+// event. At most maxConcurrent commands run at the same time.
+func NewExecNotifier(command string, args []string, timeout time.Duration, maxConcurrent int, log zerolog.Logger) ExecNotifier {
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultExecMaxConcurrent
+	}
+	return ExecNotifier{
+		Command: command,
+		Args:    args,
+		Timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrent),
+		log:     log,
+	}
+}
+
+type ExecNotifier struct {
+	Command string
+	Args    []string
+	// Timeout is the maximum run time of the command before it is killed.
+	Timeout time.Duration
+	// slots limits the number of commands running concurrently.
+	slots chan struct{}
+	log   zerolog.Logger
+}
+
+// Notify runs the configured command with the log line as JSON on stdin and
+// its fields as SSHW_* environment variables. A non zero exit status or a
+// timeout is returned as an error.
+func (e ExecNotifier) Notify(logLine LogLine) error {
+	payloadJSON, err := json.Marshal(logLine)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log line: %w", err)
+	}
+	env, err := execEnv(payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed building command environment: %w", err)
+	}
+
+	e.slots <- struct{}{}
+	defer func() { <-e.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Stdin = bytes.NewReader(payloadJSON)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), env...)
+	cmd.WaitDelay = execWaitDelay
+
+	e.log.Info().Msg(fmt.Sprintf("running exec hook %s for %s", e.Command, logLine.EventType))
+	err = cmd.Run()
+	e.logOutput(stdout.String(), stderr.String())
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("exec hook %s timed out after %s", e.Command, e.Timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("exec hook %s exited with status %d", e.Command, exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("error running exec hook %s: %w", e.Command, err)
+	}
+
+	return nil
+}
+
+func (e ExecNotifier) logOutput(stdout, stderr string) {
+	for _, line := range nonEmptyLines(stdout) {
+		e.log.Debug().Str("command", e.Command).Msg(line)
+	}
+	for _, line := range nonEmptyLines(stderr) {
+		e.log.Warn().Str("command", e.Command).Msg(line)
+	}
+}
+
+// execEnv converts the JSON fields of a log line into SSHW_* environment
+// variables, e.g. ip_address becomes SSHW_IP_ADDRESS. Non string values are
+// passed as JSON.
+func execEnv(payloadJSON []byte) ([]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payloadJSON, &fields); err != nil {
+		return nil, fmt.Errorf("failed unmarshaling log line: %w", err)
+	}
+
+	env := make([]string, 0, len(fields))
+	for name, raw := range fields {
+		value := string(raw)
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			value = s
+		}
+		env = append(env, execEnvPrefix+strings.ToUpper(name)+"="+value)
+	}
+	return env, nil
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestExecNotifier_Notify(t *testing.T) {
+	logLine := LogLine{
+		Username:    "root",
+		IpAddress:   "1.2.3.4",
+		LoginTime:   "Dec 1",
+		EventType:   LoggedIn,
+		HostMachine: "foobar",
+	}
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		wantOut string
+		wantErr bool
+	}{
+		{
+			name:    "event passed on stdin",
+			script:  `cat > "$OUT"`,
+			wantOut: `{"username":"root","ip_address":"1.2.3.4","login_time":"Dec 1","event_type":"logged in","host_machine":"foobar"}`,
+			wantErr: false,
+		},
+		{
+			name:    "event passed as environment",
+			script:  `printf '%s|%s|%s' "$SSHW_USERNAME" "$SSHW_IP_ADDRESS" "$SSHW_EVENT_TYPE" > "$OUT"`,
+			wantOut: "root|1.2.3.4|logged in",
+			wantErr: false,
+		},
+		{
+			name:    "non zero exit is a failure",
+			script:  `echo "asset db unavailable" >&2; exit 3`,
+			wantErr: true,
+		},
+		{
+			name:    "timeout is a failure",
+			script:  `sleep 5`,
+			timeout: 100 * time.Millisecond,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			t.Setenv("OUT", out)
+
+			e := NewExecNotifier("/bin/sh", []string{"-c", tt.script}, tt.timeout, 1, zerolog.Nop())
+			if err := e.Notify(logLine); (err != nil) != tt.wantErr {
+				t.Errorf("ExecNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantOut == "" {
+				return
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("failed reading command output: %v", err)
+			}
+			if string(got) != tt.wantOut {
+				t.Errorf("ExecNotifier.Notify() command output = %s, want %s", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestExecNotifier_NotifyConcurrencyLimit(t *testing.T) {
+	dir := t.TempDir()
+	// Each run fails if another run holds the lock directory.
+	script := `mkdir "$LOCK" || exit 1; sleep 0.1; rmdir "$LOCK"`
+	t.Setenv("LOCK", filepath.Join(dir, "lock"))
+
+	e := NewExecNotifier("/bin/sh", []string{"-c", script}, time.Second, 1, zerolog.Nop())
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- e.Notify(LogLine{EventType: LoggedIn})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("ExecNotifier.Notify() error = %v, want commands to run one at a time", err)
+		}
+	}
+}
+
+func Test_execEnv(t *testing.T) {
+	got, err := execEnv([]byte(`{"username":"root","count":3}`))
+	if err != nil {
+		t.Fatalf("execEnv() error = %v", err)
+	}
+	sort.Strings(got)
+	want := []string{"SSHW_COUNT=3", "SSHW_USERNAME=root"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("execEnv() = %v, want %v", got, want)
+	}
+}
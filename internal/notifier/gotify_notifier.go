@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// gotifyPriorities maps event severities to Gotify priorities, from 0 to 10.
+var gotifyPriorities = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityLow:      3,
+	SeverityMedium:   5,
+	SeverityHigh:     8,
+	SeverityCritical: 10,
+}
+
+// defaultGotifyPriority is used for events of unknown severity.
+const defaultGotifyPriority = 5
+
+type GotifyPayload struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+// NewGotifyNotifier creates a Gotify notifier. priorities override the
+// priority of the given event types, which otherwise follows the event
+// severity.
+func NewGotifyNotifier(serverURL, appToken string, priorities map[EventType]int, log zerolog.Logger) GotifyNotifier {
+	return GotifyNotifier{
+		ServerURL:  strings.TrimRight(serverURL, "/"),
+		AppToken:   appToken,
+		Priorities: priorities,
+		Tags:       defaultPushTags,
+		HttpClient: &http.Client{},
+		log:        log,
+	}
+}
+
+type GotifyNotifier struct {
+	ServerURL string
+	// AppToken is the token of the Gotify application messages are sent as.
+	AppToken string
+	// Priorities overrides the Gotify priority of event types. Other event
+	// types are sent with the priority matching their severity.
+	Priorities map[EventType]int
+	// Tags maps an event type to tags sent in the message extras.
+	Tags       map[EventType][]string
+	HttpClient hTTPClient
+	log        zerolog.Logger
+}
+
+// Notify sends the log line as a Gotify message.
+func (g GotifyNotifier) Notify(logLine LogLine) error {
+	payload := GotifyPayload{
+		Title:    pushTitle(logLine),
+		Message:  logLine.Summary(),
+		Priority: g.priority(logLine),
+		Extras:   map[string]any{"ssh-watcher::tags": pushTags(g.Tags, logLine)},
+	}
+	headers := map[string]string{"X-Gotify-Key": g.AppToken}
+
+	g.log.Info().Msg("sending message to gotify")
+	if _, err := postJSON(g.HttpClient, g.ServerURL+"/message", headers, payload); err != nil {
+		g.log.Error().Err(err).Msg("failed sending gotify message")
+		return fmt.Errorf("error sending Gotify message: %w", err)
+	}
+
+	return nil
+}
+
+func (g GotifyNotifier) priority(logLine LogLine) int {
+	if priority, ok := g.Priorities[logLine.EventType]; ok {
+		return priority
+	}
+	if priority, ok := gotifyPriorities[eventSeverity(logLine)]; ok {
+		return priority
+	}
+	return defaultGotifyPriority
+}
@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestGotifyNotifier_Notify(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		priorities   map[EventType]int
+		logLine      LogLine
+		wantPriority int
+		wantErr      bool
+	}{
+		{
+			name:         "accepted login",
+			status:       http.StatusOK,
+			logLine:      LogLine{Username: "root", EventType: LoggedIn},
+			wantPriority: 5,
+			wantErr:      false,
+		},
+		{
+			name:         "overridden priority",
+			status:       http.StatusOK,
+			priorities:   map[EventType]int{LoggedIn: 10},
+			logLine:      LogLine{Username: "root", EventType: LoggedIn},
+			wantPriority: 10,
+			wantErr:      false,
+		},
+		{
+			name:         "priority of severity",
+			status:       http.StatusOK,
+			logLine:      LogLine{Username: "root", EventType: LoggedIn, Severity: SeverityHigh},
+			wantPriority: 8,
+			wantErr:      false,
+		},
+		{
+			name:         "detection",
+			status:       http.StatusOK,
+			logLine:      LogLine{IpAddress: "203.0.113.7", EventType: SuspiciousLoginAfterFailures},
+			wantPriority: 10,
+			wantErr:      false,
+		},
+		{
+			name:         "unmapped event type",
+			status:       http.StatusOK,
+			logLine:      LogLine{EventType: EventType("something else")},
+			wantPriority: 1,
+			wantErr:      false,
+		},
+		{
+			name:         "unauthorized",
+			status:       http.StatusUnauthorized,
+			logLine:      LogLine{EventType: FailedLoginAttempt},
+			wantPriority: 3,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got GotifyPayload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/message" {
+					t.Errorf("unexpected request path %s", r.URL.Path)
+				}
+				if r.Header.Get("X-Gotify-Key") != "token" {
+					t.Errorf("unexpected gotify key header %s", r.Header.Get("X-Gotify-Key"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			g := NewGotifyNotifier(server.URL, "token", tt.priorities, zerolog.Nop())
+			if err := g.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("GotifyNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Priority != tt.wantPriority {
+				t.Errorf("GotifyNotifier.Notify() priority = %v, want %v", got.Priority, tt.wantPriority)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultContextTimeout time.Duration = 5 * time.Second
+)
+
+// hTTPClient is an interface for making HTTP requests
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . hTTPClient
+type hTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// readCloser is the interface for io.ReadCloser.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . readCloser
+type readCloser interface {
+	io.Reader
+	io.Closer
+}
+
+// sendJSON marshals payload and sends it to url using the given HTTP method.
+// Any non 2xx response status is returned as an error. The response body is
+// returned so callers can log or inspect it.
+func sendJSON(client hTTPClient, method, url string, headers map[string]string, payload any) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling payload %v: %w", payload, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultContextTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(payloadJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return bodyBytes, fmt.Errorf("unexpected response status %s: %s", resp.Status, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
+
+// postJSON sends payload to url as a JSON POST request.
+func postJSON(client hTTPClient, url string, headers map[string]string, payload any) ([]byte, error) {
+	return sendJSON(client, http.MethodPost, url, headers, payload)
+}
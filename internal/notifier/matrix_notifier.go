@@ -0,0 +1,85 @@
+package notifier
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MatrixPayload struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func NewMatrixNotifier(homeserverURL, accessToken, roomID string, log zerolog.Logger) MatrixNotifier {
+	return MatrixNotifier{
+		HomeserverURL: strings.TrimRight(homeserverURL, "/"),
+		AccessToken:   accessToken,
+		RoomID:        roomID,
+		HttpClient:    &http.Client{},
+		txnPrefix:     fmt.Sprintf("sshw-%d", time.Now().UnixNano()),
+		txnCounter:    &atomic.Uint64{},
+		log:           log,
+	}
+}
+
+type MatrixNotifier struct {
+	// HomeserverURL is the base URL of the Matrix homeserver, e.g. https://matrix.org.
+	HomeserverURL string
+	AccessToken   string
+	// RoomID is the room id, e.g. !abcdef:matrix.org, messages are sent to.
+	RoomID     string
+	HttpClient hTTPClient
+	// txnPrefix and txnCounter build transaction ids that are unique for the
+	// lifetime of the access token so the homeserver can deduplicate retries.
+	txnPrefix  string
+	txnCounter *atomic.Uint64
+	log        zerolog.Logger
+}
+
+// Notify sends the log line to the Matrix room as an m.text message with an
+// HTML formatted body.
+func (m MatrixNotifier) Notify(logLine LogLine) error {
+	payload := MatrixPayload{
+		MsgType:       "m.text",
+		Body:          logLine.Summary(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: matrixFormattedBody(logLine),
+	}
+
+	txnID := m.nextTxnID()
+	sendURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", m.HomeserverURL, url.PathEscape(m.RoomID), url.PathEscape(txnID))
+	headers := map[string]string{"Authorization": "Bearer " + m.AccessToken}
+
+	m.log.Info().Msg(fmt.Sprintf("sending message to matrix room %s with txn id %s", m.RoomID, txnID))
+	if _, err := sendJSON(m.HttpClient, http.MethodPut, sendURL, headers, payload); err != nil {
+		m.log.Error().Err(err).Msg("failed sending matrix message")
+		return fmt.Errorf("error sending Matrix message: %w", err)
+	}
+
+	return nil
+}
+
+func (m MatrixNotifier) nextTxnID() string {
+	return fmt.Sprintf("%s-%d", m.txnPrefix, m.txnCounter.Add(1))
+}
+
+func matrixFormattedBody(logLine LogLine) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<strong>SSH %s</strong><br>", html.EscapeString(string(logLine.EventType)))
+	for _, field := range messageFields(logLine) {
+		fmt.Fprintf(&b, "%s: <code>%s</code><br>", field.name, html.EscapeString(field.value))
+	}
+	if details := messageDetails(logLine); details != "" {
+		fmt.Fprintf(&b, "<pre>%s</pre>", html.EscapeString(details))
+	}
+	return b.String()
+}
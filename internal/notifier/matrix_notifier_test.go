@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMatrixNotifier_Notify(t *testing.T) {
+	var paths []string
+	var got MatrixPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("unexpected request method %s", r.Method)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+		paths = append(paths, r.URL.EscapedPath())
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed decoding request body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewMatrixNotifier(server.URL, "token", "!room:example.org", zerolog.Nop())
+	logLine := LogLine{Username: "<root>", IpAddress: "1.2.3.4", EventType: LoggedIn}
+	for i := 0; i < 2; i++ {
+		if err := m.Notify(logLine); err != nil {
+			t.Fatalf("MatrixNotifier.Notify() error = %v", err)
+		}
+	}
+
+	if len(paths) != 2 || paths[0] == paths[1] {
+		t.Fatalf("MatrixNotifier.Notify() paths = %v, want two distinct transaction ids", paths)
+	}
+	if !strings.HasPrefix(paths[0], "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/sshw-") {
+		t.Errorf("MatrixNotifier.Notify() path = %v", paths[0])
+	}
+	if got.MsgType != "m.text" || got.Format != "org.matrix.custom.html" {
+		t.Errorf("MatrixNotifier.Notify() payload = %v", got)
+	}
+	if !strings.Contains(got.FormattedBody, "User: <code>&lt;root&gt;</code>") {
+		t.Errorf("MatrixNotifier.Notify() formatted body = %v, want escaped username", got.FormattedBody)
+	}
+}
+
+func TestMatrixNotifier_NotifyDetails(t *testing.T) {
+	d := testDigest()
+	tests := []struct {
+		name    string
+		logLine LogLine
+		want    string
+	}{
+		{
+			name:    "digest",
+			logLine: LogLine{EventType: DigestSummary, Digest: &d},
+			want:    "<pre>SSH digest: 5 events from 2024-01-01T03:00:00Z to 2024-01-01T04:00:00Z",
+		},
+		{
+			name: "diff",
+			logLine: LogLine{
+				EventType: AuthorizedKeyAdded,
+				Reason:    "key added to /root/.ssh/authorized_keys",
+				Diff:      "+ ssh-ed25519 SHA256:abc <alice>",
+			},
+			want: "<pre>+ ssh-ed25519 SHA256:abc &lt;alice&gt;</pre>",
+		},
+		{
+			name: "canary raw lines",
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: FailedLoginAttempt,
+				Canary:    true,
+				RawLines:  []string{"Failed password for canary from 1.2.3.4"},
+			},
+			want: "<pre>Recent log lines of 1.2.3.4:\nFailed password for canary from 1.2.3.4</pre>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matrixFormattedBody(tt.logLine); !strings.Contains(got, tt.want) {
+				t.Errorf("matrixFormattedBody() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatrixNotifier_NotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	m := NewMatrixNotifier(server.URL, "token", "!room:example.org", zerolog.Nop())
+	if err := m.Notify(LogLine{EventType: LoggedIn}); err == nil {
+		t.Errorf("MatrixNotifier.Notify() error = nil, want error")
+	}
+}
@@ -1,24 +1,300 @@
 package notifier
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type EventType string
 
 const (
 	LoggedIn                          EventType = "logged in"
 	FailedLoginAttempt                EventType = "failed login attempt"
 	FailedLoginAttemptInvalidUsername EventType = "failed login attempt with invalid username"
+	BruteForceDetected                EventType = "brute force detected"
+	PasswordSprayDetected             EventType = "password spray detected"
+	SuspiciousLoginAfterFailures      EventType = "suspicious login after failures"
+	NewSourceForUser                  EventType = "new source for user"
+	ImpossibleTravel                  EventType = "impossible travel"
+	DisallowedCountryLogin            EventType = "login from disallowed country"
+	OutOfHoursLogin                   EventType = "out of hours login"
+	UnknownKeyUsed                    EventType = "unknown key used"
+	AuthorizedKeyAdded                EventType = "authorized key added"
+	AuthorizedKeyRemoved              EventType = "authorized key removed"
+	AuthorizedKeyChanged              EventType = "authorized key changed"
+	SshdConfigChanged                 EventType = "sshd config changed"
+	SshdPostureRegression             EventType = "sshd posture regression"
+	DistributedAttackDetected         EventType = "distributed attack detected"
+	IpBanned                          EventType = "IP banned"
+	IpUnbanned                        EventType = "IP unbanned"
+	NotificationsDropped              EventType = "notifications dropped"
+	DigestSummary                     EventType = "digest"
+)
+
+// Severity ranks how urgent an event is, from SeverityInfo to SeverityCritical.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
 )
 
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// defaultSeverities maps event types to their severity before any escalation.
+var defaultSeverities = map[EventType]Severity{
+	LoggedIn:                          SeverityMedium,
+	FailedLoginAttempt:                SeverityLow,
+	FailedLoginAttemptInvalidUsername: SeverityLow,
+	BruteForceDetected:                SeverityHigh,
+	PasswordSprayDetected:             SeverityHigh,
+	SuspiciousLoginAfterFailures:      SeverityCritical,
+	NewSourceForUser:                  SeverityHigh,
+	ImpossibleTravel:                  SeverityHigh,
+	DisallowedCountryLogin:            SeverityHigh,
+	OutOfHoursLogin:                   SeverityHigh,
+	UnknownKeyUsed:                    SeverityHigh,
+	AuthorizedKeyAdded:                SeverityHigh,
+	AuthorizedKeyRemoved:              SeverityMedium,
+	AuthorizedKeyChanged:              SeverityHigh,
+	SshdConfigChanged:                 SeverityHigh,
+	SshdPostureRegression:             SeverityHigh,
+	DistributedAttackDetected:         SeverityHigh,
+	IpBanned:                          SeverityMedium,
+	IpUnbanned:                        SeverityLow,
+	NotificationsDropped:              SeverityHigh,
+	DigestSummary:                     SeverityMedium,
+}
+
+// Rank orders severities, higher is more severe. Unknown severities rank 0.
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// ParseSeverity parses one of info, low, medium, high or critical.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severityRanks[severity]; !ok {
+		return "", fmt.Errorf("unknown severity %q", s)
+	}
+	return severity, nil
+}
+
+// SetDefaultSeverity overrides the default severity of an event type. It must
+// be called before events are processed.
+func SetDefaultSeverity(eventType EventType, severity Severity) {
+	defaultSeverities[eventType] = severity
+}
+
+// DefaultSeverity returns the severity of an event type before escalation.
+// Event types without a default are SeverityInfo.
+func DefaultSeverity(eventType EventType) Severity {
+	if severity, ok := defaultSeverities[eventType]; ok {
+		return severity
+	}
+	return SeverityInfo
+}
+
 type LogLine struct {
 	Username    string    `json:"username"`
 	IpAddress   string    `json:"ip_address"`
 	LoginTime   string    `json:"login_time"`
 	EventType   EventType `json:"event_type"`
 	HostMachine string    `json:"host_machine"`
+	Severity    Severity  `json:"severity,omitempty"`
+	// Time is when the event happened, parsed from the timestamp of its log
+	// line. It is zero when the timestamp could not be parsed.
+	Time time.Time `json:"-"`
+	// AuthMethod is the authentication method of a login attempt, e.g.
+	// password or publickey.
+	AuthMethod string `json:"auth_method,omitempty"`
+	// KeyFingerprint is the fingerprint of the public key used to log in.
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+	// KeyOwner is the owner of the key used to log in, taken from the comment
+	// of the key in its authorized_keys file.
+	KeyOwner string `json:"key_owner,omitempty"`
+	// Location is where IpAddress is located, if known.
+	Location *Location `json:"location,omitempty"`
+	// ThreatFeeds are the names of the threat intelligence feeds listing
+	// IpAddress.
+	ThreatFeeds []string `json:"threat_feeds,omitempty"`
+	// MatchedRules are the names of the access list rules matching the event.
+	MatchedRules []string `json:"matched_rules,omitempty"`
+	// Denylisted is set when IpAddress or Username matched a deny rule of the
+	// access list. Sources listed in threat feeds are recorded in ThreatFeeds
+	// instead.
+	Denylisted bool `json:"denylisted,omitempty"`
+	// OutOfHours is set when a login happened outside the user's schedule
+	// and is escalated to the out of hours severity. Policies with their own
+	// severity do not set it.
+	OutOfHours bool `json:"out_of_hours,omitempty"`
+	// Canary is set when the event used a canary username or key. Canary
+	// events are never suppressed, deduplicated or digested.
+	Canary bool `json:"canary,omitempty"`
+	// RawLines are the recent log lines mentioning IpAddress, attached to
+	// canary events.
+	RawLines []string `json:"raw_lines,omitempty"`
+	// Tags are labels added by rules.
+	Tags []string `json:"tags,omitempty"`
+	// Sinks are the sinks a rule sends the event to instead of the routed
+	// ones.
+	Sinks []string `json:"-"`
+	// Respond is set when a rule asks the active responder to ban the source.
+	Respond bool `json:"-"`
+	// Count is the number of log lines summarized by a detection event.
+	Count int `json:"count,omitempty"`
+	// Repeats is the number of times the event repeated after it was sent
+	// before a dedup window ended.
+	Repeats int `json:"repeats,omitempty"`
+	// Usernames are the usernames involved in a detection event.
+	Usernames []string `json:"usernames,omitempty"`
+	// IpAddresses are the source IP addresses involved in a detection event.
+	IpAddresses []string `json:"ip_addresses,omitempty"`
+	// Elapsed is the time between the first failed login and the successful
+	// login of a suspicious login, or between the logins of an impossible
+	// travel, e.g. "4m10s".
+	Elapsed string `json:"elapsed,omitempty"`
+	// Reason explains an action taken in response to an event, e.g. a ban.
+	Reason string `json:"reason,omitempty"`
+	// Diff shows the lines added and removed by a file change, prefixed with
+	// "+ " and "- ".
+	Diff string `json:"diff,omitempty"`
+	// Digest summarizes the events buffered by a digest sink.
+	Digest *Digest `json:"digest,omitempty"`
+}
+
+// Location is the geographic location and autonomous system of an IP
+// address.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 country code.
+	Country     string       `json:"country,omitempty"`
+	CountryName string       `json:"country_name,omitempty"`
+	City        string       `json:"city,omitempty"`
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+	Asn         uint         `json:"asn,omitempty"`
+	AsnOrg      string       `json:"asn_org,omitempty"`
+}
+
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// AccuracyRadiusKm is the radius around the coordinates the IP address
+	// is likely located in.
+	AccuracyRadiusKm uint `json:"accuracy_radius_km,omitempty"`
+}
+
+// String returns a short description of the location, e.g.
+// "Berlin, Germany, AS3320 Deutsche Telekom AG".
+func (l Location) String() string {
+	var parts []string
+	if l.City != "" {
+		parts = append(parts, l.City)
+	}
+	switch {
+	case l.CountryName != "":
+		parts = append(parts, l.CountryName)
+	case l.Country != "":
+		parts = append(parts, l.Country)
+	}
+	if l.Asn != 0 {
+		parts = append(parts, strings.TrimSpace(fmt.Sprintf("AS%d %s", l.Asn, l.AsnOrg)))
+	}
+	return strings.Join(parts, ", ")
 }
 
 type SlackPayload struct {
-	Channel   string `json:"channel"`
-	Username  string `json:"username"`
-	IconEmoji string `json:"icon_emoji"`
-	Text      string `json:"text"`
+	Channel     string            `json:"channel"`
+	Username    string            `json:"username"`
+	IconEmoji   string            `json:"icon_emoji"`
+	Text        string            `json:"text"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+// SlackAttachment is a legacy Slack message attachment, used for the colour
+// bar showing the severity of an event.
+type SlackAttachment struct {
+	Color    string `json:"color"`
+	Fallback string `json:"fallback"`
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+}
+
+// OccurredAt returns when the event happened, or now if the timestamp of its
+// log line could not be parsed. Detectors use it so replayed log lines are
+// correlated at the time they were logged.
+func (l LogLine) OccurredAt(now time.Time) time.Time {
+	if l.Time.IsZero() {
+		return now
+	}
+	return l.Time
+}
+
+// Summary returns a short human readable description of the log line.
+func (l LogLine) Summary() string {
+	if l.Digest != nil {
+		return l.Digest.Text()
+	}
+	var b strings.Builder
+	if l.Username != "" {
+		fmt.Fprintf(&b, "User %s ", l.Username)
+	}
+	b.WriteString(string(l.EventType))
+	if l.IpAddress != "" {
+		fmt.Fprintf(&b, " from IP %s", l.IpAddress)
+		if l.Location != nil {
+			fmt.Fprintf(&b, " (%s)", l.Location)
+		}
+		if len(l.ThreatFeeds) > 0 {
+			fmt.Fprintf(&b, " listed in %s", strings.Join(l.ThreatFeeds, ", "))
+		}
+	}
+	if l.KeyOwner != "" {
+		fmt.Fprintf(&b, " with the key of %s", l.KeyOwner)
+	}
+	fmt.Fprintf(&b, " at %s on %s", l.LoginTime, l.HostMachine)
+	if l.Count > 0 {
+		fmt.Fprintf(&b, " (%d attempts", l.Count)
+		if l.Elapsed != "" {
+			fmt.Fprintf(&b, " over %s", l.Elapsed)
+		}
+		if len(l.Usernames) > 0 {
+			fmt.Fprintf(&b, ", users: %s", strings.Join(l.Usernames, ", "))
+		}
+		if len(l.IpAddresses) > 0 {
+			fmt.Fprintf(&b, ", IPs: %s", strings.Join(l.IpAddresses, ", "))
+		}
+		b.WriteString(")")
+	}
+	if l.Reason != "" {
+		fmt.Fprintf(&b, ": %s", l.Reason)
+	}
+	if l.Repeats > 0 {
+		fmt.Fprintf(&b, " (repeated %d times)", l.Repeats)
+	}
+	if l.Diff != "" {
+		fmt.Fprintf(&b, "\n%s", l.Diff)
+	}
+	if len(l.RawLines) > 0 {
+		fmt.Fprintf(&b, "\n\nRecent log lines of %s:\n%s", l.IpAddress, strings.Join(l.RawLines, "\n"))
+	}
+	return b.String()
+}
+
+// eventSeverity returns the severity of the log line, or the default severity
+// of its event type if it has none.
+func eventSeverity(logLine LogLine) Severity {
+	if logLine.Severity == "" {
+		return DefaultSeverity(logLine.EventType)
+	}
+	return logLine.Severity
 }
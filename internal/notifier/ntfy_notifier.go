@@ -0,0 +1,139 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultNtfyServerURL is the public ntfy server.
+const DefaultNtfyServerURL = "https://ntfy.sh"
+
+// NtfyPriority is a message priority accepted by ntfy, from 1 (min) to 5 (max).
+type NtfyPriority int
+
+const (
+	NtfyMin     NtfyPriority = 1
+	NtfyLow     NtfyPriority = 2
+	NtfyDefault NtfyPriority = 3
+	NtfyHigh    NtfyPriority = 4
+	NtfyMax     NtfyPriority = 5
+)
+
+// ntfyPriorities maps event severities to ntfy priorities.
+var ntfyPriorities = map[Severity]NtfyPriority{
+	SeverityInfo:     NtfyMin,
+	SeverityLow:      NtfyLow,
+	SeverityMedium:   NtfyDefault,
+	SeverityHigh:     NtfyHigh,
+	SeverityCritical: NtfyMax,
+}
+
+// defaultPushTags maps event types to the tags attached to push
+// notifications. ntfy renders tags matching emoji short codes as emojis.
+var defaultPushTags = map[EventType][]string{
+	LoggedIn:                          {"key", "ssh"},
+	FailedLoginAttempt:                {"warning", "ssh"},
+	FailedLoginAttemptInvalidUsername: {"no_entry", "ssh"},
+}
+
+// severityPushTags are the tags of event types without default tags, by
+// severity.
+var severityPushTags = map[Severity][]string{
+	SeverityHigh:     {"rotating_light", "ssh"},
+	SeverityCritical: {"rotating_light", "ssh"},
+}
+
+// pushTags returns the tags of the log line, falling back to tags matching its
+// severity.
+func pushTags(tags map[EventType][]string, logLine LogLine) []string {
+	if t, ok := tags[logLine.EventType]; ok {
+		return t
+	}
+	if t, ok := severityPushTags[eventSeverity(logLine)]; ok {
+		return t
+	}
+	return []string{"ssh"}
+}
+
+type NtfyPayload struct {
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title"`
+	Message  string       `json:"message"`
+	Priority NtfyPriority `json:"priority"`
+	Tags     []string     `json:"tags,omitempty"`
+}
+
+// NewNtfyNotifier creates a ntfy notifier. priorities override the priority
+// of the given event types, which otherwise follows the event severity.
+func NewNtfyNotifier(serverURL, topic, accessToken string, priorities map[EventType]NtfyPriority, log zerolog.Logger) NtfyNotifier {
+	if serverURL == "" {
+		serverURL = DefaultNtfyServerURL
+	}
+	return NtfyNotifier{
+		ServerURL:   strings.TrimRight(serverURL, "/"),
+		Topic:       topic,
+		AccessToken: accessToken,
+		Priorities:  priorities,
+		Tags:        defaultPushTags,
+		HttpClient:  &http.Client{},
+		log:         log,
+	}
+}
+
+type NtfyNotifier struct {
+	ServerURL string
+	Topic     string
+	// AccessToken is optional and only needed for protected topics.
+	AccessToken string
+	// Priorities overrides the ntfy priority of event types. Other event types
+	// are sent with the priority matching their severity.
+	Priorities map[EventType]NtfyPriority
+	// Tags maps an event type to the tags of the message.
+	Tags       map[EventType][]string
+	HttpClient hTTPClient
+	log        zerolog.Logger
+}
+
+// Notify publishes the log line to the ntfy topic.
+func (n NtfyNotifier) Notify(logLine LogLine) error {
+	payload := NtfyPayload{
+		Topic:    n.Topic,
+		Title:    pushTitle(logLine),
+		Message:  logLine.Summary(),
+		Priority: n.priority(logLine),
+		Tags:     pushTags(n.Tags, logLine),
+	}
+
+	var headers map[string]string
+	if n.AccessToken != "" {
+		headers = map[string]string{"Authorization": "Bearer " + n.AccessToken}
+	}
+
+	n.log.Info().Msg(fmt.Sprintf("publishing message to ntfy topic %s", n.Topic))
+	if _, err := postJSON(n.HttpClient, n.ServerURL, headers, payload); err != nil {
+		n.log.Error().Err(err).Msg("failed publishing ntfy message")
+		return fmt.Errorf("error publishing ntfy message: %w", err)
+	}
+
+	return nil
+}
+
+func (n NtfyNotifier) priority(logLine LogLine) NtfyPriority {
+	if priority, ok := n.Priorities[logLine.EventType]; ok {
+		return priority
+	}
+	if priority, ok := ntfyPriorities[eventSeverity(logLine)]; ok {
+		return priority
+	}
+	return NtfyDefault
+}
+
+func pushTitle(logLine LogLine) string {
+	if logLine.HostMachine == "" {
+		return "SSH " + string(logLine.EventType)
+	}
+	return fmt.Sprintf("SSH %s on %s", logLine.EventType, logLine.HostMachine)
+}
@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNtfyNotifier_Notify(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		accessToken  string
+		priorities   map[EventType]NtfyPriority
+		logLine      LogLine
+		wantPriority NtfyPriority
+		wantTags     []string
+		wantErr      bool
+	}{
+		{
+			name:         "accepted login",
+			status:       http.StatusOK,
+			accessToken:  "token",
+			logLine:      LogLine{Username: "root", EventType: LoggedIn, HostMachine: "web-1"},
+			wantPriority: NtfyDefault,
+			wantTags:     []string{"key", "ssh"},
+			wantErr:      false,
+		},
+		{
+			name:         "overridden priority",
+			status:       http.StatusOK,
+			priorities:   map[EventType]NtfyPriority{LoggedIn: NtfyMax},
+			logLine:      LogLine{Username: "root", EventType: LoggedIn},
+			wantPriority: NtfyMax,
+			wantTags:     []string{"key", "ssh"},
+			wantErr:      false,
+		},
+		{
+			name:         "priority of severity",
+			status:       http.StatusOK,
+			logLine:      LogLine{Username: "root", EventType: LoggedIn, Severity: SeverityCritical},
+			wantPriority: NtfyMax,
+			wantTags:     []string{"key", "ssh"},
+			wantErr:      false,
+		},
+		{
+			name:         "detection",
+			status:       http.StatusOK,
+			logLine:      LogLine{IpAddress: "203.0.113.7", EventType: BruteForceDetected},
+			wantPriority: NtfyHigh,
+			wantTags:     []string{"rotating_light", "ssh"},
+			wantErr:      false,
+		},
+		{
+			name:         "unmapped event type",
+			status:       http.StatusOK,
+			logLine:      LogLine{EventType: EventType("something else")},
+			wantPriority: NtfyMin,
+			wantTags:     []string{"ssh"},
+			wantErr:      false,
+		},
+		{
+			name:         "forbidden",
+			status:       http.StatusForbidden,
+			logLine:      LogLine{EventType: FailedLoginAttemptInvalidUsername},
+			wantPriority: NtfyLow,
+			wantTags:     []string{"no_entry", "ssh"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got NtfyPayload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wantAuth := ""
+				if tt.accessToken != "" {
+					wantAuth = "Bearer " + tt.accessToken
+				}
+				if r.Header.Get("Authorization") != wantAuth {
+					t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			n := NewNtfyNotifier(server.URL, "alerts", tt.accessToken, tt.priorities, zerolog.Nop())
+			if err := n.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("NtfyNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Topic != "alerts" || got.Priority != tt.wantPriority {
+				t.Errorf("NtfyNotifier.Notify() payload = %v, want priority %v", got, tt.wantPriority)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.wantTags) {
+				t.Errorf("NtfyNotifier.Notify() tags = %v, want %v", got.Tags, tt.wantTags)
+			}
+		})
+	}
+}
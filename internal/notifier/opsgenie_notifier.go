@@ -0,0 +1,163 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultOpsgenieAPIURL is the Opsgenie Alert API base URL. Accounts hosted in
+// the EU instance use https://api.eu.opsgenie.com instead.
+const DefaultOpsgenieAPIURL = "https://api.opsgenie.com"
+
+// OpsgeniePriority is an alert priority accepted by the Opsgenie Alert API.
+type OpsgeniePriority string
+
+const (
+	OpsgenieP1 OpsgeniePriority = "P1"
+	OpsgenieP2 OpsgeniePriority = "P2"
+	OpsgenieP3 OpsgeniePriority = "P3"
+	OpsgenieP4 OpsgeniePriority = "P4"
+	OpsgenieP5 OpsgeniePriority = "P5"
+)
+
+// opsgeniePriorities maps event severities to Opsgenie priorities.
+var opsgeniePriorities = map[Severity]OpsgeniePriority{
+	SeverityInfo:     OpsgenieP5,
+	SeverityLow:      OpsgenieP4,
+	SeverityMedium:   OpsgenieP3,
+	SeverityHigh:     OpsgenieP2,
+	SeverityCritical: OpsgenieP1,
+}
+
+type OpsgeniePayload struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    OpsgeniePriority  `json:"priority"`
+}
+
+type OpsgenieClosePayload struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+func NewOpsgenieNotifier(apiKey, apiURL string, tags []string, log zerolog.Logger) OpsgenieNotifier {
+	if apiURL == "" {
+		apiURL = DefaultOpsgenieAPIURL
+	}
+	return OpsgenieNotifier{
+		APIKey:     apiKey,
+		APIURL:     strings.TrimRight(apiURL, "/"),
+		Tags:       tags,
+		HttpClient: &http.Client{},
+		log:        log,
+	}
+}
+
+type OpsgenieNotifier struct {
+	APIKey string
+	APIURL string
+	// Tags are added to every alert in addition to the event type tag.
+	Tags []string
+	// Priorities overrides the Opsgenie priority of event types. Other event
+	// types are sent with the priority matching their severity.
+	Priorities map[EventType]OpsgeniePriority
+	HttpClient hTTPClient
+	log        zerolog.Logger
+}
+
+// Notify creates an Opsgenie alert for the log line. Alerts share an alias per
+// host machine, IP address and event type so Opsgenie deduplicates repeats
+// into the open alert.
+func (o OpsgenieNotifier) Notify(logLine LogLine) error {
+	payload := OpsgeniePayload{
+		Message:     truncate(logLine.Summary(), opsgenieMaxMessageLength),
+		Alias:       OpsgenieAlias(logLine),
+		Description: logLine.Summary(),
+		Tags:        append(append([]string{}, o.Tags...), opsgenieTag(logLine.EventType)),
+		Details: map[string]string{
+			"username":     logLine.Username,
+			"ip_address":   logLine.IpAddress,
+			"login_time":   logLine.LoginTime,
+			"event_type":   string(logLine.EventType),
+			"host_machine": logLine.HostMachine,
+		},
+		Entity:   logLine.HostMachine,
+		Source:   eventSource,
+		Priority: o.priority(logLine),
+	}
+
+	o.log.Info().Msg(fmt.Sprintf("sending alert to opsgenie with alias %s", payload.Alias))
+	bodyBytes, err := postJSON(o.HttpClient, o.APIURL+"/v2/alerts", o.headers(), payload)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed sending opsgenie alert")
+		return fmt.Errorf("error sending Opsgenie alert: %w", err)
+	}
+	o.log.Info().Msg(string(bodyBytes))
+
+	return nil
+}
+
+// Resolve closes the Opsgenie alert previously created for the log line.
+func (o OpsgenieNotifier) Resolve(logLine LogLine) error {
+	closeURL := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.APIURL, url.PathEscape(OpsgenieAlias(logLine)))
+	payload := OpsgenieClosePayload{
+		Source: eventSource,
+		Note:   "resolved by ssh-watcher",
+	}
+
+	bodyBytes, err := postJSON(o.HttpClient, closeURL, o.headers(), payload)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed closing opsgenie alert")
+		return fmt.Errorf("error closing Opsgenie alert: %w", err)
+	}
+	o.log.Info().Msg(string(bodyBytes))
+
+	return nil
+}
+
+func (o OpsgenieNotifier) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.APIKey}
+}
+
+func (o OpsgenieNotifier) priority(logLine LogLine) OpsgeniePriority {
+	if priority, ok := o.Priorities[logLine.EventType]; ok {
+		return priority
+	}
+	if priority, ok := opsgeniePriorities[eventSeverity(logLine)]; ok {
+		return priority
+	}
+	return OpsgenieP3
+}
+
+// opsgenieMaxMessageLength is the maximum alert message length in characters
+// accepted by the Opsgenie Alert API.
+const opsgenieMaxMessageLength = 130
+
+// OpsgenieAlias builds the alias used to deduplicate Opsgenie alerts. It is
+// the same value as the PagerDuty dedup key.
+func OpsgenieAlias(logLine LogLine) string {
+	return PagerDutyDedupKey(logLine)
+}
+
+func opsgenieTag(eventType EventType) string {
+	return strings.ReplaceAll(string(eventType), " ", "-")
+}
+
+// truncate shortens s to at most length characters without splitting a
+// multi-byte character.
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
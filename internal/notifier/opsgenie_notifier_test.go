@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestOpsgenieNotifier_Notify(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		tags         []string
+		logLine      LogLine
+		wantPriority OpsgeniePriority
+		wantTags     []string
+		wantErr      bool
+	}{
+		{
+			name:   "accepted login",
+			status: http.StatusAccepted,
+			tags:   []string{"ssh"},
+			logLine: LogLine{
+				Username:    "root",
+				IpAddress:   "1.2.3.4",
+				EventType:   LoggedIn,
+				HostMachine: "foobar",
+				Severity:    SeverityCritical,
+			},
+			wantPriority: OpsgenieP1,
+			wantTags:     []string{"ssh", "logged-in"},
+			wantErr:      false,
+		},
+		{
+			name:   "default severity of the event type",
+			status: http.StatusAccepted,
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: SuspiciousLoginAfterFailures,
+			},
+			wantPriority: OpsgenieP1,
+			wantTags:     []string{"suspicious-login-after-failures"},
+			wantErr:      false,
+		},
+		{
+			name:   "unmapped event type",
+			status: http.StatusAccepted,
+			logLine: LogLine{
+				EventType: EventType("something else"),
+			},
+			wantPriority: OpsgenieP5,
+			wantTags:     []string{"something-else"},
+			wantErr:      false,
+		},
+		{
+			name:   "unauthorized",
+			status: http.StatusUnauthorized,
+			logLine: LogLine{
+				EventType: FailedLoginAttempt,
+			},
+			wantPriority: OpsgenieP4,
+			wantTags:     []string{"failed-login-attempt"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got OpsgeniePayload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/alerts" {
+					t.Errorf("unexpected request path %s", r.URL.Path)
+				}
+				if r.Header.Get("Authorization") != "GenieKey key" {
+					t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			o := NewOpsgenieNotifier("key", server.URL, tt.tags, zerolog.Nop())
+			if err := o.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("OpsgenieNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Priority != tt.wantPriority {
+				t.Errorf("OpsgenieNotifier.Notify() priority = %v, want %v", got.Priority, tt.wantPriority)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.wantTags) {
+				t.Errorf("OpsgenieNotifier.Notify() tags = %v, want %v", got.Tags, tt.wantTags)
+			}
+			if got.Alias != OpsgenieAlias(tt.logLine) {
+				t.Errorf("OpsgenieNotifier.Notify() alias = %v, want %v", got.Alias, OpsgenieAlias(tt.logLine))
+			}
+		})
+	}
+}
+
+func TestOpsgenieNotifier_Resolve(t *testing.T) {
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotQuery = r.URL.RawQuery
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	o := NewOpsgenieNotifier("key", server.URL, nil, zerolog.Nop())
+	if err := o.Resolve(LogLine{IpAddress: "1.2.3.4", EventType: LoggedIn, HostMachine: "foobar"}); err != nil {
+		t.Fatalf("OpsgenieNotifier.Resolve() error = %v", err)
+	}
+	if want := "/v2/alerts/ssh-watcher%2Ffoobar%2F1.2.3.4%2Flogged%20in/close"; gotPath != want {
+		t.Errorf("OpsgenieNotifier.Resolve() path = %v, want %v", gotPath, want)
+	}
+	if want := "identifierType=alias"; gotQuery != want {
+		t.Errorf("OpsgenieNotifier.Resolve() query = %v, want %v", gotQuery, want)
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		length int
+		want   string
+	}{
+		{name: "short", s: "abc", length: 5, want: "abc"},
+		{name: "ascii", s: "abcdef", length: 3, want: "abc"},
+		{name: "multi-byte characters", s: "Zürich, Málaga", length: 8, want: "Zürich, "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.s, tt.length); got != tt.want {
+				t.Errorf("truncate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
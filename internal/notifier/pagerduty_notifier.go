@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultPagerDutyEventsURL is the PagerDuty Events API v2 enqueue endpoint.
+	DefaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+	eventSource = "ssh-watcher"
+)
+
+// PagerDutyAction is the event action sent to the PagerDuty Events API.
+type PagerDutyAction string
+
+const (
+	PagerDutyTrigger PagerDutyAction = "trigger"
+	PagerDutyResolve PagerDutyAction = "resolve"
+)
+
+// PagerDutySeverity is a severity accepted by the PagerDuty Events API.
+type PagerDutySeverity string
+
+const (
+	PagerDutyCritical PagerDutySeverity = "critical"
+	PagerDutyError    PagerDutySeverity = "error"
+	PagerDutyWarning  PagerDutySeverity = "warning"
+	PagerDutyInfo     PagerDutySeverity = "info"
+)
+
+// pagerDutySeverities maps event severities to PagerDuty severities.
+var pagerDutySeverities = map[Severity]PagerDutySeverity{
+	SeverityInfo:     PagerDutyInfo,
+	SeverityLow:      PagerDutyInfo,
+	SeverityMedium:   PagerDutyWarning,
+	SeverityHigh:     PagerDutyError,
+	SeverityCritical: PagerDutyCritical,
+}
+
+type PagerDutyPayload struct {
+	RoutingKey  string                 `json:"routing_key"`
+	EventAction PagerDutyAction        `json:"event_action"`
+	DedupKey    string                 `json:"dedup_key"`
+	Payload     *PagerDutyEventPayload `json:"payload,omitempty"`
+}
+
+type PagerDutyEventPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      PagerDutySeverity `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails LogLine           `json:"custom_details"`
+}
+
+func NewPagerDutyNotifier(routingKey, eventsURL string, log zerolog.Logger) PagerDutyNotifier {
+	if eventsURL == "" {
+		eventsURL = DefaultPagerDutyEventsURL
+	}
+	return PagerDutyNotifier{
+		RoutingKey: routingKey,
+		EventsURL:  eventsURL,
+		HttpClient: &http.Client{},
+		log:        log,
+	}
+}
+
+type PagerDutyNotifier struct {
+	RoutingKey string
+	EventsURL  string
+	HttpClient hTTPClient
+	log        zerolog.Logger
+}
+
+// resolvedOnUnban are the event types whose incidents are resolved when the
+// IP address they were triggered for is unbanned.
+var resolvedOnUnban = []EventType{
+	IpBanned,
+	BruteForceDetected,
+	PasswordSprayDetected,
+	FailedLoginAttempt,
+	FailedLoginAttemptInvalidUsername,
+}
+
+// Notify triggers a PagerDuty alert for the log line. Repeated triggers for
+// the same host, IP address and event type share a dedup key so PagerDuty
+// groups them into a single incident. An IpUnbanned event resolves the
+// incidents of the unbanned IP address instead of triggering one.
+func (p PagerDutyNotifier) Notify(logLine LogLine) error {
+	if logLine.EventType == IpUnbanned {
+		for _, eventType := range resolvedOnUnban {
+			resolved := LogLine{HostMachine: logLine.HostMachine, IpAddress: logLine.IpAddress, EventType: eventType}
+			if err := p.Resolve(resolved); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return p.send(PagerDutyTrigger, logLine)
+}
+
+// Resolve resolves the PagerDuty incident previously triggered for the log line.
+func (p PagerDutyNotifier) Resolve(logLine LogLine) error {
+	return p.send(PagerDutyResolve, logLine)
+}
+
+func (p PagerDutyNotifier) send(action PagerDutyAction, logLine LogLine) error {
+	payload := PagerDutyPayload{
+		RoutingKey:  p.RoutingKey,
+		EventAction: action,
+		DedupKey:    PagerDutyDedupKey(logLine),
+	}
+	if action == PagerDutyTrigger {
+		payload.Payload = &PagerDutyEventPayload{
+			Summary:       logLine.Summary(),
+			Source:        sourceOrDefault(logLine.HostMachine, eventSource),
+			Severity:      p.severity(logLine),
+			Component:     "sshd",
+			Class:         string(logLine.EventType),
+			CustomDetails: logLine,
+		}
+	}
+
+	p.log.Info().Msg(fmt.Sprintf("sending %s event to pagerduty with dedup key %s", action, payload.DedupKey))
+	bodyBytes, err := postJSON(p.HttpClient, p.EventsURL, nil, payload)
+	if err != nil {
+		p.log.Error().Err(err).Msg("failed sending pagerduty event")
+		return fmt.Errorf("error sending PagerDuty event: %w", err)
+	}
+	p.log.Info().Msg(string(bodyBytes))
+
+	return nil
+}
+
+func (p PagerDutyNotifier) severity(logLine LogLine) PagerDutySeverity {
+	if pagerDutySeverity, ok := pagerDutySeverities[eventSeverity(logLine)]; ok {
+		return pagerDutySeverity
+	}
+	return PagerDutyInfo
+}
+
+// PagerDutyDedupKey builds the dedup key for a log line from its host machine,
+// IP address and event type.
+func PagerDutyDedupKey(logLine LogLine) string {
+	return strings.Join([]string{eventSource, logLine.HostMachine, logLine.IpAddress, string(logLine.EventType)}, "/")
+}
+
+func sourceOrDefault(source, fallback string) string {
+	if source == "" {
+		return fallback
+	}
+	return source
+}
@@ -0,0 +1,203 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewPagerDutyNotifier(t *testing.T) {
+	type args struct {
+		routingKey string
+		eventsURL  string
+		log        zerolog.Logger
+	}
+	tests := []struct {
+		name string
+		args args
+		want PagerDutyNotifier
+	}{
+		{
+			name: "default events url",
+			args: args{
+				routingKey: "key",
+				log:        zerolog.Nop(),
+			},
+			want: PagerDutyNotifier{
+				RoutingKey: "key",
+				EventsURL:  DefaultPagerDutyEventsURL,
+				HttpClient: &http.Client{},
+				log:        zerolog.Nop(),
+			},
+		},
+		{
+			name: "custom events url",
+			args: args{
+				routingKey: "key",
+				eventsURL:  "http://localhost",
+				log:        zerolog.Nop(),
+			},
+			want: PagerDutyNotifier{
+				RoutingKey: "key",
+				EventsURL:  "http://localhost",
+				HttpClient: &http.Client{},
+				log:        zerolog.Nop(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewPagerDutyNotifier(tt.args.routingKey, tt.args.eventsURL, tt.args.log); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPagerDutyNotifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPagerDutyNotifier_Notify(t *testing.T) {
+	logLine := LogLine{
+		Username:    "root",
+		IpAddress:   "1.2.3.4",
+		LoginTime:   "Dec 1",
+		EventType:   LoggedIn,
+		HostMachine: "foobar",
+		Severity:    SeverityCritical,
+	}
+	tests := []struct {
+		name         string
+		status       int
+		logLine      LogLine
+		wantSeverity PagerDutySeverity
+		wantErr      bool
+	}{
+		{
+			name:         "accepted by events api",
+			status:       http.StatusAccepted,
+			logLine:      logLine,
+			wantSeverity: PagerDutyCritical,
+			wantErr:      false,
+		},
+		{
+			name:   "unmapped event type is info",
+			status: http.StatusAccepted,
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: EventType("something else"),
+			},
+			wantSeverity: PagerDutyInfo,
+			wantErr:      false,
+		},
+		{
+			name:   "severity of the event",
+			status: http.StatusAccepted,
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: BruteForceDetected,
+				Severity:  SeverityHigh,
+			},
+			wantSeverity: PagerDutyError,
+			wantErr:      false,
+		},
+		{
+			name:   "default severity of the event type",
+			status: http.StatusAccepted,
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: FailedLoginAttempt,
+			},
+			wantSeverity: PagerDutyInfo,
+			wantErr:      false,
+		},
+		{
+			name:         "rejected by events api",
+			status:       http.StatusBadRequest,
+			logLine:      logLine,
+			wantSeverity: PagerDutyCritical,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PagerDutyPayload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			p := NewPagerDutyNotifier("key", server.URL, zerolog.Nop())
+			if err := p.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("PagerDutyNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.EventAction != PagerDutyTrigger {
+				t.Errorf("PagerDutyNotifier.Notify() event_action = %v, want %v", got.EventAction, PagerDutyTrigger)
+			}
+			if got.DedupKey != PagerDutyDedupKey(tt.logLine) {
+				t.Errorf("PagerDutyNotifier.Notify() dedup_key = %v, want %v", got.DedupKey, PagerDutyDedupKey(tt.logLine))
+			}
+			if got.Payload == nil || got.Payload.Severity != tt.wantSeverity {
+				t.Errorf("PagerDutyNotifier.Notify() payload = %v, want severity %v", got.Payload, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestPagerDutyNotifier_Resolve(t *testing.T) {
+	var got PagerDutyPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed decoding request body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	logLine := LogLine{IpAddress: "1.2.3.4", EventType: LoggedIn, HostMachine: "foobar"}
+	p := NewPagerDutyNotifier("key", server.URL, zerolog.Nop())
+	if err := p.Resolve(logLine); err != nil {
+		t.Fatalf("PagerDutyNotifier.Resolve() error = %v", err)
+	}
+	want := PagerDutyPayload{
+		RoutingKey:  "key",
+		EventAction: PagerDutyResolve,
+		DedupKey:    "ssh-watcher/foobar/1.2.3.4/logged in",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PagerDutyNotifier.Resolve() payload = %v, want %v", got, want)
+	}
+}
+
+func TestPagerDutyNotifier_NotifyUnbanned(t *testing.T) {
+	var got []PagerDutyPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload PagerDutyPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed decoding request body: %v", err)
+		}
+		got = append(got, payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	p := NewPagerDutyNotifier("key", server.URL, zerolog.Nop())
+	if err := p.Notify(LogLine{IpAddress: "1.2.3.4", EventType: IpUnbanned, HostMachine: "foobar", Reason: "ban expired"}); err != nil {
+		t.Fatalf("PagerDutyNotifier.Notify() error = %v", err)
+	}
+	var want []PagerDutyPayload
+	for _, eventType := range []string{"IP banned", "brute force detected", "password spray detected", "failed login attempt", "failed login attempt with invalid username"} {
+		want = append(want, PagerDutyPayload{
+			RoutingKey:  "key",
+			EventAction: PagerDutyResolve,
+			DedupKey:    "ssh-watcher/foobar/1.2.3.4/" + eventType,
+		})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PagerDutyNotifier.Notify() payloads = %v, want %v", got, want)
+	}
+}
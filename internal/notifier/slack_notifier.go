@@ -1,37 +1,13 @@
 package notifier
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"time"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
-const (
-	defaultContextTimeout time.Duration = 5 * time.Second
-)
-
-// hTTPClient is an interface for making HTTP requests
-//
-//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . hTTPClient
-type hTTPClient interface {
-	Do(req *http.Request) (*http.Response, error)
-}
-
-// readCloser is the interface for io.ReadCloser.
-//
-//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . readCloser
-type readCloser interface {
-	io.Reader
-	io.Closer
-}
-
 func NewSlackNotifier(webhookURL, slackChannel, slackUsername, slackIcon string, log zerolog.Logger) SlackNotifier {
 	return SlackNotifier{
 		WebhookURL:    webhookURL,
@@ -55,50 +31,77 @@ type SlackNotifier struct {
 func (s SlackNotifier) Notify(logLine LogLine) error {
 	s.log.Info().Msg(fmt.Sprintf("Sending notification to slack: User %s %s from IP %s at %s\n", logLine.Username, logLine.EventType, logLine.IpAddress, logLine.LoginTime))
 
+	if logLine.Digest != nil {
+		return s.notifyDigest(*logLine.Digest)
+	}
+
 	payloadJson, err := json.Marshal(logLine)
 	if err != nil {
 		return fmt.Errorf("failed to marshal log line: %w", err)
 	}
 
+	severity := logLine.Severity
+	if severity == "" {
+		severity = DefaultSeverity(logLine.EventType)
+	}
 	slackPayload := SlackPayload{
 		Channel:   s.SlackChannel,
 		Username:  s.SlackUsername,
 		IconEmoji: s.SlackIcon,
 		Text:      string(payloadJson),
+		Attachments: []SlackAttachment{{
+			Color:    slackColor(severity),
+			Fallback: logLine.Summary(),
+			Title:    fmt.Sprintf("SSH %s (%s)", logLine.EventType, severity),
+			Text:     logLine.Summary(),
+		}},
 	}
 
-	s.log.Info().Msg(fmt.Sprintf("payload: %v", slackPayload))
+	return s.send(slackPayload)
+}
 
-	payloadJSON, err := json.Marshal(slackPayload)
-	if err != nil {
-		return fmt.Errorf("error marshaling Slack payload %v: %w", slackPayload, err)
-	}
+// notifyDigest sends a digest as a single attachment with a section per part
+// of the digest.
+func (s SlackNotifier) notifyDigest(digest Digest) error {
+	return s.send(SlackPayload{
+		Channel:   s.SlackChannel,
+		Username:  s.SlackUsername,
+		IconEmoji: s.SlackIcon,
+		Text:      digest.Title(),
+		Attachments: []SlackAttachment{{
+			Color:    slackColor(SeverityInfo),
+			Fallback: digest.Text(),
+			Title:    digest.Title(),
+			Text:     digest.Slack(),
+		}},
+	})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultContextTimeout)
-	defer cancel()
+func (s SlackNotifier) send(slackPayload SlackPayload) error {
+	s.log.Info().Msg(fmt.Sprintf("payload: %v", slackPayload))
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.WebhookURL, bytes.NewBuffer(payloadJSON))
+	bodyBytes, err := postJSON(s.HttpClient, s.WebhookURL, nil, slackPayload)
 	if err != nil {
-		return fmt.Errorf("error creating Slack request: %w", err)
+		s.log.Error().Err(err).Msg("failed sending slack message")
+		return fmt.Errorf("error sending Slack message: %w", err)
 	}
+	s.log.Info().Msg(string(bodyBytes))
 
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := s.HttpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending Slack request: %w", err)
-	}
+	return nil
+}
 
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading Slack response body: %w", err)
-	}
-	s.log.Info().Msg(string(bodyBytes))
+// slackColors maps severities to the colour of the attachment bar.
+var slackColors = map[Severity]string{
+	SeverityInfo:     "#439FE0",
+	SeverityLow:      "good",
+	SeverityMedium:   "warning",
+	SeverityHigh:     "#E8912D",
+	SeverityCritical: "danger",
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Error().Msg(fmt.Sprintf("response status code not ok: %v", resp.StatusCode))
-		return fmt.Errorf("error sending Slack message: %s", resp.Status)
+func slackColor(severity Severity) string {
+	if color, ok := slackColors[severity]; ok {
+		return color
 	}
-
-	return nil
+	return slackColors[SeverityInfo]
 }
@@ -217,3 +217,22 @@ func TestSlackNotifier_Notify(t *testing.T) {
 		})
 	}
 }
+
+func Test_slackColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		severity Severity
+		want     string
+	}{
+		{name: "critical", severity: SeverityCritical, want: "danger"},
+		{name: "medium", severity: SeverityMedium, want: "warning"},
+		{name: "unknown", severity: Severity("urgent"), want: "#439FE0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slackColor(tt.severity); got != tt.want {
+				t.Errorf("slackColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultTelegramAPIURL is the Telegram Bot API base URL.
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+type TelegramPayload struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+func NewTelegramNotifier(botToken, chatID, apiURL string, log zerolog.Logger) TelegramNotifier {
+	if apiURL == "" {
+		apiURL = DefaultTelegramAPIURL
+	}
+	return TelegramNotifier{
+		BotToken:   botToken,
+		ChatID:     chatID,
+		APIURL:     strings.TrimRight(apiURL, "/"),
+		HttpClient: &http.Client{},
+		log:        log,
+	}
+}
+
+type TelegramNotifier struct {
+	BotToken string
+	// ChatID is the chat, group or channel id (or @channelusername) to post to.
+	ChatID     string
+	APIURL     string
+	HttpClient hTTPClient
+	log        zerolog.Logger
+}
+
+// Notify sends the log line as a MarkdownV2 formatted message through the
+// Telegram Bot API sendMessage method.
+func (t TelegramNotifier) Notify(logLine LogLine) error {
+	payload := TelegramPayload{
+		ChatID:    t.ChatID,
+		Text:      telegramMessage(logLine),
+		ParseMode: "MarkdownV2",
+	}
+
+	t.log.Info().Msg(fmt.Sprintf("sending message to telegram chat %s", t.ChatID))
+	sendURL := fmt.Sprintf("%s/bot%s/sendMessage", t.APIURL, t.BotToken)
+	if _, err := postJSON(t.HttpClient, sendURL, nil, payload); err != nil {
+		t.log.Error().Err(err).Msg("failed sending telegram message")
+		return fmt.Errorf("error sending Telegram message: %w", err)
+	}
+
+	return nil
+}
+
+// telegramMaxDetailsLength keeps messages with long details below the 4096
+// characters Telegram accepts.
+const telegramMaxDetailsLength = 3500
+
+func telegramMessage(logLine LogLine) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n", EscapeMarkdownV2("SSH "+string(logLine.EventType)))
+	for _, field := range messageFields(logLine) {
+		fmt.Fprintf(&b, "%s: `%s`\n", EscapeMarkdownV2(field.name), escapeMarkdownV2Code(field.value))
+	}
+	if details := messageDetails(logLine); details != "" {
+		fmt.Fprintf(&b, "```\n%s\n```\n", escapeMarkdownV2Code(truncate(details, telegramMaxDetailsLength)))
+	}
+	return b.String()
+}
+
+// markdownV2Replacer escapes the characters reserved by Telegram MarkdownV2
+// outside of code entities.
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// EscapeMarkdownV2 escapes s so it is rendered literally by Telegram MarkdownV2.
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// escapeMarkdownV2Code escapes s for use inside a MarkdownV2 code entity where
+// only backslash and backtick are reserved.
+func escapeMarkdownV2Code(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s)
+}
+
+type messageField struct {
+	name  string
+	value string
+}
+
+// messageFields returns the non empty event fields shown in chat messages.
+func messageFields(logLine LogLine) []messageField {
+	location := ""
+	if logLine.Location != nil {
+		location = logLine.Location.String()
+	}
+	attempts := ""
+	if logLine.Count > 0 {
+		attempts = fmt.Sprint(logLine.Count)
+	}
+	repeated := ""
+	if logLine.Repeats > 0 {
+		repeated = fmt.Sprintf("%d times", logLine.Repeats)
+	}
+	fields := []messageField{
+		{name: "User", value: logLine.Username},
+		{name: "IP", value: logLine.IpAddress},
+		{name: "Location", value: location},
+		{name: "Threat feeds", value: strings.Join(logLine.ThreatFeeds, ", ")},
+		{name: "Key owner", value: logLine.KeyOwner},
+		{name: "Attempts", value: attempts},
+		{name: "Elapsed", value: logLine.Elapsed},
+		{name: "Users", value: strings.Join(logLine.Usernames, ", ")},
+		{name: "IPs", value: strings.Join(logLine.IpAddresses, ", ")},
+		{name: "Time", value: logLine.LoginTime},
+		{name: "Host", value: logLine.HostMachine},
+		{name: "Reason", value: logLine.Reason},
+		{name: "Repeated", value: repeated},
+	}
+	nonEmpty := fields[:0]
+	for _, field := range fields {
+		if field.value != "" {
+			nonEmpty = append(nonEmpty, field)
+		}
+	}
+	return nonEmpty
+}
+
+// messageDetails returns the multi-line content of the log line shown below
+// the fields of chat messages: the text of a digest, the diff of a file change
+// or the raw log lines of a canary event.
+func messageDetails(logLine LogLine) string {
+	if logLine.Digest != nil {
+		return logLine.Digest.Text()
+	}
+	var parts []string
+	if logLine.Diff != "" {
+		parts = append(parts, logLine.Diff)
+	}
+	if len(logLine.RawLines) > 0 {
+		parts = append(parts, fmt.Sprintf("Recent log lines of %s:\n%s", logLine.IpAddress, strings.Join(logLine.RawLines, "\n")))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,144 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "ip address",
+			in:   "1.2.3.4",
+			want: `1\.2\.3\.4`,
+		},
+		{
+			name: "reserved characters",
+			in:   "_*[]()~`>#+-=|{}.!\\",
+			want: "\\_\\*\\[\\]\\(\\)\\~\\`\\>\\#\\+\\-\\=\\|\\{\\}\\.\\!\\\\",
+		},
+		{
+			name: "plain text",
+			in:   "logged in",
+			want: "logged in",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeMarkdownV2(tt.in); got != tt.want {
+				t.Errorf("EscapeMarkdownV2() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelegramNotifier_Notify(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		logLine  LogLine
+		wantText string
+		wantErr  bool
+	}{
+		{
+			name:   "message sent",
+			status: http.StatusOK,
+			logLine: LogLine{
+				Username:    "foo_bar",
+				IpAddress:   "1.2.3.4",
+				EventType:   LoggedIn,
+				HostMachine: "web-1",
+			},
+			wantText: "*SSH logged in*\nUser: `foo_bar`\nIP: `1.2.3.4`\nHost: `web-1`\n",
+			wantErr:  false,
+		},
+		{
+			name:   "diff",
+			status: http.StatusOK,
+			logLine: LogLine{
+				EventType:   SshdConfigChanged,
+				HostMachine: "web-1",
+				Reason:      "/etc/ssh/sshd_config changed",
+				Diff:        "+ PermitRootLogin yes\n- PermitRootLogin no",
+			},
+			wantText: "*SSH sshd config changed*\nHost: `web-1`\nReason: `/etc/ssh/sshd_config changed`\n```\n+ PermitRootLogin yes\n- PermitRootLogin no\n```\n",
+			wantErr:  false,
+		},
+		{
+			name:   "detection",
+			status: http.StatusOK,
+			logLine: LogLine{
+				IpAddress: "1.2.3.4",
+				EventType: BruteForceDetected,
+				Count:     12,
+				Usernames: []string{"root", "admin"},
+				Repeats:   2,
+			},
+			wantText: "*SSH brute force detected*\nIP: `1.2.3.4`\nAttempts: `12`\nUsers: `root, admin`\nRepeated: `2 times`\n",
+			wantErr:  false,
+		},
+		{
+			name:   "bad request",
+			status: http.StatusBadRequest,
+			logLine: LogLine{
+				EventType: FailedLoginAttempt,
+			},
+			wantText: "*SSH failed login attempt*\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got TelegramPayload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/bottoken/sendMessage" {
+					t.Errorf("unexpected request path %s", r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			tg := NewTelegramNotifier("token", "-100", server.URL, zerolog.Nop())
+			if err := tg.Notify(tt.logLine); (err != nil) != tt.wantErr {
+				t.Errorf("TelegramNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Text != tt.wantText {
+				t.Errorf("TelegramNotifier.Notify() text = %q, want %q", got.Text, tt.wantText)
+			}
+			if got.ChatID != "-100" || got.ParseMode != "MarkdownV2" {
+				t.Errorf("TelegramNotifier.Notify() payload = %v", got)
+			}
+		})
+	}
+}
+
+func TestTelegramNotifier_NotifyDigest(t *testing.T) {
+	var got TelegramPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed decoding request body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	d := testDigest()
+	tg := NewTelegramNotifier("token", "-100", server.URL, zerolog.Nop())
+	if err := tg.Notify(LogLine{EventType: DigestSummary, Digest: &d}); err != nil {
+		t.Fatalf("TelegramNotifier.Notify() error = %v", err)
+	}
+	want := "*SSH digest*\n```\n" + escapeMarkdownV2Code(d.Text()) + "\n```\n"
+	if got.Text != want {
+		t.Errorf("TelegramNotifier.Notify() text = %q, want %q", got.Text, want)
+	}
+}
@@ -0,0 +1,168 @@
+// Package patterns parses log lines with the user defined patterns of the
+// patterns file.
+package patterns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// File is the content of the patterns file.
+type File struct {
+	Patterns []Pattern `json:"patterns"`
+}
+
+// Pattern turns the log lines matching Regex into events of EventType. The
+// named capture groups of Regex set the event fields of the same name:
+// username, ip_address, auth_method, key_fingerprint and login_time.
+type Pattern struct {
+	Name string `json:"name"`
+	// Programs limits the pattern to lines logged by these programs, e.g.
+	// sshd or sshd(pam_google_authenticator). Empty matches every program.
+	Programs  []string `json:"programs"`
+	Regex     string   `json:"regex"`
+	EventType string   `json:"event_type"`
+	// Severity is the severity of the events, by default the default of
+	// EventType.
+	Severity string `json:"severity"`
+}
+
+// fields maps capture group names to the event fields they set.
+var fields = map[string]func(logLine *notifier.LogLine, value string){
+	"username":        func(l *notifier.LogLine, v string) { l.Username = v },
+	"ip_address":      func(l *notifier.LogLine, v string) { l.IpAddress = v },
+	"auth_method":     func(l *notifier.LogLine, v string) { l.AuthMethod = v },
+	"key_fingerprint": func(l *notifier.LogLine, v string) { l.KeyFingerprint = v },
+	"login_time":      func(l *notifier.LogLine, v string) { l.LoginTime = v },
+}
+
+// pattern is the compiled form of a Pattern.
+type pattern struct {
+	name      string
+	programs  map[string]bool
+	regex     *regexp.Regexp
+	eventType notifier.EventType
+	severity  notifier.Severity
+}
+
+// Load reads the patterns file at path.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("failed reading patterns file: %w", err)
+	}
+	file := File{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return File{}, fmt.Errorf("failed parsing patterns file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+func compile(p Pattern) (pattern, error) {
+	if p.Name == "" {
+		return pattern{}, fmt.Errorf("pattern has no name")
+	}
+	if p.EventType == "" {
+		return pattern{}, fmt.Errorf("pattern %q has no event type", p.Name)
+	}
+	regex, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return pattern{}, fmt.Errorf("pattern %q: %w", p.Name, err)
+	}
+	for _, group := range regex.SubexpNames()[1:] {
+		if _, ok := fields[group]; group != "" && !ok {
+			return pattern{}, fmt.Errorf("pattern %q has capture group %q that is not an event field", p.Name, group)
+		}
+	}
+	compiled := pattern{
+		name:      p.Name,
+		programs:  map[string]bool{},
+		regex:     regex,
+		eventType: notifier.EventType(p.EventType),
+		severity:  notifier.DefaultSeverity(notifier.EventType(p.EventType)),
+	}
+	if p.Severity != "" {
+		compiled.severity, err = notifier.ParseSeverity(p.Severity)
+		if err != nil {
+			return pattern{}, fmt.Errorf("pattern %q: %w", p.Name, err)
+		}
+	}
+	for _, program := range p.Programs {
+		compiled.programs[program] = true
+	}
+	return compiled, nil
+}
+
+// New compiles the patterns of file.
+func New(file File) (Parser, error) {
+	parser := Parser{}
+	for _, p := range file.Patterns {
+		compiled, err := compile(p)
+		if err != nil {
+			return Parser{}, err
+		}
+		parser.patterns = append(parser.patterns, compiled)
+	}
+	return parser, nil
+}
+
+// Parser parses log lines with the first matching pattern.
+type Parser struct {
+	patterns []pattern
+}
+
+// Parse returns the event of the first pattern matching the line.
+func (p Parser) Parse(line string) (notifier.LogLine, bool) {
+	_, logLine, ok := p.Match(line)
+	return logLine, ok
+}
+
+// Match returns the name and event of the first pattern matching the line.
+func (p Parser) Match(line string) (string, notifier.LogLine, bool) {
+	program := programOf(line)
+	for _, pattern := range p.patterns {
+		if len(pattern.programs) > 0 && !pattern.programs[program] {
+			continue
+		}
+		groups := pattern.regex.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		logLine := notifier.LogLine{
+			EventType: pattern.eventType,
+			Severity:  pattern.severity,
+		}
+		for i, name := range pattern.regex.SubexpNames() {
+			if set, ok := fields[name]; ok && groups[i] != "" {
+				set(&logLine, groups[i])
+			}
+		}
+		return pattern.name, logLine, true
+	}
+	return "", notifier.LogLine{}, false
+}
+
+// programOf returns the program that logged a syslog line, e.g. sshd for
+// "Mar 30 00:00:00 host sshd[5052]: ...".
+func programOf(line string) string {
+	tokens := strings.Fields(line)
+	for i := 1; i < len(tokens) && i < 6; i++ {
+		if !strings.HasSuffix(tokens[i], ":") {
+			continue
+		}
+		program := strings.TrimSuffix(tokens[i], ":")
+		if pid := strings.LastIndex(program, "["); pid > 0 && strings.HasSuffix(program, "]") {
+			program = program[:pid]
+		}
+		return program
+	}
+	return ""
+}
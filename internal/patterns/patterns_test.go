@@ -0,0 +1,146 @@
+package patterns
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestParser_Match(t *testing.T) {
+	parser, err := New(File{Patterns: []Pattern{
+		{
+			Name:      "google authenticator",
+			Programs:  []string{"sshd(pam_google_authenticator)"},
+			Regex:     `Invalid verification code for (?P<username>\S+)`,
+			EventType: "invalid verification code",
+			Severity:  "medium",
+		},
+		{
+			Name:      "duo",
+			Programs:  []string{"sshd"},
+			Regex:     `Failed Duo login for '(?P<username>[^']+)' from (?P<ip_address>\S+)`,
+			EventType: "duo login failed",
+		},
+		{
+			Name:      "patched sshd",
+			Regex:     `Rejected (?P<auth_method>\S+) for (?P<username>\S+) from (?P<ip_address>\S+)`,
+			EventType: string(notifier.FailedLoginAttempt),
+		},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		line     string
+		wantName string
+		want     notifier.LogLine
+		wantOk   bool
+	}{
+		{
+			name:     "pam module program",
+			line:     "Mar 30 00:00:00 foo sshd(pam_google_authenticator)[5052]: Invalid verification code for alice",
+			wantName: "google authenticator",
+			want:     notifier.LogLine{Username: "alice", EventType: "invalid verification code", Severity: notifier.SeverityMedium},
+			wantOk:   true,
+		},
+		{
+			name:     "custom event type defaults to info",
+			line:     "Mar 30 00:00:00 foo sshd[5052]: Failed Duo login for 'bob' from 1.2.3.4",
+			wantName: "duo",
+			want:     notifier.LogLine{Username: "bob", IpAddress: "1.2.3.4", EventType: "duo login failed", Severity: notifier.SeverityInfo},
+			wantOk:   true,
+		},
+		{
+			name:   "other program",
+			line:   "Mar 30 00:00:00 foo login[5052]: Failed Duo login for 'bob' from 1.2.3.4",
+			wantOk: false,
+		},
+		{
+			name:     "built-in event type keeps its default severity",
+			line:     "2024-03-30T00:00:00.000000+00:00 foo sshd[5052]: Rejected keyboard-interactive for carol from 2001:db8::1",
+			wantName: "patched sshd",
+			want:     notifier.LogLine{Username: "carol", IpAddress: "2001:db8::1", AuthMethod: "keyboard-interactive", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow},
+			wantOk:   true,
+		},
+		{
+			name:   "no match",
+			line:   "Mar 30 00:00:00 foo sshd[5052]: Accepted password for alice from 1.2.3.4 port 22 ssh2",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, got, ok := parser.Match(tt.line)
+			if ok != tt.wantOk || name != tt.wantName || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %q, %+v, %v, want %q, %+v, %v", name, got, ok, tt.wantName, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern Pattern
+	}{
+		{name: "no name", pattern: Pattern{Regex: "x", EventType: "x"}},
+		{name: "no event type", pattern: Pattern{Name: "x", Regex: "x"}},
+		{name: "invalid regex", pattern: Pattern{Name: "x", Regex: "(", EventType: "x"}},
+		{name: "unknown field", pattern: Pattern{Name: "x", Regex: "(?P<password>\\S+)", EventType: "x"}},
+		{name: "invalid severity", pattern: Pattern{Name: "x", Regex: "x", EventType: "x", Severity: "urgent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(File{Patterns: []Pattern{tt.pattern}}); err == nil {
+				t.Error("New() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: `{"patterns": [{"name": "x", "regex": "x", "event_type": "x"}]}`},
+		{name: "invalid json", content: `{"patterns": [`, wantErr: true},
+		{name: "unknown field", content: `{"patterns": [{"name": "x", "regexp": "x", "event_type": "x"}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "patterns.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_programOf(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "Mar 30 00:00:00 foo sshd[5052]: Accepted password", want: "sshd"},
+		{line: "Mar  3 00:00:00 foo sshd(pam_unix)[5052]: session opened", want: "sshd(pam_unix)"},
+		{line: "2024-03-30T00:00:00+00:00 foo sudo: alice : TTY=pts/0", want: "sudo"},
+		{line: "garbage", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := programOf(tt.line); got != tt.want {
+				t.Errorf("programOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
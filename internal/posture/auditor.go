@@ -0,0 +1,141 @@
+package posture
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+	"github.com/rs/zerolog"
+)
+
+// notifierClient is an interface for sending notifications
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . notifierClient
+type notifierClient interface {
+	Notify(LogLine notifier.LogLine) error
+}
+
+// Settings configures the auditor.
+type Settings struct {
+	// HostMachine is reported as the host of regression events.
+	HostMachine string
+	// SshdConfig is the location of the main sshd configuration file.
+	SshdConfig string
+	// StateFilePath is the location of the file the findings of the last
+	// audit are persisted in.
+	StateFilePath string
+}
+
+// state is the persisted result of the last audit.
+type state struct {
+	AuditedAt time.Time `json:"audited_at"`
+	Findings  []Finding `json:"findings"`
+}
+
+// New creates an auditor comparing audits against the findings persisted at
+// settings.StateFilePath and reporting regressions through client.
+func New(client notifierClient, settings Settings, log zerolog.Logger) (*Auditor, error) {
+	a := &Auditor{
+		notifier: client,
+		settings: settings,
+		now:      time.Now,
+		log:      log,
+	}
+	if err := statefile.Load(settings.StateFilePath, &a.state); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Auditor periodically audits the sshd configuration and reports findings
+// that were not present in the previous audit.
+type Auditor struct {
+	notifier notifierClient
+	settings Settings
+	state    state
+	// logged is set once the report of the first audit is logged.
+	logged bool
+	now    func() time.Time
+	log    zerolog.Logger
+}
+
+// Check audits the sshd configuration, reports regressions since the previous
+// audit and persists the findings. The first audit in the process logs every
+// finding; the first audit ever only records them. A configuration that cannot
+// be read keeps the previous findings.
+func (a *Auditor) Check() {
+	findings, err := Audit(a.settings.SshdConfig)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed auditing sshd config")
+		return
+	}
+	if !a.logged {
+		a.logReport(findings)
+		a.logged = true
+	}
+
+	previous := map[string]bool{}
+	for _, finding := range a.state.Findings {
+		previous[finding.key()] = true
+	}
+	current := map[string]bool{}
+	for _, finding := range findings {
+		current[finding.key()] = true
+		if previous[finding.key()] || a.state.AuditedAt.IsZero() {
+			continue
+		}
+		a.log.Warn().Str("finding", finding.String()).Msg("sshd posture regression")
+		event := a.regression(finding)
+		if err := a.notifier.Notify(event); err != nil {
+			a.log.Error().Err(err).Msg(fmt.Sprintf("failed reporting %s", event.EventType))
+		}
+	}
+	for _, finding := range a.state.Findings {
+		if !current[finding.key()] {
+			a.log.Info().Str("finding", finding.String()).Msg("sshd posture finding resolved")
+		}
+	}
+
+	a.state = state{AuditedAt: a.now(), Findings: findings}
+	if err := statefile.Save(a.settings.StateFilePath, a.state); err != nil {
+		a.log.Error().Err(err).Msg("failed persisting sshd posture findings")
+	}
+}
+
+// Run audits the sshd configuration now and then every interval until ctx is
+// done.
+func (a *Auditor) Run(ctx context.Context, interval time.Duration) {
+	a.Check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.Check()
+		}
+	}
+}
+
+func (a *Auditor) logReport(findings []Finding) {
+	if len(findings) == 0 {
+		a.log.Info().Str("sshd_config", a.settings.SshdConfig).Msg("sshd posture audit found no issues")
+		return
+	}
+	for _, finding := range findings {
+		a.log.Warn().Str("finding", finding.String()).Str("severity", string(finding.Severity)).Msg("sshd posture finding")
+	}
+}
+
+func (a *Auditor) regression(finding Finding) notifier.LogLine {
+	return notifier.LogLine{
+		LoginTime:   a.now().Format(time.RFC3339),
+		EventType:   notifier.SshdPostureRegression,
+		HostMachine: a.settings.HostMachine,
+		Severity:    finding.Severity,
+		Reason:      finding.String(),
+	}
+}
@@ -0,0 +1,67 @@
+package posture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/posture/posturefakes"
+	"github.com/rs/zerolog"
+)
+
+func TestAuditor_Check(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, map[string]string{"sshd_config": "PermitRootLogin yes\n" + hardened})
+	settings := Settings{
+		HostMachine:   "web-1",
+		SshdConfig:    path,
+		StateFilePath: filepath.Join(dir, "state", "posture.json"),
+	}
+	newAuditor := func(client *posturefakes.FakeNotifierClient) *Auditor {
+		a, err := New(client, settings, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		a.now = func() time.Time { return time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC) }
+		return a
+	}
+
+	client := &posturefakes.FakeNotifierClient{}
+	a := newAuditor(client)
+	a.Check()
+	if client.NotifyCallCount() != 0 {
+		t.Fatalf("Notify() called %d times, want the first audit only recorded", client.NotifyCallCount())
+	}
+
+	// A restarted auditor compares against the persisted findings.
+	writeConfig(t, dir, map[string]string{"sshd_config": "PasswordAuthentication yes\n" + hardened})
+	a = newAuditor(client)
+	a.Check()
+	if client.NotifyCallCount() != 1 {
+		t.Fatalf("Notify() called %d times, want 1", client.NotifyCallCount())
+	}
+	want := notifier.LogLine{
+		LoginTime:   "2024-01-01T03:00:00Z",
+		EventType:   notifier.SshdPostureRegression,
+		HostMachine: "web-1",
+		Severity:    notifier.SeverityHigh,
+		Reason:      "PasswordAuthentication yes at " + path + ":1, want no",
+	}
+	if got := client.NotifyArgsForCall(0); got.Reason != want.Reason || got.EventType != want.EventType || got.Severity != want.Severity || got.HostMachine != want.HostMachine || got.LoginTime != want.LoginTime {
+		t.Errorf("Notify() = %+v, want %+v", got, want)
+	}
+
+	// Unchanged findings and unreadable configurations are not reported.
+	a.Check()
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	a.Check()
+	writeConfig(t, dir, map[string]string{"sshd_config": "PasswordAuthentication yes\n" + hardened})
+	a.Check()
+	if client.NotifyCallCount() != 1 {
+		t.Errorf("Notify() called %d times, want 1", client.NotifyCallCount())
+	}
+}
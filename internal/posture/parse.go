@@ -0,0 +1,121 @@
+package posture
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth is the deepest nesting of Include directives followed, as in
+// sshd.
+const maxIncludeDepth = 16
+
+// Directive is a keyword set in an sshd configuration file.
+type Directive struct {
+	// Keyword is the keyword in lower case, e.g. passwordauthentication.
+	Keyword string
+	Value   string
+	File    string
+	Line    int
+	// Match holds the criteria of the Match block setting the keyword, e.g.
+	// "User deploy", and is empty for global keywords.
+	Match string
+}
+
+// Location returns the file and line the directive is set on.
+func (d Directive) Location() string {
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+// Parse reads the sshd configuration file at path and the files it includes
+// and returns the directives in the order sshd reads them. Relative Include
+// paths are resolved against the directory of path, like sshd resolves them
+// against /etc/ssh.
+func Parse(path string) ([]Directive, error) {
+	p := parser{dir: filepath.Dir(path)}
+	if err := p.parseFile(path, "", 0); err != nil {
+		return nil, err
+	}
+	return p.directives, nil
+}
+
+type parser struct {
+	dir        string
+	directives []Directive
+}
+
+// parseFile appends the directives of the file at path. match is the Match
+// block the file is included in. A Match block started in an included file
+// ends with the file.
+func (p *parser) parseFile(path, match string, depth int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed reading sshd config: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		keyword, value, ok := splitDirective(scanner.Text())
+		if !ok {
+			continue
+		}
+		switch keyword {
+		case "match":
+			match = value
+			if strings.EqualFold(value, "all") {
+				match = ""
+			}
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%s:%d: too many nested includes", path, lineNumber)
+			}
+			for _, pattern := range strings.Fields(value) {
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(p.dir, pattern)
+				}
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return fmt.Errorf("%s:%d: invalid include %q: %w", path, lineNumber, pattern, err)
+				}
+				for _, included := range matches {
+					if err := p.parseFile(included, match, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			p.directives = append(p.directives, Directive{
+				Keyword: keyword,
+				Value:   value,
+				File:    path,
+				Line:    lineNumber,
+				Match:   match,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed reading sshd config %s: %w", path, err)
+	}
+	return nil
+}
+
+// splitDirective splits a configuration line into its lower case keyword and
+// its value. Keywords and values are separated by whitespace or an equal
+// sign. It reports false for blank lines and comments.
+func splitDirective(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), "", true
+	}
+	keyword := strings.ToLower(line[:end])
+	value := strings.TrimSpace(line[end:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return keyword, strings.Trim(value, `"`), true
+}
@@ -0,0 +1,224 @@
+// Package posture audits the effective sshd configuration against a hardening
+// policy.
+package posture
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// Finding is a setting of the sshd configuration violating the policy.
+type Finding struct {
+	// Check is the keyword checked, e.g. PasswordAuthentication.
+	Check string `json:"check"`
+	// Match holds the criteria of the Match block the setting applies to and
+	// is empty for global settings.
+	Match string `json:"match,omitempty"`
+	// Value is the offending value, for algorithm lists the weak algorithms.
+	Value string `json:"value"`
+	// Want describes the values the policy allows.
+	Want string `json:"want"`
+	// Location is the file and line of the setting, empty when sshd's default
+	// applies.
+	Location string            `json:"location,omitempty"`
+	Severity notifier.Severity `json:"severity"`
+}
+
+// key identifies a finding across audits regardless of where it is set.
+func (f Finding) key() string {
+	return f.Check + "\x00" + f.Match + "\x00" + f.Value
+}
+
+func (f Finding) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", f.Check, f.Value)
+	if f.Match != "" {
+		fmt.Fprintf(&b, " in Match %s", f.Match)
+	}
+	if f.Location != "" {
+		fmt.Fprintf(&b, " at %s", f.Location)
+	} else {
+		b.WriteString(" by default")
+	}
+	fmt.Fprintf(&b, ", want %s", f.Want)
+	return b.String()
+}
+
+// check is a policy check of one keyword.
+type check struct {
+	keyword      string
+	defaultValue string
+	want         string
+	severity     notifier.Severity
+	// violation returns the offending part of value, or "" if value passes.
+	violation func(value string) string
+}
+
+var (
+	weakKexAlgorithms = toSet(
+		"diffie-hellman-group1-sha1",
+		"diffie-hellman-group14-sha1",
+		"diffie-hellman-group-exchange-sha1",
+	)
+	weakCiphers = toSet(
+		"3des-cbc",
+		"aes128-cbc",
+		"aes192-cbc",
+		"aes256-cbc",
+		"arcfour",
+		"arcfour128",
+		"arcfour256",
+		"blowfish-cbc",
+		"cast128-cbc",
+		"rijndael-cbc@lysator.liu.se",
+	)
+)
+
+// checks are the policy checks, defaulting to the values of OpenSSH 7.0 and
+// later.
+var checks = []check{
+	{
+		keyword:      "PasswordAuthentication",
+		defaultValue: "yes",
+		want:         "no",
+		severity:     notifier.SeverityHigh,
+		violation:    unless("no"),
+	},
+	{
+		keyword:      "PermitRootLogin",
+		defaultValue: "prohibit-password",
+		want:         "no or prohibit-password",
+		severity:     notifier.SeverityHigh,
+		violation:    unless("no", "prohibit-password", "without-password", "forced-commands-only"),
+	},
+	{
+		// Key fingerprints are only logged from VERBOSE on.
+		keyword:      "LogLevel",
+		defaultValue: "INFO",
+		want:         "VERBOSE or higher",
+		severity:     notifier.SeverityMedium,
+		violation:    unless("verbose", "debug", "debug1", "debug2", "debug3"),
+	},
+	{
+		keyword:   "KexAlgorithms",
+		want:      "no weak algorithms",
+		severity:  notifier.SeverityHigh,
+		violation: weak(weakKexAlgorithms),
+	},
+	{
+		keyword:   "Ciphers",
+		want:      "no weak algorithms",
+		severity:  notifier.SeverityHigh,
+		violation: weak(weakCiphers),
+	},
+}
+
+func toSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// unless returns a violation func passing only the allowed values, compared
+// case insensitively.
+func unless(allowed ...string) func(string) string {
+	set := toSet(allowed...)
+	return func(value string) string {
+		if set[strings.ToLower(value)] {
+			return ""
+		}
+		return value
+	}
+}
+
+// weak returns a violation func listing the algorithms of a comma separated
+// list that are in set. Lists removing algorithms from the default, starting
+// with "-", pass; lists appending or prepending to it, starting with "+" or
+// "^", are checked.
+func weak(set map[string]bool) func(string) string {
+	return func(value string) string {
+		if strings.HasPrefix(value, "-") {
+			return ""
+		}
+		value = strings.TrimLeft(value, "+^")
+		var found []string
+		for _, algorithm := range strings.Split(value, ",") {
+			algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+			if set[algorithm] {
+				found = append(found, algorithm)
+			}
+		}
+		return strings.Join(found, ",")
+	}
+}
+
+// Evaluate checks the directives of an sshd configuration against the
+// policy. Global settings are checked first, with sshd's default for unset
+// keywords. Settings of Match blocks are checked separately as they override
+// the global ones for matching connections. As in sshd, the first value of a
+// keyword wins.
+func Evaluate(directives []Directive) []Finding {
+	byKeyword := make(map[string]check, len(checks))
+	for _, c := range checks {
+		byKeyword[strings.ToLower(c.keyword)] = c
+	}
+
+	var findings []Finding
+	for _, c := range checks {
+		setting := Directive{Value: c.defaultValue}
+		for _, d := range directives {
+			if d.Match == "" && d.Keyword == strings.ToLower(c.keyword) {
+				setting = d
+				break
+			}
+		}
+		if finding, ok := evaluate(c, setting); ok {
+			findings = append(findings, finding)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, d := range directives {
+		c, ok := byKeyword[d.Keyword]
+		if d.Match == "" || !ok || seen[d.Match+"\x00"+d.Keyword] {
+			continue
+		}
+		seen[d.Match+"\x00"+d.Keyword] = true
+		if finding, ok := evaluate(c, d); ok {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func evaluate(c check, setting Directive) (Finding, bool) {
+	value := c.violation(setting.Value)
+	if value == "" {
+		return Finding{}, false
+	}
+	finding := Finding{
+		Check:    c.keyword,
+		Match:    setting.Match,
+		Value:    value,
+		Want:     c.want,
+		Severity: c.severity,
+	}
+	if setting.File != "" {
+		finding.Location = setting.Location()
+	}
+	return finding, true
+}
+
+// Audit parses the sshd configuration file at path and the files it
+// includes and checks it against the policy.
+func Audit(path string) ([]Finding, error) {
+	directives, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(directives), nil
+}
@@ -0,0 +1,125 @@
+package posture
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+const hardened = "PasswordAuthentication no\nPermitRootLogin prohibit-password\nLogLevel VERBOSE\n"
+
+func writeConfig(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "sshd_config")
+}
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want lists findings with locations relative to the config dir.
+		want []Finding
+	}{
+		{
+			name:  "hardened",
+			files: map[string]string{"sshd_config": "# hardened\n" + hardened},
+		},
+		{
+			name:  "defaults",
+			files: map[string]string{"sshd_config": ""},
+			want: []Finding{
+				{Check: "PasswordAuthentication", Value: "yes", Want: "no", Severity: notifier.SeverityHigh},
+				{Check: "LogLevel", Value: "INFO", Want: "VERBOSE or higher", Severity: notifier.SeverityMedium},
+			},
+		},
+		{
+			name: "first value wins",
+			files: map[string]string{"sshd_config": hardened +
+				"PasswordAuthentication yes\npermitrootlogin=yes\n"},
+		},
+		{
+			name: "included files are read in place",
+			files: map[string]string{
+				"sshd_config":                  "Include sshd_config.d/*.conf\n" + hardened,
+				"sshd_config.d/50-cloud.conf":  "PasswordAuthentication yes\n",
+				"sshd_config.d/60-ignored.txt": "PermitRootLogin yes\n",
+			},
+			want: []Finding{
+				{Check: "PasswordAuthentication", Value: "yes", Want: "no", Location: "sshd_config.d/50-cloud.conf:1", Severity: notifier.SeverityHigh},
+			},
+		},
+		{
+			name: "match blocks are checked separately",
+			files: map[string]string{"sshd_config": hardened +
+				"Match User deploy\n  PasswordAuthentication yes\n  PasswordAuthentication no\n" +
+				"Match Address 10.0.0.0/8\n  PermitRootLogin yes\n" +
+				"Match all\nLogLevel INFO\n"},
+			want: []Finding{
+				{Check: "PasswordAuthentication", Match: "User deploy", Value: "yes", Want: "no", Location: "sshd_config:5", Severity: notifier.SeverityHigh},
+				{Check: "PermitRootLogin", Match: "Address 10.0.0.0/8", Value: "yes", Want: "no or prohibit-password", Location: "sshd_config:8", Severity: notifier.SeverityHigh},
+			},
+		},
+		{
+			name: "match blocks end with the included file",
+			files: map[string]string{
+				"sshd_config":        "Include conf.d/*\nPermitRootLogin yes\n" + hardened,
+				"conf.d/deploy.conf": "Match User deploy\nLogLevel INFO\n",
+			},
+			want: []Finding{
+				{Check: "PermitRootLogin", Value: "yes", Want: "no or prohibit-password", Location: "sshd_config:2", Severity: notifier.SeverityHigh},
+				{Check: "LogLevel", Match: "User deploy", Value: "INFO", Want: "VERBOSE or higher", Location: "conf.d/deploy.conf:2", Severity: notifier.SeverityMedium},
+			},
+		},
+		{
+			name: "weak algorithms",
+			files: map[string]string{"sshd_config": hardened +
+				"KexAlgorithms +diffie-hellman-group1-sha1,curve25519-sha256\n" +
+				"Ciphers \"aes256-gcm@openssh.com,3des-cbc,AES128-CBC\"\n"},
+			want: []Finding{
+				{Check: "KexAlgorithms", Value: "diffie-hellman-group1-sha1", Want: "no weak algorithms", Location: "sshd_config:4", Severity: notifier.SeverityHigh},
+				{Check: "Ciphers", Value: "3des-cbc,aes128-cbc", Want: "no weak algorithms", Location: "sshd_config:5", Severity: notifier.SeverityHigh},
+			},
+		},
+		{
+			name:  "removing weak algorithms",
+			files: map[string]string{"sshd_config": hardened + "Ciphers -3des-cbc,aes128-cbc\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeConfig(t, dir, tt.files)
+			for i := range tt.want {
+				if tt.want[i].Location != "" {
+					tt.want[i].Location = filepath.Join(dir, tt.want[i].Location)
+				}
+			}
+			got, err := Audit(path)
+			if err != nil {
+				t.Fatalf("Audit() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Audit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudit_IncludeLoop(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), map[string]string{"sshd_config": "Include sshd_config\n"})
+	if _, err := Audit(path); err == nil {
+		t.Error("Audit() error = nil, want an error for an include loop")
+	}
+}
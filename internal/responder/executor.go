@@ -0,0 +1,176 @@
+package responder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const defaultCommandTimeout = 10 * time.Second
+
+// executor applies bans to the host firewall.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . executor
+type executor interface {
+	Ban(ip netip.Addr, ttl time.Duration) error
+	Unban(ip netip.Addr) error
+}
+
+// commandRunner runs a firewall command.
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . commandRunner
+type commandRunner interface {
+	Run(name string, args ...string) error
+}
+
+// ExecRunner runs commands on the host.
+type ExecRunner struct {
+	Timeout time.Duration
+}
+
+// Run runs name with args and returns its stderr with the error when it
+// exits with a non zero status.
+func (e ExecRunner) Run(name string, args ...string) error {
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// NewNftablesExecutor creates an executor adding banned IPv4 addresses to
+// set and IPv6 addresses to set6 of an existing nftables table. The sets
+// should be created with the timeout flag so the kernel expires bans even if
+// ssh-watcher is not running.
+func NewNftablesExecutor(runner commandRunner, family, table, set, set6 string) NftablesExecutor {
+	return NftablesExecutor{
+		Family: family,
+		Table:  table,
+		Set:    set,
+		Set6:   set6,
+		runner: runner,
+	}
+}
+
+type NftablesExecutor struct {
+	Family string
+	Table  string
+	Set    string
+	Set6   string
+	runner commandRunner
+}
+
+func (n NftablesExecutor) set(ip netip.Addr) string {
+	if ip.Is6() {
+		return n.Set6
+	}
+	return n.Set
+}
+
+func (n NftablesExecutor) Ban(ip netip.Addr, ttl time.Duration) error {
+	element := ip.String()
+	if ttl > 0 {
+		element += fmt.Sprintf(" timeout %ds", int(ttl.Round(time.Second).Seconds()))
+	}
+	return n.runner.Run("nft", "add", "element", n.Family, n.Table, n.set(ip), "{ "+element+" }")
+}
+
+// Unban deletes the element of ip. An element that no longer exists counts
+// as unbanned: the kernel removes elements added with a timeout once it
+// passes, usually just before the ban expires in ssh-watcher.
+func (n NftablesExecutor) Unban(ip netip.Addr) error {
+	err := n.runner.Run("nft", "delete", "element", n.Family, n.Table, n.set(ip), "{ "+ip.String()+" }")
+	if err != nil && strings.Contains(err.Error(), "No such file or directory") {
+		return nil
+	}
+	return err
+}
+
+// NewIpsetExecutor creates an executor adding banned IPv4 addresses to set
+// and IPv6 addresses to set6. The sets and the firewall rule dropping their
+// members must already exist.
+func NewIpsetExecutor(runner commandRunner, set, set6 string) IpsetExecutor {
+	return IpsetExecutor{
+		Set:    set,
+		Set6:   set6,
+		runner: runner,
+	}
+}
+
+type IpsetExecutor struct {
+	Set    string
+	Set6   string
+	runner commandRunner
+}
+
+func (i IpsetExecutor) set(ip netip.Addr) string {
+	if ip.Is6() {
+		return i.Set6
+	}
+	return i.Set
+}
+
+func (i IpsetExecutor) Ban(ip netip.Addr, _ time.Duration) error {
+	return i.runner.Run("ipset", "add", i.set(ip), ip.String(), "-exist")
+}
+
+func (i IpsetExecutor) Unban(ip netip.Addr) error {
+	return i.runner.Run("ipset", "del", i.set(ip), ip.String(), "-exist")
+}
+
+// NewIptablesExecutor creates an executor inserting a DROP rule per banned
+// address at the top of chain, using ip6tables for IPv6 addresses.
+func NewIptablesExecutor(runner commandRunner, chain string) IptablesExecutor {
+	return IptablesExecutor{
+		Chain:  chain,
+		runner: runner,
+	}
+}
+
+type IptablesExecutor struct {
+	Chain  string
+	runner commandRunner
+}
+
+func (i IptablesExecutor) command(ip netip.Addr) string {
+	if ip.Is6() {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+func (i IptablesExecutor) rule(ip netip.Addr) []string {
+	return []string{i.Chain, "-s", ip.String(), "-j", "DROP"}
+}
+
+// Ban inserts the rule unless it already exists so repeated bans do not
+// stack duplicate rules.
+func (i IptablesExecutor) Ban(ip netip.Addr, _ time.Duration) error {
+	if err := i.runner.Run(i.command(ip), append([]string{"-C"}, i.rule(ip)...)...); err == nil {
+		return nil
+	}
+	return i.runner.Run(i.command(ip), append([]string{"-I"}, i.rule(ip)...)...)
+}
+
+// Unban deletes the rule. A rule that no longer exists, e.g. because the
+// firewall was reloaded, counts as unbanned.
+func (i IptablesExecutor) Unban(ip netip.Addr) error {
+	err := i.runner.Run(i.command(ip), append([]string{"-D"}, i.rule(ip)...)...)
+	if err != nil && strings.Contains(err.Error(), "Bad rule (does a matching rule exist") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package responder
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/responder/responderfakes"
+)
+
+// commands returns the commands run by runner as name followed by args.
+func commands(runner *responderfakes.FakeCommandRunner) [][]string {
+	var cmds [][]string
+	for i := 0; i < runner.RunCallCount(); i++ {
+		name, args := runner.RunArgsForCall(i)
+		cmds = append(cmds, append([]string{name}, args...))
+	}
+	return cmds
+}
+
+func TestNftablesExecutor(t *testing.T) {
+	runner := &responderfakes.FakeCommandRunner{}
+	n := NewNftablesExecutor(runner, "inet", "filter", "banned", "banned6")
+
+	if err := n.Ban(netip.MustParseAddr("1.2.3.4"), time.Hour); err != nil {
+		t.Fatalf("Ban() error = %v", err)
+	}
+	if err := n.Unban(netip.MustParseAddr("2001:db8::1")); err != nil {
+		t.Fatalf("Unban() error = %v", err)
+	}
+
+	want := [][]string{
+		{"nft", "add", "element", "inet", "filter", "banned", "{ 1.2.3.4 timeout 3600s }"},
+		{"nft", "delete", "element", "inet", "filter", "banned6", "{ 2001:db8::1 }"},
+	}
+	if got := commands(runner); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestNftablesExecutor_UnbanExpiredElement(t *testing.T) {
+	runner := &responderfakes.FakeCommandRunner{}
+	n := NewNftablesExecutor(runner, "inet", "filter", "banned", "banned6")
+
+	runner.RunReturns(fmt.Errorf("nft delete element: exit status 1: Error: Could not process rule: No such file or directory"))
+	if err := n.Unban(netip.MustParseAddr("1.2.3.4")); err != nil {
+		t.Errorf("Unban() of an element the kernel expired error = %v, want nil", err)
+	}
+	runner.RunReturns(fmt.Errorf("nft delete element: exit status 1: Error: Could not process rule: Operation not permitted"))
+	if err := n.Unban(netip.MustParseAddr("1.2.3.4")); err == nil {
+		t.Error("Unban() error = nil, want other failures returned")
+	}
+}
+
+func TestIpsetExecutor(t *testing.T) {
+	runner := &responderfakes.FakeCommandRunner{}
+	i := NewIpsetExecutor(runner, "banned", "banned6")
+
+	if err := i.Ban(netip.MustParseAddr("2001:db8::1"), time.Hour); err != nil {
+		t.Fatalf("Ban() error = %v", err)
+	}
+	if err := i.Unban(netip.MustParseAddr("1.2.3.4")); err != nil {
+		t.Fatalf("Unban() error = %v", err)
+	}
+
+	want := [][]string{
+		{"ipset", "add", "banned6", "2001:db8::1", "-exist"},
+		{"ipset", "del", "banned", "1.2.3.4", "-exist"},
+	}
+	if got := commands(runner); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestIptablesExecutor_Ban(t *testing.T) {
+	tests := []struct {
+		name       string
+		ip         string
+		ruleExists bool
+		want       [][]string
+	}{
+		{
+			name: "inserts missing rule",
+			ip:   "1.2.3.4",
+			want: [][]string{
+				{"iptables", "-C", "INPUT", "-s", "1.2.3.4", "-j", "DROP"},
+				{"iptables", "-I", "INPUT", "-s", "1.2.3.4", "-j", "DROP"},
+			},
+		},
+		{
+			name:       "keeps existing rule",
+			ip:         "1.2.3.4",
+			ruleExists: true,
+			want: [][]string{
+				{"iptables", "-C", "INPUT", "-s", "1.2.3.4", "-j", "DROP"},
+			},
+		},
+		{
+			name: "ipv6 uses ip6tables",
+			ip:   "2001:db8::1",
+			want: [][]string{
+				{"ip6tables", "-C", "INPUT", "-s", "2001:db8::1", "-j", "DROP"},
+				{"ip6tables", "-I", "INPUT", "-s", "2001:db8::1", "-j", "DROP"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &responderfakes.FakeCommandRunner{}
+			if !tt.ruleExists {
+				runner.RunReturnsOnCall(0, fmt.Errorf("exit status 1"))
+			}
+			i := NewIptablesExecutor(runner, "INPUT")
+			if err := i.Ban(netip.MustParseAddr(tt.ip), time.Hour); err != nil {
+				t.Fatalf("Ban() error = %v", err)
+			}
+			if got := commands(runner); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIptablesExecutor_UnbanMissingRule(t *testing.T) {
+	runner := &responderfakes.FakeCommandRunner{}
+	i := NewIptablesExecutor(runner, "INPUT")
+
+	runner.RunReturns(fmt.Errorf("iptables -D INPUT -s 1.2.3.4 -j DROP: exit status 1: iptables: Bad rule (does a matching rule exist in that chain?)."))
+	if err := i.Unban(netip.MustParseAddr("1.2.3.4")); err != nil {
+		t.Errorf("Unban() of a rule that no longer exists error = %v, want nil", err)
+	}
+	runner.RunReturns(fmt.Errorf("iptables -D INPUT -s 1.2.3.4 -j DROP: exit status 4: iptables: Permission denied (you must be root)."))
+	if err := i.Unban(netip.MustParseAddr("1.2.3.4")); err == nil {
+		t.Error("Unban() error = nil, want other failures returned")
+	}
+}
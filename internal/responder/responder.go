@@ -0,0 +1,301 @@
+// Package responder blocks the source of attacks at the host firewall.
+package responder
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// notifierClient is an interface for sending notifications
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . notifierClient
+type notifierClient interface {
+	Notify(LogLine notifier.LogLine) error
+}
+
+// banStore persists active bans across restarts.
+type banStore interface {
+	Load() (map[string]Ban, error)
+	Save(bans map[string]Ban) error
+}
+
+// Settings configures the responder.
+type Settings struct {
+	// HostMachine is reported as the host of ban and unban events.
+	HostMachine string
+	// BanDuration is how long an IP address stays banned.
+	BanDuration time.Duration
+	// Triggers are the event types whose source IP address is banned.
+	Triggers []notifier.EventType
+	// TriggerRules are the access list rules and rules whose matches are
+	// banned.
+	TriggerRules []string
+	// BanDenylisted bans the source of events matching a deny rule of the
+	// access list or listed in a threat feed.
+	BanDenylisted bool
+	// Allowlist holds the ranges that are never banned.
+	Allowlist []netip.Prefix
+	// UnbanOnShutdown lifts all bans when the responder is closed. Otherwise
+	// bans are kept and lifted on schedule after the next start.
+	UnbanOnShutdown bool
+}
+
+// New creates a responder and re-applies the bans persisted in store that
+// have not expired yet. Expired bans are lifted.
+func New(executor executor, store banStore, client notifierClient, settings Settings, log zerolog.Logger) (*Responder, error) {
+	r := &Responder{
+		executor: executor,
+		store:    store,
+		notifier: client,
+		settings: settings,
+		triggers: map[notifier.EventType]bool{},
+		rules:    map[string]bool{},
+		banning:  map[string]bool{},
+		now:      time.Now,
+		log:      log,
+	}
+	for _, eventType := range settings.Triggers {
+		r.triggers[eventType] = true
+	}
+	for _, name := range settings.TriggerRules {
+		r.rules[name] = true
+	}
+	if err := r.restore(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Responder is an app stage banning the source IP address of trigger events
+// through an executor and lifting the bans once they expire. Ban and unban
+// actions are reported through the notifier.
+type Responder struct {
+	executor executor
+	store    banStore
+	notifier notifierClient
+	settings Settings
+	triggers map[notifier.EventType]bool
+	rules    map[string]bool
+	bans     map[string]Ban
+	// banning holds the IP addresses whose ban is being applied, so they are
+	// not banned twice while r.mu is released around the executor.
+	banning map[string]bool
+	now     func() time.Time
+	log     zerolog.Logger
+	mu      sync.Mutex
+}
+
+func (r *Responder) restore() error {
+	bans, err := r.store.Load()
+	if err != nil {
+		return err
+	}
+
+	now := r.now()
+	r.bans = map[string]Ban{}
+	for ip, ban := range bans {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			r.log.Warn().Err(err).Msg("dropping persisted ban with invalid IP address")
+			continue
+		}
+		if !now.Before(ban.ExpiresAt) {
+			if err := r.executor.Unban(addr); err != nil {
+				r.log.Debug().Err(err).Str("ip", ip).Msg("failed lifting ban that expired while stopped")
+			}
+			continue
+		}
+		if err := r.executor.Ban(addr, ban.ExpiresAt.Sub(now)); err != nil {
+			return fmt.Errorf("failed restoring ban of %s: %w", ip, err)
+		}
+		r.bans[ip] = ban
+	}
+	return r.store.Save(r.bans)
+}
+
+// Process implements the app stage interface. Log lines are always passed on
+// unchanged; the source IP address of a trigger event, an event matching a
+// trigger rule or an event a rule asks to respond to is banned unless it is
+// allowlisted or already banned.
+func (r *Responder) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.IpAddress == "" {
+		return []notifier.LogLine{logLine}
+	}
+	if reason, ok := r.trigger(logLine); ok {
+		r.ban(logLine, reason)
+	}
+	return []notifier.LogLine{logLine}
+}
+
+// trigger reports whether the log line triggers a ban and why.
+func (r *Responder) trigger(logLine notifier.LogLine) (string, bool) {
+	if r.triggers[logLine.EventType] {
+		return string(logLine.EventType), true
+	}
+	if r.settings.BanDenylisted {
+		if logLine.Denylisted {
+			return fmt.Sprintf("%s matched a deny rule", logLine.EventType), true
+		}
+		if len(logLine.ThreatFeeds) > 0 {
+			return fmt.Sprintf("%s from a source listed in %s", logLine.EventType, strings.Join(logLine.ThreatFeeds, ", ")), true
+		}
+	}
+	for _, name := range logLine.MatchedRules {
+		if r.rules[name] {
+			return fmt.Sprintf("%s matched rule %s", logLine.EventType, name), true
+		}
+	}
+	if logLine.Respond {
+		return fmt.Sprintf("%s matched rules %s", logLine.EventType, strings.Join(logLine.MatchedRules, ", ")), true
+	}
+	return "", false
+}
+
+func (r *Responder) ban(logLine notifier.LogLine, reason string) {
+	addr, err := netip.ParseAddr(logLine.IpAddress)
+	if err != nil {
+		r.log.Warn().Err(err).Msg("not banning invalid IP address")
+		return
+	}
+	addr = addr.Unmap()
+	ip := addr.String()
+	if cidr.Contains(r.settings.Allowlist, ip) {
+		r.log.Info().Msg(fmt.Sprintf("not banning allowlisted IP address %s", ip))
+		return
+	}
+
+	r.mu.Lock()
+	now := r.now()
+	if ban, ok := r.bans[ip]; (ok && now.Before(ban.ExpiresAt)) || r.banning[ip] {
+		r.mu.Unlock()
+		return
+	}
+	r.banning[ip] = true
+	r.mu.Unlock()
+
+	// The firewall command may be slow, so other events are processed and
+	// expired bans lifted while it runs.
+	err = r.executor.Ban(addr, r.settings.BanDuration)
+
+	r.mu.Lock()
+	delete(r.banning, ip)
+	if err != nil {
+		r.mu.Unlock()
+		r.log.Error().Err(err).Str("ip", ip).Msg("failed banning IP address")
+		return
+	}
+	ban := Ban{
+		BannedAt:  now,
+		ExpiresAt: now.Add(r.settings.BanDuration),
+		Reason:    reason,
+	}
+	r.bans[ip] = ban
+	r.save()
+	r.mu.Unlock()
+
+	r.report(notifier.IpBanned, ip, fmt.Sprintf("%s, banned until %s", ban.Reason, ban.ExpiresAt.Format(time.RFC3339)))
+}
+
+// UnbanExpired lifts the bans that expired. Bans that fail to be lifted are
+// kept and retried on the next call.
+func (r *Responder) UnbanExpired() {
+	r.mu.Lock()
+	now := r.now()
+	var expired []string
+	for ip, ban := range r.bans {
+		if !now.Before(ban.ExpiresAt) {
+			expired = append(expired, ip)
+		}
+	}
+	unbanned := r.unban(expired)
+	r.mu.Unlock()
+
+	for _, ip := range unbanned {
+		r.report(notifier.IpUnbanned, ip, "ban expired")
+	}
+}
+
+// Run lifts expired bans every interval until ctx is done.
+func (r *Responder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.UnbanExpired()
+		}
+	}
+}
+
+// Close lifts all bans when UnbanOnShutdown is set.
+func (r *Responder) Close() error {
+	if !r.settings.UnbanOnShutdown {
+		return nil
+	}
+
+	r.mu.Lock()
+	ips := make([]string, 0, len(r.bans))
+	for ip := range r.bans {
+		ips = append(ips, ip)
+	}
+	unbanned := r.unban(ips)
+	remaining := len(r.bans)
+	r.mu.Unlock()
+
+	for _, ip := range unbanned {
+		r.report(notifier.IpUnbanned, ip, "ssh-watcher shutting down")
+	}
+	if remaining > 0 {
+		return fmt.Errorf("failed lifting %d bans on shutdown", remaining)
+	}
+	return nil
+}
+
+// unban lifts the bans of ips and returns the ones lifted. r.mu must be held.
+func (r *Responder) unban(ips []string) []string {
+	var unbanned []string
+	for _, ip := range ips {
+		if err := r.executor.Unban(netip.MustParseAddr(ip)); err != nil {
+			r.log.Error().Err(err).Str("ip", ip).Msg("failed lifting ban")
+			continue
+		}
+		delete(r.bans, ip)
+		unbanned = append(unbanned, ip)
+	}
+	if len(unbanned) > 0 {
+		r.save()
+	}
+	return unbanned
+}
+
+// save persists the bans. r.mu must be held. A failure is logged rather than
+// returned since the firewall already reflects the change.
+func (r *Responder) save() {
+	if err := r.store.Save(r.bans); err != nil {
+		r.log.Error().Err(err).Msg("failed persisting bans")
+	}
+}
+
+func (r *Responder) report(eventType notifier.EventType, ip, reason string) {
+	logLine := notifier.LogLine{
+		IpAddress:   ip,
+		LoginTime:   r.now().Format(time.RFC3339),
+		EventType:   eventType,
+		HostMachine: r.settings.HostMachine,
+		Severity:    notifier.DefaultSeverity(eventType),
+		Reason:      reason,
+	}
+	if err := r.notifier.Notify(logLine); err != nil {
+		r.log.Error().Err(err).Msg(fmt.Sprintf("failed reporting %s", eventType))
+	}
+}
@@ -0,0 +1,335 @@
+package responder
+
+import (
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/responder/responderfakes"
+	"github.com/rs/zerolog"
+)
+
+var start = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func bruteForce(ip string) notifier.LogLine {
+	return notifier.LogLine{
+		IpAddress: ip,
+		EventType: notifier.BruteForceDetected,
+		Severity:  notifier.SeverityHigh,
+	}
+}
+
+func newTestResponder(t *testing.T, store FileBanStore, executor *responderfakes.FakeExecutor, client *responderfakes.FakeNotifierClient, settings Settings) *Responder {
+	t.Helper()
+	r, err := New(executor, store, client, settings, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	r.now = func() time.Time { return start }
+	return r
+}
+
+// reported returns the event types and IPs reported through client.
+func reported(client *responderfakes.FakeNotifierClient) []string {
+	var events []string
+	for i := 0; i < client.NotifyCallCount(); i++ {
+		logLine := client.NotifyArgsForCall(i)
+		events = append(events, fmt.Sprintf("%s %s", logLine.EventType, logLine.IpAddress))
+	}
+	return events
+}
+
+func TestResponder_Process(t *testing.T) {
+	tests := []struct {
+		name          string
+		logLines      []notifier.LogLine
+		banDenylisted bool
+		banErr        error
+		wantBans      []string
+		wantReports   []string
+	}{
+		{
+			name:        "bans trigger event source",
+			logLines:    []notifier.LogLine{bruteForce("1.2.3.4")},
+			wantBans:    []string{"1.2.3.4"},
+			wantReports: []string{"IP banned 1.2.3.4"},
+		},
+		{
+			name:        "does not ban twice",
+			logLines:    []notifier.LogLine{bruteForce("1.2.3.4"), bruteForce("1.2.3.4")},
+			wantBans:    []string{"1.2.3.4"},
+			wantReports: []string{"IP banned 1.2.3.4"},
+		},
+		{
+			name:        "bans trigger rule match",
+			logLines:    []notifier.LogLine{{IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttempt, MatchedRules: []string{"office", "hostile"}}},
+			wantBans:    []string{"1.2.3.4"},
+			wantReports: []string{"IP banned 1.2.3.4"},
+		},
+		{
+			name:        "bans when a rule asks to respond",
+			logLines:    []notifier.LogLine{{IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttempt, MatchedRules: []string{"tor"}, Respond: true}},
+			wantBans:    []string{"1.2.3.4"},
+			wantReports: []string{"IP banned 1.2.3.4"},
+		},
+		{
+			name:          "bans denylisted source",
+			logLines:      []notifier.LogLine{{IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttempt, Denylisted: true}},
+			banDenylisted: true,
+			wantBans:      []string{"1.2.3.4"},
+			wantReports:   []string{"IP banned 1.2.3.4"},
+		},
+		{
+			name:          "bans source listed in a threat feed",
+			logLines:      []notifier.LogLine{{IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttempt, ThreatFeeds: []string{"firehol"}}},
+			banDenylisted: true,
+			wantBans:      []string{"1.2.3.4"},
+			wantReports:   []string{"IP banned 1.2.3.4"},
+		},
+		{
+			name:     "ignores denylisted source unless enabled",
+			logLines: []notifier.LogLine{{IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttempt, Denylisted: true, ThreatFeeds: []string{"firehol"}}},
+		},
+		{
+			name:     "never bans allowlisted ranges",
+			logLines: []notifier.LogLine{bruteForce("10.1.2.3")},
+		},
+		{
+			name:     "ignores other event types",
+			logLines: []notifier.LogLine{{IpAddress: "1.2.3.4", EventType: notifier.FailedLoginAttempt}},
+		},
+		{
+			name:     "ignores detections without a single source",
+			logLines: []notifier.LogLine{{Username: "root", IpAddresses: []string{"1.2.3.4"}, EventType: notifier.BruteForceDetected}},
+		},
+		{
+			name:     "failed ban is not recorded",
+			logLines: []notifier.LogLine{bruteForce("1.2.3.4")},
+			banErr:   fmt.Errorf("nft: no such set"),
+			wantBans: []string{"1.2.3.4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileBanStore(filepath.Join(t.TempDir(), "bans.json"))
+			executor := &responderfakes.FakeExecutor{}
+			executor.BanReturns(tt.banErr)
+			client := &responderfakes.FakeNotifierClient{}
+			r := newTestResponder(t, store, executor, client, Settings{
+				BanDuration:   time.Hour,
+				Triggers:      []notifier.EventType{notifier.BruteForceDetected},
+				TriggerRules:  []string{"hostile"},
+				BanDenylisted: tt.banDenylisted,
+				Allowlist:     []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			})
+
+			for _, logLine := range tt.logLines {
+				if got := r.Process(logLine); !reflect.DeepEqual(got, []notifier.LogLine{logLine}) {
+					t.Errorf("Process() = %v, want log line passed through", got)
+				}
+			}
+
+			var bans []string
+			for i := 0; i < executor.BanCallCount(); i++ {
+				ip, ttl := executor.BanArgsForCall(i)
+				if ttl != time.Hour {
+					t.Errorf("Ban() ttl = %v, want 1h", ttl)
+				}
+				bans = append(bans, ip.String())
+			}
+			if !reflect.DeepEqual(bans, tt.wantBans) {
+				t.Errorf("bans = %v, want %v", bans, tt.wantBans)
+			}
+			if got := reported(client); !reflect.DeepEqual(got, tt.wantReports) {
+				t.Errorf("reported = %v, want %v", got, tt.wantReports)
+			}
+
+			persisted, err := store.Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			wantPersisted := len(tt.wantReports)
+			if len(persisted) != wantPersisted {
+				t.Errorf("persisted bans = %v, want %d", persisted, wantPersisted)
+			}
+		})
+	}
+}
+
+func TestResponder_UnbanExpired(t *testing.T) {
+	store := NewFileBanStore(filepath.Join(t.TempDir(), "bans.json"))
+	executor := &responderfakes.FakeExecutor{}
+	client := &responderfakes.FakeNotifierClient{}
+	r := newTestResponder(t, store, executor, client, Settings{
+		BanDuration: time.Hour,
+		Triggers:    []notifier.EventType{notifier.BruteForceDetected},
+	})
+
+	r.Process(bruteForce("1.2.3.4"))
+	r.now = func() time.Time { return start.Add(30 * time.Minute) }
+	r.Process(bruteForce("5.6.7.8"))
+
+	r.now = func() time.Time { return start.Add(time.Hour) }
+	r.UnbanExpired()
+
+	if executor.UnbanCallCount() != 1 || executor.UnbanArgsForCall(0).String() != "1.2.3.4" {
+		t.Fatalf("Unban() calls = %d, want only 1.2.3.4", executor.UnbanCallCount())
+	}
+	want := []string{"IP banned 1.2.3.4", "IP banned 5.6.7.8", "IP unbanned 1.2.3.4"}
+	if got := reported(client); !reflect.DeepEqual(got, want) {
+		t.Errorf("reported = %v, want %v", got, want)
+	}
+	persisted, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := persisted["5.6.7.8"]; !ok || len(persisted) != 1 {
+		t.Errorf("persisted bans = %v, want only 5.6.7.8", persisted)
+	}
+
+	// The source can be banned again once its ban expired.
+	r.Process(bruteForce("1.2.3.4"))
+	if executor.BanCallCount() != 3 {
+		t.Errorf("Ban() calls = %d, want 3", executor.BanCallCount())
+	}
+}
+
+func TestResponder_UnbanExpiredRetriesFailures(t *testing.T) {
+	store := NewFileBanStore(filepath.Join(t.TempDir(), "bans.json"))
+	executor := &responderfakes.FakeExecutor{}
+	executor.UnbanReturnsOnCall(0, fmt.Errorf("nft: busy"))
+	client := &responderfakes.FakeNotifierClient{}
+	r := newTestResponder(t, store, executor, client, Settings{
+		BanDuration: time.Hour,
+		Triggers:    []notifier.EventType{notifier.BruteForceDetected},
+	})
+
+	r.Process(bruteForce("1.2.3.4"))
+	r.now = func() time.Time { return start.Add(2 * time.Hour) }
+	r.UnbanExpired()
+	r.UnbanExpired()
+
+	if executor.UnbanCallCount() != 2 {
+		t.Errorf("Unban() calls = %d, want 2", executor.UnbanCallCount())
+	}
+	if client.NotifyCallCount() != 2 {
+		t.Errorf("reported = %v, want one ban and one unban", reported(client))
+	}
+}
+
+func TestResponder_ProcessDuringBan(t *testing.T) {
+	store := NewFileBanStore(filepath.Join(t.TempDir(), "bans.json"))
+	executor := &responderfakes.FakeExecutor{}
+	client := &responderfakes.FakeNotifierClient{}
+	r := newTestResponder(t, store, executor, client, Settings{
+		BanDuration: time.Hour,
+		Triggers:    []notifier.EventType{notifier.BruteForceDetected},
+	})
+
+	// While the first ban is applied the lock is free: another event of the
+	// same source does not ban it again and other sources are banned.
+	executor.BanStub = func(ip netip.Addr, _ time.Duration) error {
+		if ip.String() == "1.2.3.4" {
+			r.Process(bruteForce("1.2.3.4"))
+			r.Process(bruteForce("5.6.7.8"))
+		}
+		return nil
+	}
+	r.Process(bruteForce("1.2.3.4"))
+
+	if executor.BanCallCount() != 2 {
+		t.Errorf("Ban() calls = %d, want 2", executor.BanCallCount())
+	}
+	want := []string{"IP banned 5.6.7.8", "IP banned 1.2.3.4"}
+	if got := reported(client); !reflect.DeepEqual(got, want) {
+		t.Errorf("reported = %v, want %v", got, want)
+	}
+}
+
+func TestResponder_Close(t *testing.T) {
+	tests := []struct {
+		name            string
+		unbanOnShutdown bool
+		wantUnbans      int
+		wantPersisted   int
+	}{
+		{
+			name:            "unbans on shutdown",
+			unbanOnShutdown: true,
+			wantUnbans:      2,
+			wantPersisted:   0,
+		},
+		{
+			name:          "keeps bans on shutdown",
+			wantUnbans:    0,
+			wantPersisted: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileBanStore(filepath.Join(t.TempDir(), "bans.json"))
+			executor := &responderfakes.FakeExecutor{}
+			client := &responderfakes.FakeNotifierClient{}
+			r := newTestResponder(t, store, executor, client, Settings{
+				BanDuration:     time.Hour,
+				Triggers:        []notifier.EventType{notifier.BruteForceDetected},
+				UnbanOnShutdown: tt.unbanOnShutdown,
+			})
+			r.Process(bruteForce("1.2.3.4"))
+			r.Process(bruteForce("2001:db8::1"))
+
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if executor.UnbanCallCount() != tt.wantUnbans {
+				t.Errorf("Unban() calls = %d, want %d", executor.UnbanCallCount(), tt.wantUnbans)
+			}
+			persisted, err := store.Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(persisted) != tt.wantPersisted {
+				t.Errorf("persisted bans = %v, want %d", persisted, tt.wantPersisted)
+			}
+		})
+	}
+}
+
+func TestNew_RestoresBans(t *testing.T) {
+	store := NewFileBanStore(filepath.Join(t.TempDir(), "bans.json"))
+	now := time.Now()
+	err := store.Save(map[string]Ban{
+		"1.2.3.4": {BannedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), Reason: "brute force detected"},
+		"5.6.7.8": {BannedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour), Reason: "brute force detected"},
+	})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	executor := &responderfakes.FakeExecutor{}
+	if _, err := New(executor, store, &responderfakes.FakeNotifierClient{}, Settings{}, zerolog.Nop()); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if executor.BanCallCount() != 1 {
+		t.Fatalf("Ban() calls = %d, want 1", executor.BanCallCount())
+	}
+	ip, ttl := executor.BanArgsForCall(0)
+	if ip.String() != "1.2.3.4" || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Ban() = %s %v, want 1.2.3.4 for the remaining ban duration", ip, ttl)
+	}
+	if executor.UnbanCallCount() != 1 || executor.UnbanArgsForCall(0).String() != "5.6.7.8" {
+		t.Errorf("Unban() calls = %d, want only expired 5.6.7.8", executor.UnbanCallCount())
+	}
+	persisted, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := persisted["1.2.3.4"]; !ok || len(persisted) != 1 {
+		t.Errorf("persisted bans = %v, want only 1.2.3.4", persisted)
+	}
+}
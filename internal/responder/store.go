@@ -0,0 +1,44 @@
+package responder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+)
+
+// Ban is an active ban of an IP address.
+type Ban struct {
+	BannedAt  time.Time `json:"banned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Reason    string    `json:"reason"`
+}
+
+// FileBanStore persists active bans as JSON so they can be lifted on
+// schedule after a restart.
+type FileBanStore struct {
+	StateFilePath string
+}
+
+func NewFileBanStore(stateFilePath string) FileBanStore {
+	return FileBanStore{
+		StateFilePath: stateFilePath,
+	}
+}
+
+// Load returns the persisted bans keyed by IP address.
+func (f FileBanStore) Load() (map[string]Ban, error) {
+	bans := map[string]Ban{}
+	if err := statefile.Load(f.StateFilePath, &bans); err != nil {
+		return nil, fmt.Errorf("failed loading bans: %w", err)
+	}
+	return bans, nil
+}
+
+// Save replaces the persisted bans.
+func (f FileBanStore) Save(bans map[string]Ban) error {
+	if err := statefile.Save(f.StateFilePath, bans); err != nil {
+		return fmt.Errorf("failed saving bans: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package router
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// notifierClient is an interface for sending notifications
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . notifierClient
+type notifierClient interface {
+	Notify(LogLine notifier.LogLine) error
+}
+
+// Sinks maps sink names to the notifier delivering to that sink.
+type Sinks map[string]notifierClient
+
+const (
+	// deliveryAttempts is how often delivery to a failing sink is tried.
+	deliveryAttempts = 3
+	// defaultRetryDelay is the delay before the first retry of failed sinks,
+	// doubled for every further retry.
+	defaultRetryDelay = time.Second
+)
+
+// DeliveryError is returned when no routed sink delivered an event.
+type DeliveryError struct {
+	// Failures maps sink names to the error returned by that sink.
+	Failures map[string]error
+}
+
+func (e *DeliveryError) Error() string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("%s: %v", name, e.Failures[name]))
+	}
+	return "failed delivering to sinks: " + strings.Join(messages, "; ")
+}
+
+func (e *DeliveryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, err := range e.Failures {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+type route struct {
+	name    string
+	matcher matcher
+	sinks   []string
+	cont    bool
+}
+
+// New creates a router delivering events to the named sinks according to the
+// ordered routes. Events matching no route are delivered to defaultSinks.
+// Sinks listed in minSeverities only receive events of at least that severity.
+func New(sinks Sinks, minSeverities map[string]notifier.Severity, routes []config.Route, defaultSinks []string, log zerolog.Logger) (Router, error) {
+	r := Router{
+		sinks:         sinks,
+		minSeverities: minSeverities,
+		defaultSinks:  defaultSinks,
+		retryDelay:    defaultRetryDelay,
+		log:           log,
+	}
+
+	if err := r.checkSinks(defaultSinks); err != nil {
+		return Router{}, fmt.Errorf("invalid default sinks: %w", err)
+	}
+	for name := range minSeverities {
+		if err := r.checkSinks([]string{name}); err != nil {
+			return Router{}, fmt.Errorf("invalid minimum severity: %w", err)
+		}
+	}
+	for i, cfgRoute := range routes {
+		name := cfgRoute.Name
+		if name == "" {
+			name = fmt.Sprintf("route %d", i+1)
+		}
+		m, err := newMatcher(cfgRoute.Match)
+		if err != nil {
+			return Router{}, fmt.Errorf("invalid match in %s: %w", name, err)
+		}
+		if err := r.checkSinks(cfgRoute.Sinks); err != nil {
+			return Router{}, fmt.Errorf("invalid sinks in %s: %w", name, err)
+		}
+		r.routes = append(r.routes, route{
+			name:    name,
+			matcher: m,
+			sinks:   cfgRoute.Sinks,
+			cont:    cfgRoute.Continue,
+		})
+	}
+	return r, nil
+}
+
+// Router is a notifierClient that fans events out to multiple named sinks.
+type Router struct {
+	sinks         Sinks
+	minSeverities map[string]notifier.Severity
+	routes        []route
+	defaultSinks  []string
+	retryDelay    time.Duration
+	log           zerolog.Logger
+}
+
+// SinkNames returns the names of all sinks.
+func (r Router) SinkNames() []string {
+	names := make([]string, 0, len(r.sinks))
+	for name := range r.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r Router) checkSinks(names []string) error {
+	for _, name := range names {
+		if _, ok := r.sinks[name]; !ok {
+			return fmt.Errorf("unknown sink %q", name)
+		}
+	}
+	return nil
+}
+
+// Route returns the names of the sinks the log line is delivered to. Sinks
+// set on the log line by rules replace the routed ones. Canary events and
+// notifications dropped events ignore the minimum severities so sinks always
+// learn that they missed events.
+func (r Router) Route(logLine notifier.LogLine) []string {
+	routed := logLine.Sinks
+	if len(routed) == 0 {
+		routed = r.route(logLine)
+	}
+	var names []string
+	for _, name := range routed {
+		if _, ok := r.sinks[name]; !ok {
+			r.log.Error().Msg(fmt.Sprintf("%s sent to unknown sink %s", logLine.EventType, name))
+			continue
+		}
+		if minSeverity, ok := r.minSeverities[name]; ok && !ignoresMinSeverity(logLine) && logLine.Severity.Rank() < minSeverity.Rank() {
+			r.log.Debug().Msg(fmt.Sprintf("%s below minimum severity %s of %s", logLine.EventType, minSeverity, name))
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// ignoresMinSeverity reports whether the log line is sent regardless of the
+// minimum severities of sinks.
+func ignoresMinSeverity(logLine notifier.LogLine) bool {
+	return logLine.Canary || logLine.EventType == notifier.NotificationsDropped
+}
+
+// route returns the names of the sinks of the routes matching the log line.
+func (r Router) route(logLine notifier.LogLine) []string {
+	var names []string
+	seen := map[string]bool{}
+	matched := false
+	for _, route := range r.routes {
+		if !route.matcher.matches(logLine) {
+			continue
+		}
+		matched = true
+		r.log.Debug().Msg(fmt.Sprintf("%s matched %s", logLine.EventType, route.name))
+		for _, name := range route.sinks {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		if !route.cont {
+			break
+		}
+	}
+	if !matched {
+		return r.defaultSinks
+	}
+	return names
+}
+
+// Notify delivers the log line to every routed sink concurrently. A failing
+// or slow sink does not prevent delivery to the others and only the failed
+// sinks are retried, so no sink receives the event twice. Sinks still failing
+// after deliveryAttempts are logged; an error is only returned, as a
+// *DeliveryError, when no sink delivered the event.
+func (r Router) Notify(logLine notifier.LogLine) error {
+	names := r.Route(logLine)
+	if len(names) == 0 {
+		r.log.Info().Msg(fmt.Sprintf("no sink routed for %s", logLine.EventType))
+		return nil
+	}
+
+	pending := names
+	delay := r.retryDelay
+	var failures map[string]error
+	for attempt := 1; ; attempt++ {
+		failures = r.deliver(logLine, pending)
+		if len(failures) == 0 || attempt == deliveryAttempts {
+			break
+		}
+		pending = make([]string, 0, len(failures))
+		for name := range failures {
+			pending = append(pending, name)
+		}
+		sort.Strings(pending)
+		time.Sleep(delay)
+		delay *= 2
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	for name, err := range failures {
+		r.log.Error().Err(err).Str("sink", name).Msg(fmt.Sprintf("giving up delivering %s after %d attempts", logLine.EventType, deliveryAttempts))
+	}
+	if len(failures) < len(names) {
+		return nil
+	}
+	return &DeliveryError{Failures: failures}
+}
+
+// deliver sends the log line to the named sinks concurrently and returns the
+// errors of the sinks that failed.
+func (r Router) deliver(logLine notifier.LogLine, names []string) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failures := map[string]error{}
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := r.sinks[name].Notify(logLine); err != nil {
+				r.log.Error().Err(err).Str("sink", name).Msg("failed delivering notification")
+				mu.Lock()
+				failures[name] = err
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+	return failures
+}
+
+// matcher is the compiled form of a config.Match.
+type matcher struct {
+	eventTypes map[notifier.EventType]bool
+	users      map[string]bool
+	hosts      map[string]bool
+	severities map[notifier.Severity]bool
+	prefixes   []netip.Prefix
+}
+
+func newMatcher(match config.Match) (matcher, error) {
+	m := matcher{
+		eventTypes: map[notifier.EventType]bool{},
+		users:      toSet(match.Users),
+		hosts:      toSet(match.Hosts),
+		severities: map[notifier.Severity]bool{},
+	}
+	for _, eventType := range match.EventTypes {
+		m.eventTypes[notifier.EventType(eventType)] = true
+	}
+	for _, s := range match.Severities {
+		severity, err := notifier.ParseSeverity(s)
+		if err != nil {
+			return matcher{}, fmt.Errorf("invalid severity: %w", err)
+		}
+		m.severities[severity] = true
+	}
+	for _, ip := range match.IPs {
+		prefix, err := cidr.Parse(ip)
+		if err != nil {
+			return matcher{}, err
+		}
+		m.prefixes = append(m.prefixes, prefix)
+	}
+	return m, nil
+}
+
+func (m matcher) matches(logLine notifier.LogLine) bool {
+	if len(m.eventTypes) > 0 && !m.eventTypes[logLine.EventType] {
+		return false
+	}
+	if len(m.users) > 0 && !m.users[logLine.Username] {
+		return false
+	}
+	if len(m.hosts) > 0 && !m.hosts[logLine.HostMachine] {
+		return false
+	}
+	if len(m.severities) > 0 && !m.severities[logLine.Severity] {
+		return false
+	}
+	if len(m.prefixes) > 0 && !cidr.Contains(m.prefixes, logLine.IpAddress) {
+		return false
+	}
+	return true
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
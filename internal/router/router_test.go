@@ -0,0 +1,268 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/router/routerfakes"
+	"github.com/rs/zerolog"
+)
+
+func testSinks() Sinks {
+	return Sinks{
+		"slack":     &routerfakes.FakeNotifierClient{},
+		"noise":     &routerfakes.FakeNotifierClient{},
+		"pagerduty": &routerfakes.FakeNotifierClient{},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		routes       []config.Route
+		defaultSinks []string
+		wantErr      bool
+	}{
+		{
+			name: "valid routes",
+			routes: []config.Route{
+				{Match: config.Match{IPs: []string{"10.0.0.0/8", "2001:db8::1"}}, Sinks: []string{"noise"}},
+			},
+			defaultSinks: []string{"slack"},
+			wantErr:      false,
+		},
+		{
+			name: "unknown route sink",
+			routes: []config.Route{
+				{Sinks: []string{"email"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:         "unknown default sink",
+			defaultSinks: []string{"email"},
+			wantErr:      true,
+		},
+		{
+			name: "invalid cidr",
+			routes: []config.Route{
+				{Match: config.Match{IPs: []string{"10.0.0.0/33"}}, Sinks: []string{"noise"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid severity",
+			routes: []config.Route{
+				{Match: config.Match{Severities: []string{"urgent"}}, Sinks: []string{"noise"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(testSinks(), nil, tt.routes, tt.defaultSinks, zerolog.Nop()); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_minSeverities(t *testing.T) {
+	if _, err := New(testSinks(), map[string]notifier.Severity{"email": notifier.SeverityHigh}, nil, []string{"slack"}, zerolog.Nop()); err == nil {
+		t.Error("New() error = nil, want error for unknown sink")
+	}
+}
+
+func TestRouter_Route_minSeverities(t *testing.T) {
+	routes := []config.Route{
+		{Sinks: []string{"slack", "pagerduty"}},
+	}
+	minSeverities := map[string]notifier.Severity{"pagerduty": notifier.SeverityHigh}
+	r, err := New(testSinks(), minSeverities, routes, []string{"slack"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		eventType notifier.EventType
+		severity  notifier.Severity
+		canary    bool
+		want      []string
+	}{
+		{name: "below minimum", severity: notifier.SeverityMedium, want: []string{"slack"}},
+		{name: "at minimum", severity: notifier.SeverityHigh, want: []string{"slack", "pagerduty"}},
+		{name: "above minimum", severity: notifier.SeverityCritical, want: []string{"slack", "pagerduty"}},
+		{name: "canary below minimum", severity: notifier.SeverityLow, canary: true, want: []string{"slack", "pagerduty"}},
+		{name: "notifications dropped below minimum", eventType: notifier.NotificationsDropped, severity: notifier.SeverityLow, want: []string{"slack", "pagerduty"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventType := tt.eventType
+			if eventType == "" {
+				eventType = notifier.LoggedIn
+			}
+			logLine := notifier.LogLine{EventType: eventType, Severity: tt.severity, Canary: tt.canary}
+			if got := r.Route(logLine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Router.Route() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_Route(t *testing.T) {
+	routes := []config.Route{
+		{
+			Name:  "invalid users are noise",
+			Match: config.Match{EventTypes: []string{string(notifier.FailedLoginAttemptInvalidUsername)}},
+			Sinks: []string{"noise"},
+		},
+		{
+			Name:     "root logins page",
+			Match:    config.Match{EventTypes: []string{string(notifier.LoggedIn)}, Users: []string{"root"}},
+			Sinks:    []string{"pagerduty"},
+			Continue: true,
+		},
+		{
+			Name:  "office network is noise",
+			Match: config.Match{IPs: []string{"192.168.0.0/16"}},
+			Sinks: []string{"noise"},
+		},
+		{
+			Name:  "critical to slack",
+			Match: config.Match{Severities: []string{"critical"}, Hosts: []string{"bastion"}},
+			Sinks: []string{"slack", "pagerduty"},
+		},
+	}
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    []string
+	}{
+		{
+			name:    "first match stops evaluation",
+			logLine: notifier.LogLine{EventType: notifier.FailedLoginAttemptInvalidUsername, IpAddress: "192.168.1.1"},
+			want:    []string{"noise"},
+		},
+		{
+			name:    "continue evaluates later routes",
+			logLine: notifier.LogLine{EventType: notifier.LoggedIn, Username: "root", IpAddress: "192.168.1.1"},
+			want:    []string{"pagerduty", "noise"},
+		},
+		{
+			name:    "sinks are deduplicated",
+			logLine: notifier.LogLine{EventType: notifier.LoggedIn, Username: "root", Severity: notifier.SeverityCritical, HostMachine: "bastion"},
+			want:    []string{"pagerduty", "slack"},
+		},
+		{
+			name:    "cidr match",
+			logLine: notifier.LogLine{EventType: notifier.FailedLoginAttempt, IpAddress: "192.168.4.20"},
+			want:    []string{"noise"},
+		},
+		{
+			name:    "sinks set by rules replace routes",
+			logLine: notifier.LogLine{EventType: notifier.FailedLoginAttemptInvalidUsername, Sinks: []string{"pagerduty", "unknown"}},
+			want:    []string{"pagerduty"},
+		},
+		{
+			name:    "no match uses default sinks",
+			logLine: notifier.LogLine{EventType: notifier.LoggedIn, Username: "alice", IpAddress: "1.2.3.4"},
+			want:    []string{"slack"},
+		},
+	}
+	r, err := New(testSinks(), nil, routes, []string{"slack"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Route(tt.logLine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Router.Route() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_Notify(t *testing.T) {
+	failing := &routerfakes.FakeNotifierClient{
+		NotifyStub: func(notifier.LogLine) error {
+			return fmt.Errorf("webhook unavailable")
+		},
+	}
+	healthy := &routerfakes.FakeNotifierClient{}
+	sinks := Sinks{
+		"failing": failing,
+		"healthy": healthy,
+	}
+	r, err := New(sinks, nil, nil, []string{"failing", "healthy"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	r.retryDelay = time.Millisecond
+
+	if err := r.Notify(notifier.LogLine{EventType: notifier.LoggedIn}); err != nil {
+		t.Errorf("Router.Notify() error = %v, want nil as a sink delivered", err)
+	}
+	if healthy.NotifyCallCount() != 1 {
+		t.Errorf("healthy sink notified %d times, want 1", healthy.NotifyCallCount())
+	}
+	if failing.NotifyCallCount() != deliveryAttempts {
+		t.Errorf("failing sink notified %d times, want %d", failing.NotifyCallCount(), deliveryAttempts)
+	}
+}
+
+func TestRouter_NotifyRetriesFailedSinks(t *testing.T) {
+	flaky := &routerfakes.FakeNotifierClient{}
+	flaky.NotifyReturnsOnCall(0, fmt.Errorf("webhook unavailable"))
+	healthy := &routerfakes.FakeNotifierClient{}
+	r, err := New(Sinks{"flaky": flaky, "healthy": healthy}, nil, nil, []string{"flaky", "healthy"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	r.retryDelay = time.Millisecond
+
+	if err := r.Notify(notifier.LogLine{EventType: notifier.LoggedIn}); err != nil {
+		t.Errorf("Router.Notify() error = %v", err)
+	}
+	if flaky.NotifyCallCount() != 2 || healthy.NotifyCallCount() != 1 {
+		t.Errorf("notified flaky %d and healthy %d times, want 2 and 1", flaky.NotifyCallCount(), healthy.NotifyCallCount())
+	}
+}
+
+func TestRouter_NotifyAllSinksFailing(t *testing.T) {
+	failing := &routerfakes.FakeNotifierClient{}
+	failing.NotifyReturns(fmt.Errorf("webhook unavailable"))
+	r, err := New(Sinks{"failing": failing}, nil, nil, []string{"failing"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	r.retryDelay = time.Millisecond
+
+	err = r.Notify(notifier.LogLine{EventType: notifier.LoggedIn})
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) {
+		t.Fatalf("Router.Notify() error = %v, want *DeliveryError", err)
+	}
+	if _, ok := deliveryErr.Failures["failing"]; !ok || len(deliveryErr.Failures) != 1 {
+		t.Errorf("Router.Notify() failures = %v, want the failing sink", deliveryErr.Failures)
+	}
+}
+
+func TestRouter_NotifyNoSinks(t *testing.T) {
+	sink := &routerfakes.FakeNotifierClient{}
+	routes := []config.Route{{Match: config.Match{Users: []string{"ci"}}}}
+	r, err := New(Sinks{"slack": sink}, nil, routes, []string{"slack"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := r.Notify(notifier.LogLine{EventType: notifier.LoggedIn, Username: "ci"}); err != nil {
+		t.Errorf("Router.Notify() error = %v", err)
+	}
+	if sink.NotifyCallCount() != 0 {
+		t.Errorf("sink notified %d times, want 0", sink.NotifyCallCount())
+	}
+}
@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// File is the content of the rules file.
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule applies Actions to events matching Match. Rules are evaluated in order
+// and evaluation stops at the first matching rule unless Continue is set.
+type Rule struct {
+	Name     string  `json:"name"`
+	Match    Match   `json:"match"`
+	Actions  Actions `json:"actions"`
+	Continue bool    `json:"continue"`
+}
+
+// Match selects events. Every non empty field must match the event and a
+// field matches when any of its values does. An empty Match matches all events.
+type Match struct {
+	EventTypes []string `json:"event_types"`
+	Users      []string `json:"users"`
+	// IPs holds IPv4 and IPv6 addresses or CIDR ranges.
+	IPs         []string `json:"ips"`
+	Hosts       []string `json:"hosts"`
+	AuthMethods []string `json:"auth_methods"`
+	Severities  []string `json:"severities"`
+	// Countries are ISO 3166-1 alpha-2 country codes and Asns autonomous
+	// system numbers, both need GeoIP databases.
+	Countries []string `json:"countries"`
+	Asns      []uint   `json:"asns"`
+	// TimeOfDay matches events logged between its start and end in the local
+	// time of the host.
+	TimeOfDay *TimeRange `json:"time_of_day"`
+}
+
+type TimeRange struct {
+	// Start and End are times of day such as 22:00 and 06:00. A range whose
+	// end is before its start ends on the next day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Actions are applied to matching events in the order the rules matched, so
+// the severity of a later rule wins and sinks and tags accumulate.
+type Actions struct {
+	// Notify sends the event to these sinks instead of the sinks of the
+	// routing file.
+	Notify []string `json:"notify"`
+	// Severity replaces the severity of the event.
+	Severity string `json:"severity"`
+	// Suppress drops the event before it is sent.
+	Suppress bool     `json:"suppress"`
+	Tags     []string `json:"tags"`
+	// Respond makes the active responder ban the source of the event.
+	Respond bool `json:"respond"`
+}
+
+// Load reads the rules file at path.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("failed reading rules file: %w", err)
+	}
+	file := File{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return File{}, fmt.Errorf("failed parsing rules file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// FromWatchSettings returns the rules equivalent to the watch settings, used
+// when no rules file is configured. They suppress the disabled event types.
+func FromWatchSettings(settings config.WatchSettings) File {
+	file := File{}
+	for _, watched := range []struct {
+		enabled   bool
+		eventType notifier.EventType
+	}{
+		{enabled: settings.AcceptedLogins, eventType: notifier.LoggedIn},
+		{enabled: settings.FailedLogins, eventType: notifier.FailedLoginAttempt},
+		{enabled: settings.FailedLoginInvalidUsername, eventType: notifier.FailedLoginAttemptInvalidUsername},
+	} {
+		if watched.enabled {
+			continue
+		}
+		file.Rules = append(file.Rules, Rule{
+			Name:    fmt.Sprintf("ignore %s", watched.eventType),
+			Match:   Match{EventTypes: []string{string(watched.eventType)}},
+			Actions: Actions{Suppress: true},
+		})
+	}
+	return file
+}
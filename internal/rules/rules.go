@@ -0,0 +1,237 @@
+// Package rules applies the ordered rules of the rules file to events before
+// they are sent.
+package rules
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// clockLayout is the layout of times of day in rules.
+const clockLayout = "15:04"
+
+// timeOfDay is the compiled form of a TimeRange as offsets from midnight.
+type timeOfDay struct {
+	start time.Duration
+	end   time.Duration
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse(clockLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// clock returns the wall clock time of t as an offset from midnight. Unlike
+// the time elapsed since midnight it is not shifted on daylight saving days.
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+func (t timeOfDay) contains(at time.Time) bool {
+	sinceMidnight := clock(at)
+	if t.start < t.end {
+		return sinceMidnight >= t.start && sinceMidnight < t.end
+	}
+	return sinceMidnight >= t.start || sinceMidnight < t.end
+}
+
+// matcher is the compiled form of a Match.
+type matcher struct {
+	eventTypes  map[notifier.EventType]bool
+	users       map[string]bool
+	prefixes    []netip.Prefix
+	hosts       map[string]bool
+	authMethods map[string]bool
+	severities  map[notifier.Severity]bool
+	countries   map[string]bool
+	asns        map[uint]bool
+	timeOfDay   *timeOfDay
+}
+
+// rule is the compiled form of a Rule.
+type rule struct {
+	name     string
+	matcher  matcher
+	actions  Actions
+	severity notifier.Severity
+	cont     bool
+}
+
+func toSet[T comparable](values []T) map[T]bool {
+	set := make(map[T]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func compileMatch(match Match) (matcher, error) {
+	m := matcher{
+		eventTypes:  map[notifier.EventType]bool{},
+		users:       toSet(match.Users),
+		hosts:       toSet(match.Hosts),
+		authMethods: toSet(match.AuthMethods),
+		severities:  map[notifier.Severity]bool{},
+		countries:   toSet(match.Countries),
+		asns:        toSet(match.Asns),
+	}
+	for _, eventType := range match.EventTypes {
+		m.eventTypes[notifier.EventType(eventType)] = true
+	}
+	for _, s := range match.Severities {
+		severity, err := notifier.ParseSeverity(s)
+		if err != nil {
+			return matcher{}, err
+		}
+		m.severities[severity] = true
+	}
+	for _, ip := range match.IPs {
+		prefix, err := cidr.Parse(ip)
+		if err != nil {
+			return matcher{}, err
+		}
+		m.prefixes = append(m.prefixes, prefix)
+	}
+	if match.TimeOfDay != nil {
+		start, err := parseClock(match.TimeOfDay.Start)
+		if err != nil {
+			return matcher{}, err
+		}
+		end, err := parseClock(match.TimeOfDay.End)
+		if err != nil {
+			return matcher{}, err
+		}
+		m.timeOfDay = &timeOfDay{start: start, end: end}
+	}
+	return m, nil
+}
+
+func (m matcher) matches(logLine notifier.LogLine, at time.Time) bool {
+	if len(m.eventTypes) > 0 && !m.eventTypes[logLine.EventType] {
+		return false
+	}
+	if len(m.users) > 0 && !m.users[logLine.Username] {
+		return false
+	}
+	if len(m.prefixes) > 0 && !cidr.Contains(m.prefixes, logLine.IpAddress) {
+		return false
+	}
+	if len(m.hosts) > 0 && !m.hosts[logLine.HostMachine] {
+		return false
+	}
+	if len(m.authMethods) > 0 && !m.authMethods[logLine.AuthMethod] {
+		return false
+	}
+	if len(m.severities) > 0 && !m.severities[logLine.Severity] {
+		return false
+	}
+	if len(m.countries) > 0 && (logLine.Location == nil || !m.countries[logLine.Location.Country]) {
+		return false
+	}
+	if len(m.asns) > 0 && (logLine.Location == nil || !m.asns[logLine.Location.Asn]) {
+		return false
+	}
+	if m.timeOfDay != nil && !m.timeOfDay.contains(at) {
+		return false
+	}
+	return true
+}
+
+// New compiles the rules of file. Sinks named by notify actions must be in
+// sinks unless sinks is nil.
+func New(file File, sinks []string) (Engine, error) {
+	var known map[string]bool
+	if sinks != nil {
+		known = toSet(sinks)
+	}
+	e := Engine{now: time.Now}
+	for i, r := range file.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		m, err := compileMatch(r.Match)
+		if err != nil {
+			return Engine{}, fmt.Errorf("invalid match in %s: %w", name, err)
+		}
+		compiled := rule{name: name, matcher: m, actions: r.Actions, cont: r.Continue}
+		if r.Actions.Severity != "" {
+			severity, err := notifier.ParseSeverity(r.Actions.Severity)
+			if err != nil {
+				return Engine{}, fmt.Errorf("invalid severity in %s: %w", name, err)
+			}
+			compiled.severity = severity
+		}
+		for _, sink := range r.Actions.Notify {
+			if known != nil && !known[sink] {
+				return Engine{}, fmt.Errorf("unknown sink %q in %s", sink, name)
+			}
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+// Engine is an app stage applying the actions of matching rules to events.
+// The names of matching rules are recorded in MatchedRules.
+type Engine struct {
+	rules []rule
+	now   func() time.Time
+}
+
+// Evaluate applies the actions of the rules matching the log line, logged at
+// the time at, and reports whether it is suppressed.
+func (e Engine) Evaluate(logLine notifier.LogLine, at time.Time) (notifier.LogLine, bool) {
+	suppressed := false
+	for _, r := range e.rules {
+		if !r.matcher.matches(logLine, at.Local()) {
+			continue
+		}
+		logLine.MatchedRules = append(logLine.MatchedRules, r.name)
+		if r.severity != "" {
+			logLine.Severity = r.severity
+		}
+		for _, sink := range r.actions.Notify {
+			if !contains(logLine.Sinks, sink) {
+				logLine.Sinks = append(logLine.Sinks, sink)
+			}
+		}
+		for _, tag := range r.actions.Tags {
+			if !contains(logLine.Tags, tag) {
+				logLine.Tags = append(logLine.Tags, tag)
+			}
+		}
+		suppressed = suppressed || r.actions.Suppress
+		logLine.Respond = logLine.Respond || r.actions.Respond
+		if !r.cont {
+			break
+		}
+	}
+	return logLine, suppressed
+}
+
+// Process implements the app stage interface. Events are matched against the
+// time they were logged at, or processed at if it is unknown.
+func (e Engine) Process(logLine notifier.LogLine) []notifier.LogLine {
+	logLine, suppressed := e.Evaluate(logLine, logLine.OccurredAt(e.now()))
+	if suppressed {
+		return nil
+	}
+	return []notifier.LogLine{logLine}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
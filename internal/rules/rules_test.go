@@ -0,0 +1,273 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/config"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestFromWatchSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		watchSettings config.WatchSettings
+		eventType     notifier.EventType
+		want          bool
+	}{
+		{
+			name:          "test should send accepted login",
+			watchSettings: config.WatchSettings{AcceptedLogins: true},
+			eventType:     notifier.LoggedIn,
+			want:          true,
+		},
+		{
+			name:          "test should send failed login",
+			watchSettings: config.WatchSettings{FailedLogins: true},
+			eventType:     notifier.FailedLoginAttempt,
+			want:          true,
+		},
+		{
+			name:          "test should send failed login invalid username",
+			watchSettings: config.WatchSettings{FailedLoginInvalidUsername: true},
+			eventType:     notifier.FailedLoginAttemptInvalidUsername,
+			want:          true,
+		},
+		{
+			name:          "test should not send accepted login",
+			watchSettings: config.WatchSettings{FailedLogins: true, FailedLoginInvalidUsername: true},
+			eventType:     notifier.LoggedIn,
+			want:          false,
+		},
+		{
+			name:          "test should not send failed login",
+			watchSettings: config.WatchSettings{AcceptedLogins: true, FailedLoginInvalidUsername: true},
+			eventType:     notifier.FailedLoginAttempt,
+			want:          false,
+		},
+		{
+			name:          "test should not send failed login invalid username",
+			watchSettings: config.WatchSettings{AcceptedLogins: true, FailedLogins: true},
+			eventType:     notifier.FailedLoginAttemptInvalidUsername,
+			want:          false,
+		},
+		{
+			name:      "test should send detections",
+			eventType: notifier.BruteForceDetected,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(FromWatchSettings(tt.watchSettings), nil)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := len(e.Process(notifier.LogLine{EventType: tt.eventType})) > 0; got != tt.want {
+				t.Errorf("Process() sent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	file := File{Rules: []Rule{
+		{
+			Name:    "ci",
+			Match:   Match{Users: []string{"ci"}, IPs: []string{"10.0.0.0/8"}, AuthMethods: []string{"publickey"}},
+			Actions: Actions{Suppress: true},
+		},
+		{
+			Name:     "root",
+			Match:    Match{EventTypes: []string{"logged in"}, Users: []string{"root"}},
+			Actions:  Actions{Notify: []string{"oncall"}, Severity: "critical", Tags: []string{"privileged"}},
+			Continue: true,
+		},
+		{
+			Name:    "night",
+			Match:   Match{TimeOfDay: &TimeRange{Start: "22:00", End: "06:00"}},
+			Actions: Actions{Notify: []string{"oncall", "slack"}, Tags: []string{"night"}},
+		},
+		{
+			Name:    "hostile",
+			Match:   Match{Countries: []string{"XX"}, Asns: []uint{64496}},
+			Actions: Actions{Severity: "high", Respond: true},
+		},
+	}}
+	e, err := New(file, []string{"oncall", "slack"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	day := time.Date(2024, 12, 2, 12, 0, 0, 0, time.Local)
+	night := time.Date(2024, 12, 2, 23, 0, 0, 0, time.Local)
+	hostile := &notifier.Location{Country: "XX", Asn: 64496}
+
+	tests := []struct {
+		name           string
+		logLine        notifier.LogLine
+		at             time.Time
+		want           notifier.LogLine
+		wantSuppressed bool
+	}{
+		{
+			name:           "suppressed",
+			logLine:        notifier.LogLine{Username: "ci", IpAddress: "10.1.2.3", AuthMethod: "publickey", EventType: notifier.LoggedIn},
+			at:             day,
+			want:           notifier.LogLine{Username: "ci", IpAddress: "10.1.2.3", AuthMethod: "publickey", EventType: notifier.LoggedIn, MatchedRules: []string{"ci"}},
+			wantSuppressed: true,
+		},
+		{
+			name:    "auth method must match",
+			logLine: notifier.LogLine{Username: "ci", IpAddress: "10.1.2.3", AuthMethod: "password", EventType: notifier.LoggedIn},
+			at:      day,
+			want:    notifier.LogLine{Username: "ci", IpAddress: "10.1.2.3", AuthMethod: "password", EventType: notifier.LoggedIn},
+		},
+		{
+			name:    "continue evaluates later rules",
+			logLine: notifier.LogLine{Username: "root", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+			at:      night,
+			want: notifier.LogLine{
+				Username:     "root",
+				EventType:    notifier.LoggedIn,
+				Severity:     notifier.SeverityCritical,
+				MatchedRules: []string{"root", "night"},
+				Sinks:        []string{"oncall", "slack"},
+				Tags:         []string{"privileged", "night"},
+			},
+		},
+		{
+			name:    "first match stops evaluation",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.LoggedIn, Location: hostile},
+			at:      night,
+			want: notifier.LogLine{
+				Username:     "alice",
+				EventType:    notifier.LoggedIn,
+				Location:     hostile,
+				MatchedRules: []string{"night"},
+				Sinks:        []string{"oncall", "slack"},
+				Tags:         []string{"night"},
+			},
+		},
+		{
+			name:    "geo fields",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.FailedLoginAttempt, Location: hostile, Severity: notifier.SeverityLow},
+			at:      day,
+			want: notifier.LogLine{
+				Username:     "alice",
+				EventType:    notifier.FailedLoginAttempt,
+				Location:     hostile,
+				Severity:     notifier.SeverityHigh,
+				MatchedRules: []string{"hostile"},
+				Respond:      true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, suppressed := e.Evaluate(tt.logLine, tt.at)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %+v, want %+v", got, tt.want)
+			}
+			if suppressed != tt.wantSuppressed {
+				t.Errorf("Evaluate() suppressed = %v, want %v", suppressed, tt.wantSuppressed)
+			}
+		})
+	}
+}
+
+func TestEngine_ProcessReplay(t *testing.T) {
+	e, err := New(File{Rules: []Rule{{
+		Name:    "quiet night",
+		Match:   Match{TimeOfDay: &TimeRange{Start: "22:00", End: "06:00"}},
+		Actions: Actions{Suppress: true},
+	}}}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	day := time.Date(2024, 12, 2, 12, 0, 0, 0, time.Local)
+	night := time.Date(2024, 12, 2, 23, 0, 0, 0, time.Local)
+	e.now = func() time.Time { return night }
+
+	// A line logged during the day and replayed at night is matched at the
+	// time it was logged.
+	logged := notifier.LogLine{Username: "alice", EventType: notifier.LoggedIn, Time: day}
+	if got := e.Process(logged); len(got) != 1 {
+		t.Errorf("Process() = %v, want the line logged outside the window passed on", got)
+	}
+	if got := e.Process(notifier.LogLine{Username: "alice", EventType: notifier.LoggedIn}); len(got) != 0 {
+		t.Errorf("Process() = %v, want a line without a time matched at processing time", got)
+	}
+}
+
+func TestTimeOfDay_contains(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	workHours := timeOfDay{start: 7 * time.Hour, end: 20 * time.Hour}
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "within", at: time.Date(2024, 12, 2, 12, 0, 0, 0, berlin), want: true},
+		{name: "after end", at: time.Date(2024, 12, 2, 20, 0, 0, 0, berlin), want: false},
+		{name: "start on the day clocks move forward", at: time.Date(2024, 3, 31, 7, 30, 0, 0, berlin), want: true},
+		{name: "end on the day clocks move back", at: time.Date(2024, 10, 27, 19, 30, 0, 0, berlin), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workHours.contains(tt.at); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "valid", rule: Rule{Match: Match{IPs: []string{"2001:db8::/32"}}, Actions: Actions{Notify: []string{"slack"}}}},
+		{name: "invalid cidr", rule: Rule{Match: Match{IPs: []string{"10.0.0.0/33"}}}, wantErr: true},
+		{name: "invalid severity", rule: Rule{Actions: Actions{Severity: "urgent"}}, wantErr: true},
+		{name: "invalid severity match", rule: Rule{Match: Match{Severities: []string{"urgent"}}}, wantErr: true},
+		{name: "invalid time of day", rule: Rule{Match: Match{TimeOfDay: &TimeRange{Start: "10pm", End: "06:00"}}}, wantErr: true},
+		{name: "unknown sink", rule: Rule{Actions: Actions{Notify: []string{"email"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(File{Rules: []Rule{tt.rule}}, []string{"slack"}); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: `{"rules": [{"name": "x", "match": {"users": ["root"]}, "actions": {"suppress": true}}]}`},
+		{name: "invalid json", content: `{"rules": [`, wantErr: true},
+		{name: "unknown field", content: `{"rules": [{"name": "x", "match": {"user": ["root"]}, "actions": {"suppress": true}}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Action is what happens to logins outside the schedule of a policy.
+type Action string
+
+const (
+	// Event sends an OutOfHoursLogin event after the login.
+	Event Action = "event"
+	// Escalate raises the severity of the login itself.
+	Escalate Action = "escalate"
+)
+
+// File is the content of the schedule file.
+type File struct {
+	Schedules []Schedule `json:"schedules"`
+	// Groups maps group names to their members. Groups not defined here are
+	// looked up in the system group database.
+	Groups   map[string][]string `json:"groups"`
+	Policies []Policy            `json:"policies"`
+}
+
+// Policy attaches a schedule to users and groups. A policy without users and
+// groups applies to every user. The first policy applying to a user is used.
+type Policy struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"`
+	Users    []string `json:"users"`
+	Groups   []string `json:"groups"`
+	// Action is event by default.
+	Action Action `json:"action"`
+	// Severity is the severity of out of hours logins, high by default.
+	Severity string `json:"severity"`
+	// OnlyOutOfHours suppresses logins within the schedule so only out of
+	// hours logins are sent.
+	OnlyOutOfHours bool `json:"only_out_of_hours"`
+}
+
+// readFile reads and parses the schedule file at path.
+func readFile(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("failed reading schedule file: %w", err)
+	}
+	file := File{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return File{}, fmt.Errorf("failed parsing schedule file %s: %w", path, err)
+	}
+	return file, nil
+}
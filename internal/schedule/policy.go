@@ -0,0 +1,250 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// policy is the compiled form of a Policy.
+type policy struct {
+	name     string
+	schedule schedule
+	users    map[string]bool
+	groups   []string
+	action   Action
+	severity notifier.Severity
+	// ownSeverity is set when the policy configures its severity, which then
+	// replaces the out of hours escalation of the classifier.
+	ownSeverity    bool
+	onlyOutOfHours bool
+}
+
+// rules are the compiled contents of a schedule file.
+type rules struct {
+	groups   map[string]map[string]bool
+	policies []policy
+}
+
+func compile(file File) (rules, error) {
+	schedules := map[string]schedule{}
+	for _, s := range file.Schedules {
+		if s.Name == "" {
+			return rules{}, fmt.Errorf("schedule has no name")
+		}
+		compiled, err := compileSchedule(s)
+		if err != nil {
+			return rules{}, fmt.Errorf("schedule %q: %w", s.Name, err)
+		}
+		schedules[s.Name] = compiled
+	}
+
+	compiled := rules{groups: map[string]map[string]bool{}}
+	for group, members := range file.Groups {
+		compiled.groups[group] = map[string]bool{}
+		for _, member := range members {
+			compiled.groups[group][member] = true
+		}
+	}
+	for _, p := range file.Policies {
+		s, ok := schedules[p.Schedule]
+		if !ok {
+			return rules{}, fmt.Errorf("policy %q has unknown schedule %q", p.Name, p.Schedule)
+		}
+		compiledPolicy := policy{
+			name:           p.Name,
+			schedule:       s,
+			users:          map[string]bool{},
+			groups:         p.Groups,
+			action:         p.Action,
+			severity:       notifier.SeverityHigh,
+			onlyOutOfHours: p.OnlyOutOfHours,
+		}
+		switch p.Action {
+		case "":
+			compiledPolicy.action = Event
+		case Event, Escalate:
+		default:
+			return rules{}, fmt.Errorf("policy %q has unknown action %q", p.Name, p.Action)
+		}
+		if p.Severity != "" {
+			severity, err := notifier.ParseSeverity(p.Severity)
+			if err != nil {
+				return rules{}, fmt.Errorf("policy %q: %w", p.Name, err)
+			}
+			compiledPolicy.severity = severity
+			compiledPolicy.ownSeverity = true
+		}
+		for _, u := range p.Users {
+			compiledPolicy.users[u] = true
+		}
+		compiled.policies = append(compiled.policies, compiledPolicy)
+	}
+	return compiled, nil
+}
+
+// systemGroupMember reports whether username is a member of the system group
+// named group.
+func systemGroupMember(username, group string) bool {
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return false
+	}
+	u, err := user.Lookup(username)
+	if err != nil {
+		return false
+	}
+	groupIds, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	for _, id := range groupIds {
+		if id == g.Gid {
+			return true
+		}
+	}
+	return false
+}
+
+// New loads the schedule file at path. The file is checked for changes at
+// most every reloadInterval.
+func New(path string, reloadInterval time.Duration, log zerolog.Logger) (*Policies, error) {
+	p := &Policies{
+		path:           path,
+		reloadInterval: reloadInterval,
+		now:            time.Now,
+		groupMember:    systemGroupMember,
+		log:            log,
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	p.lastCheck = p.now()
+	return p, nil
+}
+
+// Policies is an app stage checking logins against the schedules of the users
+// logging in.
+type Policies struct {
+	path           string
+	rules          rules
+	modTime        time.Time
+	reloadInterval time.Duration
+	lastCheck      time.Time
+	now            func() time.Time
+	groupMember    func(username, group string) bool
+	log            zerolog.Logger
+	mu             sync.Mutex
+}
+
+// load reads the schedule file and replaces the rules.
+func (p *Policies) load() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed reading schedule file: %w", err)
+	}
+	file, err := readFile(p.path)
+	if err != nil {
+		return err
+	}
+	compiled, err := compile(file)
+	if err != nil {
+		return fmt.Errorf("invalid schedule file %s: %w", p.path, err)
+	}
+	p.rules, p.modTime = compiled, info.ModTime()
+	return nil
+}
+
+// reloadChanged reloads the rules once reloadInterval passed since the last
+// check if the file changed. Invalid changes are logged and the previous rules
+// stay in use. p.mu must be held.
+func (p *Policies) reloadChanged() {
+	now := p.now()
+	if now.Sub(p.lastCheck) < p.reloadInterval {
+		return
+	}
+	p.lastCheck = now
+	if info, err := os.Stat(p.path); err == nil && info.ModTime().Equal(p.modTime) {
+		return
+	}
+	if err := p.load(); err != nil {
+		p.log.Error().Err(err).Msg("failed reloading schedule file, keeping previous rules")
+		return
+	}
+	p.log.Info().Msg(fmt.Sprintf("reloaded schedule file %s", p.path))
+}
+
+// policyFor returns the first policy applying to username.
+func (p *Policies) policyFor(r rules, username string) (policy, bool) {
+	for _, candidate := range r.policies {
+		if len(candidate.users) == 0 && len(candidate.groups) == 0 {
+			return candidate, true
+		}
+		if candidate.users[username] {
+			return candidate, true
+		}
+		for _, group := range candidate.groups {
+			members, ok := r.groups[group]
+			if ok && members[username] || !ok && p.groupMember(username, group) {
+				return candidate, true
+			}
+		}
+	}
+	return policy{}, false
+}
+
+// Process implements the app stage interface.
+func (p *Policies) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.EventType != notifier.LoggedIn {
+		return []notifier.LogLine{logLine}
+	}
+
+	p.mu.Lock()
+	p.reloadChanged()
+	r := p.rules
+	now := logLine.OccurredAt(p.now())
+	p.mu.Unlock()
+
+	matched, ok := p.policyFor(r, logLine.Username)
+	if !ok {
+		return []notifier.LogLine{logLine}
+	}
+	if matched.schedule.contains(now) {
+		if matched.onlyOutOfHours {
+			p.log.Debug().Msg(fmt.Sprintf("login of %s within schedule %s suppressed", logLine.Username, matched.schedule.name))
+			return nil
+		}
+		return []notifier.LogLine{logLine}
+	}
+
+	reason := fmt.Sprintf("outside schedule %s of policy %s", matched.schedule.name, matched.name)
+	// Only one of the login and the event is marked so the classifier does
+	// not escalate both, and neither when the policy sets its own severity.
+	if matched.action == Escalate {
+		logLine.OutOfHours = !matched.ownSeverity
+		if matched.severity.Rank() > logLine.Severity.Rank() {
+			logLine.Severity = matched.severity
+		}
+		logLine.Reason = reason
+		return []notifier.LogLine{logLine}
+	}
+	event := notifier.LogLine{
+		Username:    logLine.Username,
+		IpAddress:   logLine.IpAddress,
+		Location:    logLine.Location,
+		ThreatFeeds: logLine.ThreatFeeds,
+		LoginTime:   logLine.LoginTime,
+		Time:        logLine.Time,
+		EventType:   notifier.OutOfHoursLogin,
+		HostMachine: logLine.HostMachine,
+		Severity:    matched.severity,
+		Reason:      reason,
+		OutOfHours:  !matched.ownSeverity,
+	}
+	return []notifier.LogLine{logLine, event}
+}
@@ -0,0 +1,163 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const scheduleFile = `{
+	"schedules": [
+		{"name": "business-hours", "timezone": "UTC", "days": ["mon", "tue", "wed", "thu", "fri"], "ranges": [{"start": "07:00", "end": "20:00"}]}
+	],
+	"groups": {"admins": ["alice"]},
+	"policies": [
+		{"name": "deploy", "schedule": "business-hours", "users": ["deploy"], "only_out_of_hours": true},
+		{"name": "interns", "schedule": "business-hours", "users": ["intern"], "severity": "low"},
+		{"name": "admins", "schedule": "business-hours", "groups": ["admins", "wheel"], "action": "escalate", "severity": "critical"}
+	]
+}`
+
+func TestPolicies_Process(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	writeFile(t, path, scheduleFile)
+	p, err := New(path, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	p.groupMember = func(username, group string) bool {
+		return username == "bob" && group == "wheel"
+	}
+
+	inHours := time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC)
+	outOfHours := time.Date(2024, 12, 2, 22, 0, 0, 0, time.UTC)
+	login := func(user string, at time.Time) notifier.LogLine {
+		return notifier.LogLine{Username: user, IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium, Time: at}
+	}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		logLine notifier.LogLine
+		want    []notifier.LogLine
+	}{
+		{
+			name:    "only out of hours account within hours",
+			at:      inHours,
+			logLine: login("deploy", inHours),
+		},
+		{
+			name:    "only out of hours account outside hours",
+			at:      outOfHours,
+			logLine: login("deploy", outOfHours),
+			want: []notifier.LogLine{
+				login("deploy", outOfHours),
+				{Username: "deploy", IpAddress: "1.2.3.4", Time: outOfHours, EventType: notifier.OutOfHoursLogin, Severity: notifier.SeverityHigh, Reason: "outside schedule business-hours of policy deploy", OutOfHours: true},
+			},
+		},
+		{
+			name:    "policy severity is not escalated",
+			at:      outOfHours,
+			logLine: login("intern", outOfHours),
+			want: []notifier.LogLine{
+				login("intern", outOfHours),
+				{Username: "intern", IpAddress: "1.2.3.4", Time: outOfHours, EventType: notifier.OutOfHoursLogin, Severity: notifier.SeverityLow, Reason: "outside schedule business-hours of policy interns"},
+			},
+		},
+		{
+			name:    "group member within hours",
+			at:      inHours,
+			logLine: login("alice", inHours),
+			want:    []notifier.LogLine{login("alice", inHours)},
+		},
+		{
+			name:    "group member outside hours is escalated",
+			at:      outOfHours,
+			logLine: login("alice", outOfHours),
+			want: []notifier.LogLine{
+				{Username: "alice", IpAddress: "1.2.3.4", Time: outOfHours, EventType: notifier.LoggedIn, Severity: notifier.SeverityCritical, Reason: "outside schedule business-hours of policy admins"},
+			},
+		},
+		{
+			name:    "system group member outside hours",
+			at:      outOfHours,
+			logLine: login("bob", outOfHours),
+			want: []notifier.LogLine{
+				{Username: "bob", IpAddress: "1.2.3.4", Time: outOfHours, EventType: notifier.LoggedIn, Severity: notifier.SeverityCritical, Reason: "outside schedule business-hours of policy admins"},
+			},
+		},
+		{
+			name:    "user without policy",
+			at:      outOfHours,
+			logLine: login("carol", outOfHours),
+			want:    []notifier.LogLine{login("carol", outOfHours)},
+		},
+		{
+			name:    "failed logins are passed through",
+			at:      outOfHours,
+			logLine: notifier.LogLine{Username: "deploy", EventType: notifier.FailedLoginAttempt},
+			want:    []notifier.LogLine{{Username: "deploy", EventType: notifier.FailedLoginAttempt}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.now = func() time.Time { return tt.at }
+			p.lastCheck = tt.at
+			if got := p.Process(tt.logLine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Process() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicies_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	writeFile(t, path, scheduleFile)
+	p, err := New(path, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	p.lastCheck = now
+
+	// An invalid change keeps the previous rules.
+	writeFile(t, path, `{"policies": [{"name": "broken", "schedule": "missing"}]}`)
+	if err := os.Chtimes(path, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if got := p.Process(notifier.LogLine{Username: "deploy", EventType: notifier.LoggedIn}); got != nil {
+		t.Errorf("Process() after invalid change = %v, want nil", got)
+	}
+
+	writeFile(t, path, `{"schedules": [{"name": "never"}], "policies": [{"name": "all", "schedule": "never", "only_out_of_hours": true}]}`)
+	if err := os.Chtimes(path, now, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	got := p.Process(notifier.LogLine{Username: "deploy", EventType: notifier.LoggedIn})
+	if len(got) != 2 || got[1].EventType != notifier.OutOfHoursLogin {
+		t.Errorf("Process() after valid change = %v, want login and out of hours event", got)
+	}
+}
+
+func TestNew_unknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	writeFile(t, path, `{"policies": [{"name": "deploy", "schedule": "never", "only_out_of_hour": true}], "schedules": [{"name": "never"}]}`)
+	if _, err := New(path, time.Minute, zerolog.Nop()); err == nil {
+		t.Error("New() error = nil, want error for a misspelled field")
+	}
+}
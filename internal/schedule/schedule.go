@@ -0,0 +1,148 @@
+// Package schedule flags logins outside the hours users are expected to log
+// in.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// clockLayout is the layout of times of day in schedules.
+const clockLayout = "15:04"
+
+// dateLayout is the layout of holiday dates.
+const dateLayout = "2006-01-02"
+
+// Schedule defines when logins are expected.
+type Schedule struct {
+	Name string `json:"name"`
+	// Timezone is an IANA timezone such as Europe/Berlin. Empty means the
+	// local timezone of the host.
+	Timezone string `json:"timezone"`
+	// Days are the weekdays the ranges apply to, e.g. ["mon", "tuesday"].
+	Days []string `json:"days"`
+	// Ranges are the times of day logins are expected on Days. A range whose
+	// end is before its start ends on the next day.
+	Ranges []TimeRange `json:"ranges"`
+	// Holidays are dates, e.g. 2024-12-25, on which no logins are expected.
+	Holidays []string `json:"holidays"`
+}
+
+type TimeRange struct {
+	// Start and End are times of day such as 07:00 and 20:00.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// parseWeekday parses the full or three letter name of a weekday.
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// compiledRange holds a TimeRange as offsets from midnight.
+type compiledRange struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// schedule is the compiled form of a Schedule.
+type schedule struct {
+	name     string
+	location *time.Location
+	days     map[time.Weekday]bool
+	ranges   []compiledRange
+	holidays map[string]bool
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse(clockLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// clock returns the wall clock time of t as an offset from midnight. Unlike
+// the time elapsed since midnight it is not shifted on daylight saving days.
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+func compileSchedule(s Schedule) (schedule, error) {
+	compiled := schedule{
+		name:     s.Name,
+		location: time.Local,
+		days:     map[time.Weekday]bool{},
+		holidays: map[string]bool{},
+	}
+	if s.Timezone != "" {
+		location, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return schedule{}, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+		}
+		compiled.location = location
+	}
+	for _, day := range s.Days {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return schedule{}, fmt.Errorf("invalid day %q", day)
+		}
+		compiled.days[weekday] = true
+	}
+	for _, r := range s.Ranges {
+		start, err := parseClock(r.Start)
+		if err != nil {
+			return schedule{}, err
+		}
+		end, err := parseClock(r.End)
+		if err != nil {
+			return schedule{}, err
+		}
+		compiled.ranges = append(compiled.ranges, compiledRange{start: start, end: end})
+	}
+	for _, holiday := range s.Holidays {
+		if _, err := time.Parse(dateLayout, holiday); err != nil {
+			return schedule{}, fmt.Errorf("invalid holiday %q, want YYYY-MM-DD", holiday)
+		}
+		compiled.holidays[holiday] = true
+	}
+	return compiled, nil
+}
+
+// contains reports whether t falls within the schedule.
+func (s schedule) contains(t time.Time) bool {
+	t = t.In(s.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+	sinceMidnight := clock(t)
+	yesterday := midnight.AddDate(0, 0, -1)
+
+	for _, r := range s.ranges {
+		if r.start < r.end {
+			if s.onDuty(midnight) && sinceMidnight >= r.start && sinceMidnight < r.end {
+				return true
+			}
+			continue
+		}
+		// The range wraps past midnight, it belongs to the day it started.
+		if s.onDuty(midnight) && sinceMidnight >= r.start {
+			return true
+		}
+		if s.onDuty(yesterday) && sinceMidnight < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// onDuty reports whether ranges apply on the day starting at midnight.
+func (s schedule) onDuty(midnight time.Time) bool {
+	return s.days[midnight.Weekday()] && !s.holidays[midnight.Format(dateLayout)]
+}
@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_contains(t *testing.T) {
+	s, err := compileSchedule(Schedule{
+		Name:     "business-hours",
+		Timezone: "Europe/Berlin",
+		Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+		Ranges:   []TimeRange{{Start: "07:00", End: "20:00"}},
+		Holidays: []string{"2024-12-25"},
+	})
+	if err != nil {
+		t.Fatalf("compileSchedule() error = %v", err)
+	}
+	night, err := compileSchedule(Schedule{
+		Name:     "night-shift",
+		Timezone: "UTC",
+		Days:     []string{"Friday"},
+		Ranges:   []TimeRange{{Start: "22:00", End: "06:00"}},
+	})
+	if err != nil {
+		t.Fatalf("compileSchedule() error = %v", err)
+	}
+
+	everyDay, err := compileSchedule(Schedule{
+		Name:     "every-day",
+		Timezone: "Europe/Berlin",
+		Days:     []string{"Sun", "mon", "tue", "wed", "thu", "fri", "saturday"},
+		Ranges:   []TimeRange{{Start: "07:00", End: "20:00"}},
+	})
+	if err != nil {
+		t.Fatalf("compileSchedule() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule schedule
+		at       time.Time
+		want     bool
+	}{
+		{
+			name:     "weekday within hours",
+			schedule: s,
+			at:       time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "weekday within hours in utc but not in schedule timezone",
+			schedule: s,
+			at:       time.Date(2024, 12, 2, 19, 30, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			name:     "end is exclusive",
+			schedule: s,
+			at:       time.Date(2024, 12, 2, 19, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			name:     "weekend",
+			schedule: s,
+			at:       time.Date(2024, 12, 7, 10, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			name:     "holiday",
+			schedule: s,
+			at:       time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			name:     "range past midnight on its day",
+			schedule: night,
+			at:       time.Date(2024, 12, 6, 23, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "range past midnight on the next day",
+			schedule: night,
+			at:       time.Date(2024, 12, 7, 5, 59, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "start on the day clocks move forward",
+			schedule: everyDay,
+			at:       time.Date(2024, 3, 31, 5, 30, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "end on the day clocks move back",
+			schedule: everyDay,
+			at:       time.Date(2024, 10, 27, 18, 30, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			name:     "range past midnight starting the day before",
+			schedule: night,
+			at:       time.Date(2024, 12, 6, 5, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.contains(tt.at); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileSchedule_invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+	}{
+		{name: "timezone", schedule: Schedule{Timezone: "Mars/Olympus"}},
+		{name: "day", schedule: Schedule{Days: []string{"someday"}}},
+		{name: "day with a weekday prefix", schedule: Schedule{Days: []string{"monkey"}}},
+		{name: "range", schedule: Schedule{Ranges: []TimeRange{{Start: "7am", End: "20:00"}}}},
+		{name: "holiday", schedule: Schedule{Holidays: []string{"25.12.2024"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileSchedule(tt.schedule); err == nil {
+				t.Error("compileSchedule() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Package severity escalates the severity of events matching escalation
+// rules.
+package severity
+
+import (
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// Settings configures the escalation rules. A rule with an empty severity is
+// disabled.
+type Settings struct {
+	// PrivilegedUsers are the users whose logins are escalated to
+	// PrivilegedLogin.
+	PrivilegedUsers []string
+	PrivilegedLogin notifier.Severity
+	// Denylisted is the severity of events marked Denylisted.
+	Denylisted notifier.Severity
+	// OutOfHours is the severity of events marked OutOfHours.
+	OutOfHours notifier.Severity
+}
+
+// NewClassifier creates a classifier applying the escalation rules of
+// settings.
+func NewClassifier(settings Settings) Classifier {
+	privilegedUsers := make(map[string]bool, len(settings.PrivilegedUsers))
+	for _, user := range settings.PrivilegedUsers {
+		privilegedUsers[user] = true
+	}
+	return Classifier{
+		privilegedUsers: privilegedUsers,
+		settings:        settings,
+	}
+}
+
+// Classifier is an app stage raising the severity of events to the highest
+// severity of the escalation rules they match. Severities are never lowered.
+type Classifier struct {
+	privilegedUsers map[string]bool
+	settings        Settings
+}
+
+// Process implements the app stage interface.
+func (c Classifier) Process(logLine notifier.LogLine) []notifier.LogLine {
+	if logLine.Severity == "" {
+		logLine.Severity = notifier.DefaultSeverity(logLine.EventType)
+	}
+	if logLine.EventType == notifier.LoggedIn && c.privilegedUsers[logLine.Username] {
+		logLine.Severity = escalate(logLine.Severity, c.settings.PrivilegedLogin)
+	}
+	if logLine.Denylisted {
+		logLine.Severity = escalate(logLine.Severity, c.settings.Denylisted)
+	}
+	if logLine.OutOfHours {
+		logLine.Severity = escalate(logLine.Severity, c.settings.OutOfHours)
+	}
+	return []notifier.LogLine{logLine}
+}
+
+// escalate returns the higher of current and to.
+func escalate(current, to notifier.Severity) notifier.Severity {
+	if to.Rank() > current.Rank() {
+		return to
+	}
+	return current
+}
@@ -0,0 +1,71 @@
+package severity
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestClassifier_Process(t *testing.T) {
+	c := NewClassifier(Settings{
+		PrivilegedUsers: []string{"root"},
+		PrivilegedLogin: notifier.SeverityCritical,
+		Denylisted:      notifier.SeverityHigh,
+		OutOfHours:      notifier.SeverityHigh,
+	})
+
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    notifier.Severity
+	}{
+		{
+			name:    "privileged login",
+			logLine: notifier.LogLine{Username: "root", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+			want:    notifier.SeverityCritical,
+		},
+		{
+			name:    "privileged failed login",
+			logLine: notifier.LogLine{Username: "root", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow},
+			want:    notifier.SeverityLow,
+		},
+		{
+			name:    "denylisted",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow, Denylisted: true},
+			want:    notifier.SeverityHigh,
+		},
+		{
+			name:    "out of hours",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium, OutOfHours: true},
+			want:    notifier.SeverityHigh,
+		},
+		{
+			name:    "never lowered",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.SuspiciousLoginAfterFailures, Severity: notifier.SeverityCritical, OutOfHours: true},
+			want:    notifier.SeverityCritical,
+		},
+		{
+			name:    "missing severity defaults to the event type",
+			logLine: notifier.LogLine{Username: "alice", EventType: notifier.BruteForceDetected},
+			want:    notifier.SeverityHigh,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.logLine
+			want.Severity = tt.want
+			if got := c.Process(tt.logLine); !reflect.DeepEqual(got, []notifier.LogLine{want}) {
+				t.Errorf("Process() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestClassifier_Process_disabledRules(t *testing.T) {
+	c := NewClassifier(Settings{PrivilegedUsers: []string{"root"}})
+	logLine := notifier.LogLine{Username: "root", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium, Denylisted: true, OutOfHours: true}
+	if got := c.Process(logLine); got[0].Severity != notifier.SeverityMedium {
+		t.Errorf("Process() severity = %v, want %v", got[0].Severity, notifier.SeverityMedium)
+	}
+}
@@ -0,0 +1,137 @@
+package silence
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiPrefix is the path the silences API is served under.
+const apiPrefix = "/api/v1/silences"
+
+// maxSilenceSize is the largest request body accepted when adding a silence.
+const maxSilenceSize = 64 << 10
+
+// apiSilence is a silence as returned by the API, with its status.
+type apiSilence struct {
+	Silence
+	Status Status `json:"status"`
+}
+
+// Handler serves the silences API and the silenced events metrics:
+//
+//	GET    /api/v1/silences       lists silences
+//	POST   /api/v1/silences       adds a silence, returning it with its ID
+//	DELETE /api/v1/silences/{id}  expires a silence
+//	GET    /metrics               silenced event counts in Prometheus format
+//
+// Unless token is empty, requests to the API must carry it as a bearer token.
+// The metrics are served without it.
+func Handler(s *Silencer, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(apiPrefix, authorized(token, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listSilences(s, w)
+		case http.MethodPost:
+			addSilence(s, w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.Handle(apiPrefix+"/", authorized(token, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		expireSilence(s, w, strings.TrimPrefix(r.URL.Path, apiPrefix+"/"))
+	}))
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		writeMetrics(s, w)
+	})
+	return mux
+}
+
+// authorized rejects requests not carrying token as a bearer token unless
+// token is empty.
+func authorized(token string, next http.HandlerFunc) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func listSilences(s *Silencer, w http.ResponseWriter) {
+	now := s.now()
+	silences := []apiSilence{}
+	for _, silence := range s.List() {
+		silences = append(silences, apiSilence{Silence: silence, Status: silence.Status(now)})
+	}
+	writeJSON(w, http.StatusOK, silences)
+}
+
+func addSilence(s *Silencer, w http.ResponseWriter, r *http.Request) {
+	var silence Silence
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSilenceSize)).Decode(&silence); err != nil {
+		http.Error(w, fmt.Sprintf("invalid silence: %v", err), http.StatusBadRequest)
+		return
+	}
+	silence, err := s.Add(silence)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiSilence{Silence: silence, Status: silence.Status(s.now())})
+}
+
+func expireSilence(s *Silencer, w http.ResponseWriter, id string) {
+	err := s.Expire(id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeMetrics(s *Silencer, w http.ResponseWriter) {
+	now := s.now()
+	active := 0
+	for _, silence := range s.List() {
+		if silence.Status(now) == Active {
+			active++
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP ssh_watcher_silences_active Number of active silences.")
+	fmt.Fprintln(w, "# TYPE ssh_watcher_silences_active gauge")
+	fmt.Fprintf(w, "ssh_watcher_silences_active %d\n", active)
+	fmt.Fprintln(w, "# HELP ssh_watcher_silenced_events_total Number of events dropped by silences.")
+	fmt.Fprintln(w, "# TYPE ssh_watcher_silenced_events_total counter")
+	for _, count := range s.Counts() {
+		fmt.Fprintf(w, "ssh_watcher_silenced_events_total{silence=%q,event_type=%q} %d\n", count.Silence, count.EventType, count.Count)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
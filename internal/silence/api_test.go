@@ -0,0 +1,114 @@
+package silence
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+func TestHandler(t *testing.T) {
+	s := newTestSilencer(t, filepath.Join(t.TempDir(), "silences.json"))
+	handler := Handler(s, "")
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	body := `{"matchers":[{"name":"host_machine","value":"web-1"}],"ends_at":"` + start.Add(time.Hour).Format(time.RFC3339) + `","created_by":"alice","comment":"kernel upgrade"}`
+	rec := serve(http.MethodPost, "/api/v1/silences", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var added apiSilence
+	if err := json.NewDecoder(rec.Body).Decode(&added); err != nil {
+		t.Fatalf("failed decoding added silence: %v", err)
+	}
+	if added.ID == "" || added.Status != Active || added.CreatedBy != "alice" {
+		t.Errorf("added silence = %+v, want an active silence with an ID", added)
+	}
+
+	if rec := serve(http.MethodPost, "/api/v1/silences", `{"matchers":[]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST invalid status = %d, want 400", rec.Code)
+	}
+
+	rec = serve(http.MethodGet, "/api/v1/silences", "")
+	var listed []apiSilence
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("failed decoding silences: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != added.ID {
+		t.Errorf("GET = %+v, want the added silence", listed)
+	}
+
+	s.Process(notifier.LogLine{EventType: notifier.LoggedIn, HostMachine: "web-1"})
+	rec = serve(http.MethodGet, "/metrics", "")
+	for _, want := range []string{
+		"ssh_watcher_silences_active 1",
+		`ssh_watcher_silenced_events_total{silence="` + added.ID + `",event_type="logged in"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics = %s, want %s", rec.Body, want)
+		}
+	}
+
+	if rec := serve(http.MethodDelete, "/api/v1/silences/"+added.ID, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want 204", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/v1/silences/unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE unknown status = %d, want 404", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/api/v1/silences", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT status = %d, want 405", rec.Code)
+	}
+}
+
+func TestHandler_Token(t *testing.T) {
+	s := newTestSilencer(t, filepath.Join(t.TempDir(), "silences.json"))
+	handler := Handler(s, "secret")
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		wantCode      int
+	}{
+		{name: "missing token", method: http.MethodGet, path: "/api/v1/silences", wantCode: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/api/v1/silences", authorization: "Bearer guess", wantCode: http.StatusUnauthorized},
+		{name: "delete without token", method: http.MethodDelete, path: "/api/v1/silences/x", wantCode: http.StatusUnauthorized},
+		{name: "valid token", method: http.MethodGet, path: "/api/v1/silences", authorization: "Bearer secret", wantCode: http.StatusOK},
+		{name: "metrics without token", method: http.MethodGet, path: "/metrics", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandler_LargeBody(t *testing.T) {
+	s := newTestSilencer(t, filepath.Join(t.TempDir(), "silences.json"))
+	body := `{"comment":"` + strings.Repeat("x", maxSilenceSize) + `"}`
+	rec := httptest.NewRecorder()
+	Handler(s, "").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST status = %d, want 400", rec.Code)
+	}
+	if len(s.List()) != 0 {
+		t.Errorf("silences = %v, want none", s.List())
+	}
+}
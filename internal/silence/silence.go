@@ -0,0 +1,149 @@
+// Package silence mutes the notifications of events matching silences for a
+// period of time, e.g. during planned maintenance.
+package silence
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+)
+
+// Silence mutes events matching all of its matchers between StartsAt and
+// EndsAt.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	Comment   string    `json:"comment"`
+}
+
+// Status is the state of a silence at a point in time.
+type Status string
+
+const (
+	Pending Status = "pending"
+	Active  Status = "active"
+	Expired Status = "expired"
+)
+
+// Status returns the state of the silence at now.
+func (s Silence) Status(now time.Time) Status {
+	switch {
+	case now.Before(s.StartsAt):
+		return Pending
+	case now.Before(s.EndsAt):
+		return Active
+	default:
+		return Expired
+	}
+}
+
+// Matcher matches an event field. Value is compared for equality, or as a
+// regular expression anchored at both ends when IsRegex is set. Values of
+// ip_address may also be CIDR ranges.
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"is_regex"`
+}
+
+// fields maps matcher names to the event field they match.
+var fields = map[string]func(logLine notifier.LogLine) string{
+	"event_type":   func(l notifier.LogLine) string { return string(l.EventType) },
+	"username":     func(l notifier.LogLine) string { return l.Username },
+	"ip_address":   func(l notifier.LogLine) string { return l.IpAddress },
+	"host_machine": func(l notifier.LogLine) string { return l.HostMachine },
+	"auth_method":  func(l notifier.LogLine) string { return l.AuthMethod },
+	"severity":     func(l notifier.LogLine) string { return string(l.Severity) },
+	"country": func(l notifier.LogLine) string {
+		if l.Location == nil {
+			return ""
+		}
+		return l.Location.Country
+	},
+}
+
+// ParseMatcher parses a matcher of the form name=value, or name=~regex for a
+// regular expression.
+func ParseMatcher(s string) (Matcher, error) {
+	if name, value, ok := strings.Cut(s, "=~"); ok {
+		return Matcher{Name: name, Value: value, IsRegex: true}, nil
+	}
+	if name, value, ok := strings.Cut(s, "="); ok {
+		return Matcher{Name: name, Value: value}, nil
+	}
+	return Matcher{}, fmt.Errorf("invalid matcher %q, want name=value or name=~regex", s)
+}
+
+func (m Matcher) String() string {
+	if m.IsRegex {
+		return m.Name + "=~" + m.Value
+	}
+	return m.Name + "=" + m.Value
+}
+
+// compiledMatcher is the compiled form of a Matcher.
+type compiledMatcher struct {
+	field  func(logLine notifier.LogLine) string
+	value  string
+	regex  *regexp.Regexp
+	prefix *netip.Prefix
+}
+
+func compileMatcher(m Matcher) (compiledMatcher, error) {
+	field, ok := fields[m.Name]
+	if !ok {
+		return compiledMatcher{}, fmt.Errorf("unknown matcher field %q", m.Name)
+	}
+	compiled := compiledMatcher{field: field, value: m.Value}
+	switch {
+	case m.IsRegex:
+		regex, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return compiledMatcher{}, fmt.Errorf("invalid matcher %s: %w", m, err)
+		}
+		compiled.regex = regex
+	case m.Name == "ip_address" && strings.Contains(m.Value, "/"):
+		prefix, err := cidr.Parse(m.Value)
+		if err != nil {
+			return compiledMatcher{}, fmt.Errorf("invalid matcher %s: %w", m, err)
+		}
+		compiled.prefix = &prefix
+	}
+	return compiled, nil
+}
+
+func (m compiledMatcher) matches(logLine notifier.LogLine) bool {
+	value := m.field(logLine)
+	switch {
+	case m.regex != nil:
+		return m.regex.MatchString(value)
+	case m.prefix != nil:
+		return cidr.Contains([]netip.Prefix{*m.prefix}, value)
+	default:
+		return value == m.value
+	}
+}
+
+// validate checks the silence can be compiled and has a valid time range.
+func (s Silence) validate() error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("silence has no matchers")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("silence ends before it starts")
+	}
+	for _, m := range s.Matchers {
+		if _, err := compileMatcher(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
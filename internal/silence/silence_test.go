@@ -0,0 +1,246 @@
+package silence
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+var start = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func newTestSilencer(t *testing.T, path string) *Silencer {
+	t.Helper()
+	s, err := New(path, 0, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	s.now = func() time.Time { return start }
+	return s
+}
+
+func mustParseMatchers(t *testing.T, matchers ...string) []Matcher {
+	t.Helper()
+	var parsed []Matcher
+	for _, m := range matchers {
+		matcher, err := ParseMatcher(m)
+		if err != nil {
+			t.Fatalf("ParseMatcher(%q) error = %v", m, err)
+		}
+		parsed = append(parsed, matcher)
+	}
+	return parsed
+}
+
+func TestParseMatcher(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Matcher
+		wantErr bool
+	}{
+		{in: "username=root", want: Matcher{Name: "username", Value: "root"}},
+		{in: "host_machine=~web-.*", want: Matcher{Name: "host_machine", Value: "web-.*", IsRegex: true}},
+		{in: "ip_address=10.0.0.0/8", want: Matcher{Name: "ip_address", Value: "10.0.0.0/8"}},
+		{in: "username", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMatcher(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMatcher() = %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestSilencer_Process(t *testing.T) {
+	loggedIn := notifier.LogLine{
+		Username:    "deploy",
+		IpAddress:   "10.1.2.3",
+		EventType:   notifier.LoggedIn,
+		HostMachine: "web-1",
+		Severity:    notifier.SeverityMedium,
+		Location:    &notifier.Location{Country: "DE"},
+	}
+	tests := []struct {
+		name     string
+		matchers []string
+		startsAt time.Time
+		endsAt   time.Time
+		silenced bool
+	}{
+		{
+			name:     "active silence matching all matchers",
+			matchers: []string{"username=deploy", "host_machine=~web-[0-9]+", "ip_address=10.0.0.0/8", "country=DE"},
+			silenced: true,
+		},
+		{
+			name:     "one matcher differs",
+			matchers: []string{"username=deploy", "event_type=failed login attempt"},
+		},
+		{
+			name:     "regex is anchored",
+			matchers: []string{"host_machine=~web"},
+		},
+		{
+			name:     "ip outside range",
+			matchers: []string{"ip_address=192.168.0.0/16"},
+		},
+		{
+			name:     "pending silence",
+			matchers: []string{"username=deploy"},
+			startsAt: start.Add(time.Hour),
+			endsAt:   start.Add(2 * time.Hour),
+		},
+		{
+			name:     "expired silence",
+			matchers: []string{"username=deploy"},
+			startsAt: start.Add(-2 * time.Hour),
+			endsAt:   start.Add(-time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSilencer(t, filepath.Join(t.TempDir(), "silences.json"))
+			startsAt, endsAt := tt.startsAt, tt.endsAt
+			if startsAt.IsZero() {
+				startsAt, endsAt = start, start.Add(time.Hour)
+			}
+			added, err := s.Add(Silence{Matchers: mustParseMatchers(t, tt.matchers...), StartsAt: startsAt, EndsAt: endsAt})
+			if err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+
+			got := s.Process(loggedIn)
+			if tt.silenced {
+				if got != nil {
+					t.Errorf("Process() = %v, want silenced", got)
+				}
+				want := []Count{{Silence: added.ID, EventType: notifier.LoggedIn, Count: 1}}
+				if counts := s.Counts(); !reflect.DeepEqual(counts, want) {
+					t.Errorf("Counts() = %v, want %v", counts, want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, []notifier.LogLine{loggedIn}) {
+				t.Errorf("Process() = %v, want log line passed through", got)
+			}
+			if counts := s.Counts(); len(counts) != 0 {
+				t.Errorf("Counts() = %v, want none", counts)
+			}
+		})
+	}
+}
+
+func TestSilencer_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		silence Silence
+	}{
+		{name: "no matchers", silence: Silence{EndsAt: start.Add(time.Hour)}},
+		{name: "ends before start", silence: Silence{Matchers: []Matcher{{Name: "username", Value: "root"}}, EndsAt: start.Add(-time.Hour)}},
+		{name: "unknown field", silence: Silence{Matchers: []Matcher{{Name: "shell", Value: "bash"}}, EndsAt: start.Add(time.Hour)}},
+		{name: "invalid regex", silence: Silence{Matchers: []Matcher{{Name: "username", Value: "(", IsRegex: true}}, EndsAt: start.Add(time.Hour)}},
+		{name: "invalid range", silence: Silence{Matchers: []Matcher{{Name: "ip_address", Value: "10.0.0.0/33"}}, EndsAt: start.Add(time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "silences.json")
+			s := newTestSilencer(t, path)
+			if _, err := s.Add(tt.silence); err == nil {
+				t.Fatal("Add() error = nil, want invalid silence")
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("silences file written for invalid silence, stat error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSilencer_Expire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	s := newTestSilencer(t, path)
+	added, err := s.Add(Silence{Matchers: mustParseMatchers(t, "username=deploy"), EndsAt: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if added.StartsAt != start {
+		t.Errorf("StartsAt = %v, want now", added.StartsAt)
+	}
+
+	s.now = func() time.Time { return start.Add(10 * time.Minute) }
+	if err := s.Expire(added.ID); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	if err := s.Expire("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expire(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	// A second silencer sharing the file, like the running watcher, sees the
+	// expiry.
+	other := newTestSilencer(t, path)
+	other.now = s.now
+	silences := other.List()
+	if len(silences) != 1 || silences[0].Status(other.now()) != Expired {
+		t.Fatalf("List() = %+v, want the silence expired", silences)
+	}
+	if got := other.Process(notifier.LogLine{Username: "deploy"}); len(got) != 1 {
+		t.Errorf("Process() = %v, want log line passed through", got)
+	}
+
+	// Silences expired longer than the retention are pruned on the next
+	// change.
+	s.now = func() time.Time { return start.Add(retention + time.Hour) }
+	if _, err := s.Add(Silence{Matchers: mustParseMatchers(t, "username=root"), EndsAt: start.Add(retention + 2*time.Hour)}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	silences = s.List()
+	if len(silences) != 1 || silences[0].Matchers[0].Value != "root" {
+		t.Errorf("List() = %+v, want only the new silence", silences)
+	}
+}
+
+func TestSilencer_ReloadsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	s, err := New(path, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := start
+	s.now = func() time.Time { return now }
+	s.lastCheck = now
+
+	cli := newTestSilencer(t, path)
+	if _, err := cli.Add(Silence{Matchers: mustParseMatchers(t, "username=deploy"), EndsAt: start.Add(time.Hour)}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	logLine := notifier.LogLine{Username: "deploy"}
+	if got := s.Process(logLine); len(got) != 1 {
+		t.Errorf("Process() before reload interval = %v, want passed through", got)
+	}
+	now = start.Add(time.Minute)
+	if got := s.Process(logLine); got != nil {
+		t.Errorf("Process() after reload = %v, want silenced", got)
+	}
+
+	// A corrupt file keeps the previous silences.
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now = start.Add(2 * time.Minute)
+	if got := s.Process(logLine); got != nil {
+		t.Errorf("Process() after failed reload = %v, want silenced", got)
+	}
+}
@@ -0,0 +1,263 @@
+package silence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/statefile"
+	"github.com/rs/zerolog"
+)
+
+// retention is how long expired silences are kept for reference before they
+// are pruned.
+const retention = 7 * 24 * time.Hour
+
+// ErrNotFound is returned when expiring an unknown silence.
+var ErrNotFound = errors.New("silence not found")
+
+// compiledSilence is the compiled form of a Silence.
+type compiledSilence struct {
+	Silence
+	matchers []compiledMatcher
+}
+
+func (s compiledSilence) matches(logLine notifier.LogLine) bool {
+	for _, m := range s.matchers {
+		if !m.matches(logLine) {
+			return false
+		}
+	}
+	return true
+}
+
+// New loads the silences persisted at path. The file is shared with the
+// silence command and checked for changes at most every reloadInterval.
+func New(path string, reloadInterval time.Duration, log zerolog.Logger) (*Silencer, error) {
+	s := &Silencer{
+		path:           path,
+		reloadInterval: reloadInterval,
+		counts:         map[countKey]int{},
+		now:            time.Now,
+		log:            log,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.lastCheck = s.now()
+	return s, nil
+}
+
+// Silencer is an app stage dropping the events matched by an active silence.
+// Silenced events are logged and counted instead of sent.
+type Silencer struct {
+	path           string
+	silences       []compiledSilence
+	modTime        time.Time
+	reloadInterval time.Duration
+	lastCheck      time.Time
+	counts         map[countKey]int
+	now            func() time.Time
+	log            zerolog.Logger
+	mu             sync.Mutex
+}
+
+// countKey identifies a silenced events counter.
+type countKey struct {
+	silence   string
+	eventType notifier.EventType
+}
+
+// Count is the number of events of a type a silence dropped since start.
+type Count struct {
+	Silence   string
+	EventType notifier.EventType
+	Count     int
+}
+
+// load reads the silences file and replaces the silences. s.mu must be held.
+func (s *Silencer) load() error {
+	var modTime time.Time
+	info, err := os.Stat(s.path)
+	switch {
+	case err == nil:
+		modTime = info.ModTime()
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed reading silences file: %w", err)
+	}
+	var silences []Silence
+	if err := statefile.Load(s.path, &silences); err != nil {
+		return err
+	}
+	compiled := make([]compiledSilence, 0, len(silences))
+	for _, silence := range silences {
+		c := compiledSilence{Silence: silence}
+		for _, m := range silence.Matchers {
+			matcher, err := compileMatcher(m)
+			if err != nil {
+				return fmt.Errorf("invalid silence %s: %w", silence.ID, err)
+			}
+			c.matchers = append(c.matchers, matcher)
+		}
+		compiled = append(compiled, c)
+	}
+	s.silences, s.modTime = compiled, modTime
+	return nil
+}
+
+// reloadChanged reloads the silences once reloadInterval passed since the
+// last check if the file changed. Invalid changes are logged and the previous
+// silences stay in use. s.mu must be held.
+func (s *Silencer) reloadChanged() {
+	now := s.now()
+	if now.Sub(s.lastCheck) < s.reloadInterval {
+		return
+	}
+	s.lastCheck = now
+	info, err := os.Stat(s.path)
+	if err == nil && info.ModTime().Equal(s.modTime) || errors.Is(err, fs.ErrNotExist) && s.modTime.IsZero() {
+		return
+	}
+	if err := s.load(); err != nil {
+		s.log.Error().Err(err).Msg("failed reloading silences file, keeping previous silences")
+		return
+	}
+	s.log.Info().Msg(fmt.Sprintf("reloaded silences file %s", s.path))
+}
+
+// update applies change to the silences freshly read from the file, prunes
+// silences expired longer than the retention and saves the result.
+func (s *Silencer) update(change func(silences []Silence, now time.Time) ([]Silence, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	now := s.now()
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		if now.Sub(silence.EndsAt) < retention {
+			silences = append(silences, silence.Silence)
+		}
+	}
+	silences, err := change(silences, now)
+	if err != nil {
+		return err
+	}
+	if err := statefile.Save(s.path, silences); err != nil {
+		return err
+	}
+	return s.load()
+}
+
+// Add validates and persists a new silence and returns it with its ID set.
+// A zero StartsAt starts the silence now.
+func (s *Silencer) Add(silence Silence) (Silence, error) {
+	id, err := newID()
+	if err != nil {
+		return Silence{}, err
+	}
+	err = s.update(func(silences []Silence, now time.Time) ([]Silence, error) {
+		silence.ID = id
+		if silence.StartsAt.IsZero() {
+			silence.StartsAt = now
+		}
+		if err := silence.validate(); err != nil {
+			return nil, err
+		}
+		return append(silences, silence), nil
+	})
+	if err != nil {
+		return Silence{}, err
+	}
+	return silence, nil
+}
+
+// Expire ends the silence with the given ID now. Expiring a silence that
+// already ended keeps its end time.
+func (s *Silencer) Expire(id string) error {
+	return s.update(func(silences []Silence, now time.Time) ([]Silence, error) {
+		for i := range silences {
+			if silences[i].ID != id {
+				continue
+			}
+			if now.Before(silences[i].EndsAt) {
+				silences[i].EndsAt = now
+			}
+			if now.Before(silences[i].StartsAt) {
+				silences[i].StartsAt = now
+			}
+			return silences, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	})
+}
+
+// List returns all silences, including expired ones not yet pruned, ordered by
+// end time.
+func (s *Silencer) List() []Silence {
+	s.mu.Lock()
+	s.reloadChanged()
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		silences = append(silences, silence.Silence)
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(silences, func(i, j int) bool {
+		return silences[i].EndsAt.Before(silences[j].EndsAt)
+	})
+	return silences
+}
+
+// Counts returns the number of events dropped per silence and event type since
+// start.
+func (s *Silencer) Counts() []Count {
+	s.mu.Lock()
+	counts := make([]Count, 0, len(s.counts))
+	for key, count := range s.counts {
+		counts = append(counts, Count{Silence: key.silence, EventType: key.eventType, Count: count})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Silence != counts[j].Silence {
+			return counts[i].Silence < counts[j].Silence
+		}
+		return counts[i].EventType < counts[j].EventType
+	})
+	return counts
+}
+
+// Process implements the app stage interface.
+func (s *Silencer) Process(logLine notifier.LogLine) []notifier.LogLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadChanged()
+	now := s.now()
+	for _, silence := range s.silences {
+		if silence.Status(now) != Active || !silence.matches(logLine) {
+			continue
+		}
+		s.counts[countKey{silence: silence.ID, eventType: logLine.EventType}]++
+		s.log.Info().Str("silence", silence.ID).Msg(fmt.Sprintf("silenced: %s", logLine.Summary()))
+		return nil
+	}
+	return []notifier.LogLine{logLine}
+}
+
+// newID returns a random silence ID.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed generating silence ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
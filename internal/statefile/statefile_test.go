@@ -0,0 +1,31 @@
+package statefile
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	got := map[string]int{"unchanged": 1}
+	if err := Load(path, &got); err != nil {
+		t.Fatalf("Load() missing file error = %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]int{"unchanged": 1}) {
+		t.Errorf("Load() missing file changed value to %v", got)
+	}
+
+	want := map[string]int{"a": 1, "b": 2}
+	if err := Save(path, want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got = map[string]int{}
+	if err := Load(path, &got); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %v, want %v", got, want)
+	}
+}
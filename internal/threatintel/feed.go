@@ -0,0 +1,59 @@
+package threatintel
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/mgla96/ssh-watcher/internal/cidr"
+)
+
+// parseEntry returns the IP address or CIDR range at the start of a feed
+// line. It understands plain lists such as FireHOL netsets and Tor exit
+// lists, Spamhaus DROP ("1.10.16.0/20 ; SBL256894") and CSV files with the
+// address in the first column. Comments, headers and other lines without a
+// leading address are skipped.
+func parseEntry(line string) (netip.Prefix, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+		return netip.Prefix{}, false
+	}
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return netip.Prefix{}, false
+	}
+	prefix, err := cidr.Parse(strings.Trim(fields[0], `"`))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// loadFeed adds the entries of the feed file at path to t under name and
+// returns the number of entries.
+func loadFeed(t *trie, name, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed reading feed %s: %w", name, err)
+	}
+	defer f.Close()
+
+	entries := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		prefix, ok := parseEntry(scanner.Text())
+		if !ok {
+			continue
+		}
+		t.insert(prefix, name)
+		entries++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed reading feed %s: %w", name, err)
+	}
+	return entries, nil
+}
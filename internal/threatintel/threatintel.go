@@ -0,0 +1,122 @@
+// Package threatintel tags events whose source is listed in local threat
+// intelligence feeds.
+package threatintel
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// New loads the feed files, keyed by feed name. The files are checked for
+// changes at most every reloadInterval.
+func New(feeds map[string]string, reloadInterval time.Duration, log zerolog.Logger) (*ThreatIntel, error) {
+	t := &ThreatIntel{
+		feeds:          feeds,
+		reloadInterval: reloadInterval,
+		now:            time.Now,
+		log:            log,
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	t.lastCheck = t.now()
+	return t, nil
+}
+
+// ThreatIntel is an app stage recording the feeds listing the source IP
+// address of an event in ThreatFeeds. Successful logins from a listed source
+// are escalated to critical.
+type ThreatIntel struct {
+	feeds          map[string]string
+	trie           *trie
+	modTimes       map[string]time.Time
+	reloadInterval time.Duration
+	lastCheck      time.Time
+	now            func() time.Time
+	log            zerolog.Logger
+	mu             sync.Mutex
+}
+
+// load reads all feeds into a new trie and replaces the current one.
+func (t *ThreatIntel) load() error {
+	names := make([]string, 0, len(t.feeds))
+	for name := range t.feeds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	loaded := newTrie()
+	modTimes := map[string]time.Time{}
+	for _, name := range names {
+		path := t.feeds[name]
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed reading feed %s: %w", name, err)
+		}
+		entries, err := loadFeed(loaded, name, path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+		t.log.Info().Msg(fmt.Sprintf("loaded %d entries from feed %s", entries, name))
+	}
+	t.trie, t.modTimes = loaded, modTimes
+	return nil
+}
+
+// reloadChanged reloads the feeds once reloadInterval passed since the last
+// check if a file changed. Feeds that fail to reload stay in use. t.mu must
+// be held.
+func (t *ThreatIntel) reloadChanged() {
+	now := t.now()
+	if now.Sub(t.lastCheck) < t.reloadInterval {
+		return
+	}
+	t.lastCheck = now
+
+	changed := false
+	for path, modTime := range t.modTimes {
+		if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(modTime) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := t.load(); err != nil {
+		t.log.Error().Err(err).Msg("failed reloading threat feeds, keeping previous entries")
+	}
+}
+
+// Lookup returns the names of the feeds listing ip.
+func (t *ThreatIntel) Lookup(ip netip.Addr) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reloadChanged()
+	return t.trie.lookup(ip)
+}
+
+// Process implements the app stage interface.
+func (t *ThreatIntel) Process(logLine notifier.LogLine) []notifier.LogLine {
+	ip, err := netip.ParseAddr(logLine.IpAddress)
+	if err != nil {
+		return []notifier.LogLine{logLine}
+	}
+	feeds := t.Lookup(ip)
+	if len(feeds) == 0 {
+		return []notifier.LogLine{logLine}
+	}
+	logLine.ThreatFeeds = feeds
+	if logLine.EventType == notifier.LoggedIn {
+		logLine.Severity = notifier.SeverityCritical
+	}
+	return []notifier.LogLine{logLine}
+}
@@ -0,0 +1,119 @@
+package threatintel
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   string
+		wantOk bool
+	}{
+		{line: "1.2.3.4", want: "1.2.3.4/32", wantOk: true},
+		{line: "203.0.113.0/24", want: "203.0.113.0/24", wantOk: true},
+		{line: "1.10.16.0/20 ; SBL256894", want: "1.10.16.0/20", wantOk: true},
+		{line: `"2001:db8::/32","bad network"`, want: "2001:db8::/32", wantOk: true},
+		{line: "# FireHOL level 1"},
+		{line: "; Spamhaus DROP List"},
+		{line: "ip,description"},
+		{line: "  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseEntry(tt.line)
+			if ok != tt.wantOk || (ok && got.String() != tt.want) {
+				t.Errorf("parseEntry() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func writeFeed(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThreatIntel_Process(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	tor, drop := filepath.Join(dir, "tor.txt"), filepath.Join(dir, "drop.txt")
+	writeFeed(t, tor, "1.2.3.4\n", now)
+	writeFeed(t, drop, "; Spamhaus DROP\n1.2.3.0/24 ; SBL1\n", now)
+	ti, err := New(map[string]string{"tor": tor, "spamhaus-drop": drop}, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		logLine notifier.LogLine
+		want    notifier.LogLine
+	}{
+		{
+			name:    "accepted login from listed source is critical",
+			logLine: notifier.LogLine{IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+			want:    notifier.LogLine{IpAddress: "1.2.3.4", EventType: notifier.LoggedIn, Severity: notifier.SeverityCritical, ThreatFeeds: []string{"spamhaus-drop", "tor"}},
+		},
+		{
+			name:    "failed login is tagged",
+			logLine: notifier.LogLine{IpAddress: "1.2.3.99", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow},
+			want:    notifier.LogLine{IpAddress: "1.2.3.99", EventType: notifier.FailedLoginAttempt, Severity: notifier.SeverityLow, ThreatFeeds: []string{"spamhaus-drop"}},
+		},
+		{
+			name:    "unlisted source",
+			logLine: notifier.LogLine{IpAddress: "5.6.7.8", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+			want:    notifier.LogLine{IpAddress: "5.6.7.8", EventType: notifier.LoggedIn, Severity: notifier.SeverityMedium},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ti.Process(tt.logLine); !reflect.DeepEqual(got, []notifier.LogLine{tt.want}) {
+				t.Errorf("Process() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreatIntel_Reload(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	tor := filepath.Join(dir, "tor.txt")
+	writeFeed(t, tor, "1.2.3.4\n", now)
+	ti, err := New(map[string]string{"tor": tor}, time.Minute, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ti.now = func() time.Time { return now }
+
+	writeFeed(t, tor, "5.6.7.8\n", now.Add(time.Hour))
+	now = now.Add(2 * time.Minute)
+	if got := ti.Lookup(netip.MustParseAddr("5.6.7.8")); !reflect.DeepEqual(got, []string{"tor"}) {
+		t.Errorf("Lookup() new entry = %v, want [tor]", got)
+	}
+	if got := ti.Lookup(netip.MustParseAddr("1.2.3.4")); len(got) != 0 {
+		t.Errorf("Lookup() removed entry = %v, want none", got)
+	}
+
+	// A feed that disappears keeps the previous entries.
+	if err := os.Remove(tor); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if got := ti.Lookup(netip.MustParseAddr("5.6.7.8")); !reflect.DeepEqual(got, []string{"tor"}) {
+		t.Errorf("Lookup() after failed reload = %v, want [tor]", got)
+	}
+}
@@ -0,0 +1,85 @@
+package threatintel
+
+import "net/netip"
+
+// trie is a binary prefix trie mapping IP prefixes to the feeds listing them.
+// Lookups take at most one step per address bit regardless of the number of
+// listed prefixes.
+type trie struct {
+	v4 *node
+	v6 *node
+}
+
+type node struct {
+	children [2]*node
+	// feeds lists the feeds containing the prefix ending at this node.
+	feeds []string
+}
+
+func newTrie() *trie {
+	return &trie{v4: &node{}, v6: &node{}}
+}
+
+func (t *trie) root(addr netip.Addr) *node {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// addrBits returns the 16 bytes of addr and the index of the first bit of
+// addr in them. IPv4 addresses occupy the last 4 bytes.
+func addrBits(addr netip.Addr) ([16]byte, int) {
+	if addr.Is4() {
+		return addr.As16(), 96
+	}
+	return addr.As16(), 0
+}
+
+// bit returns the i-th most significant bit of b.
+func bit(b *[16]byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// insert records that feed lists prefix.
+func (t *trie) insert(prefix netip.Prefix, feed string) {
+	addr := prefix.Addr()
+	b, start := addrBits(addr)
+	n := t.root(addr)
+	for i := start; i < start+prefix.Bits(); i++ {
+		next := bit(&b, i)
+		if n.children[next] == nil {
+			n.children[next] = &node{}
+		}
+		n = n.children[next]
+	}
+	for _, existing := range n.feeds {
+		if existing == feed {
+			return
+		}
+	}
+	n.feeds = append(n.feeds, feed)
+}
+
+// lookup returns the feeds listing a prefix that contains addr, in the
+// order they were first inserted along the path.
+func (t *trie) lookup(addr netip.Addr) []string {
+	addr = addr.Unmap()
+	b, start := addrBits(addr)
+	var feeds []string
+	seen := map[string]bool{}
+	n := t.root(addr)
+	for i := start; n != nil; i++ {
+		for _, feed := range n.feeds {
+			if !seen[feed] {
+				seen[feed] = true
+				feeds = append(feeds, feed)
+			}
+		}
+		if i == 128 {
+			break
+		}
+		n = n.children[bit(&b, i)]
+	}
+	return feeds
+}
@@ -0,0 +1,37 @@
+package threatintel
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestTrie_Lookup(t *testing.T) {
+	tr := newTrie()
+	tr.insert(netip.MustParsePrefix("10.0.0.0/8"), "firehol")
+	tr.insert(netip.MustParsePrefix("10.1.0.0/16"), "spamhaus")
+	tr.insert(netip.MustParsePrefix("10.1.2.3/32"), "tor")
+	tr.insert(netip.MustParsePrefix("10.1.2.3/32"), "tor")
+	tr.insert(netip.MustParsePrefix("2001:db8::/32"), "spamhaus")
+	tr.insert(netip.MustParsePrefix("0.0.0.0/0"), "everything")
+
+	tests := []struct {
+		ip   string
+		want []string
+	}{
+		{ip: "10.1.2.3", want: []string{"everything", "firehol", "spamhaus", "tor"}},
+		{ip: "10.1.9.9", want: []string{"everything", "firehol", "spamhaus"}},
+		{ip: "10.200.0.1", want: []string{"everything", "firehol"}},
+		{ip: "::ffff:10.200.0.1", want: []string{"everything", "firehol"}},
+		{ip: "192.0.2.1", want: []string{"everything"}},
+		{ip: "2001:db8::1", want: []string{"spamhaus"}},
+		{ip: "2001:db9::1", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := tr.lookup(netip.MustParseAddr(tt.ip)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package throttle limits how many notifications reach a sink by collapsing
+// repeated events and rate limiting deliveries.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// notifierClient is an interface for sending notifications
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . notifierClient
+type notifierClient interface {
+	Notify(LogLine notifier.LogLine) error
+}
+
+// fields maps the names of the fields events can be deduplicated on to their
+// value.
+var fields = map[string]func(logLine notifier.LogLine) string{
+	"event_type":      func(l notifier.LogLine) string { return string(l.EventType) },
+	"username":        func(l notifier.LogLine) string { return l.Username },
+	"ip_address":      func(l notifier.LogLine) string { return l.IpAddress },
+	"host_machine":    func(l notifier.LogLine) string { return l.HostMachine },
+	"auth_method":     func(l notifier.LogLine) string { return l.AuthMethod },
+	"key_fingerprint": func(l notifier.LogLine) string { return l.KeyFingerprint },
+	"severity":        func(l notifier.LogLine) string { return string(l.Severity) },
+}
+
+// NewDedup creates a dedup delivering to next the first of the events with
+// the same values of the named fields within window.
+func NewDedup(next notifierClient, window time.Duration, keyFields []string, log zerolog.Logger) (*Dedup, error) {
+	if len(keyFields) == 0 {
+		return nil, fmt.Errorf("dedup has no fields")
+	}
+	d := &Dedup{
+		next:    next,
+		window:  window,
+		entries: map[string]*dedupEntry{},
+		now:     time.Now,
+		log:     log,
+	}
+	for _, name := range keyFields {
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown dedup field %q", name)
+		}
+		d.keyFields = append(d.keyFields, field)
+	}
+	return d, nil
+}
+
+// Dedup is a notifierClient collapsing repeated events. The first event of a
+// key is delivered immediately and repeats within the window are counted.
+// Once the window ends the last repeat is delivered with the number of
+// repeats.
+type Dedup struct {
+	next      notifierClient
+	window    time.Duration
+	keyFields []func(logLine notifier.LogLine) string
+	entries   map[string]*dedupEntry
+	now       func() time.Time
+	log       zerolog.Logger
+	mu        sync.Mutex
+}
+
+type dedupEntry struct {
+	first   time.Time
+	last    notifier.LogLine
+	repeats int
+}
+
+func (d *Dedup) key(logLine notifier.LogLine) string {
+	values := make([]string, 0, len(d.keyFields))
+	for _, field := range d.keyFields {
+		values = append(values, field(logLine))
+	}
+	return strings.Join(values, "\x00")
+}
+
+// Notify delivers the log line unless it repeats an event delivered within
+// the window. Digests and canary events are never deduplicated.
+func (d *Dedup) Notify(logLine notifier.LogLine) error {
+	if logLine.EventType == notifier.DigestSummary || logLine.Canary {
+		return d.next.Notify(logLine)
+	}
+
+	d.mu.Lock()
+	now := d.now()
+	followUps := d.expire(now)
+	key := d.key(logLine)
+	entry, ok := d.entries[key]
+	repeats := 0
+	if ok {
+		entry.repeats++
+		entry.last = logLine
+		repeats = entry.repeats
+	} else {
+		d.entries[key] = &dedupEntry{first: now}
+	}
+	d.mu.Unlock()
+
+	d.send(followUps)
+	if ok {
+		d.log.Debug().Msg(fmt.Sprintf("%s repeated %d times", logLine.EventType, repeats))
+		return nil
+	}
+	return d.next.Notify(logLine)
+}
+
+// Flush delivers the follow-ups of the windows that ended.
+func (d *Dedup) Flush() {
+	d.mu.Lock()
+	followUps := d.expire(d.now())
+	d.mu.Unlock()
+	d.send(followUps)
+}
+
+// Run delivers follow-ups every interval until ctx is done.
+func (d *Dedup) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Flush()
+		}
+	}
+}
+
+// expire drops the entries whose window ended and returns the follow-ups of
+// those with repeats. d.mu must be held.
+func (d *Dedup) expire(now time.Time) []notifier.LogLine {
+	var followUps []notifier.LogLine
+	for key, entry := range d.entries {
+		if now.Sub(entry.first) < d.window {
+			continue
+		}
+		delete(d.entries, key)
+		if entry.repeats > 0 {
+			followUp := entry.last
+			followUp.Repeats = entry.repeats
+			followUps = append(followUps, followUp)
+		}
+	}
+	return followUps
+}
+
+func (d *Dedup) send(followUps []notifier.LogLine) {
+	for _, followUp := range followUps {
+		if err := d.next.Notify(followUp); err != nil {
+			d.log.Error().Err(err).Msg(fmt.Sprintf("failed sending repeats of %s", followUp.EventType))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/throttle/throttlefakes"
+	"github.com/rs/zerolog"
+)
+
+var start = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func invalidUser(username, ip string) notifier.LogLine {
+	return notifier.LogLine{Username: username, IpAddress: ip, EventType: notifier.FailedLoginAttemptInvalidUsername}
+}
+
+// sent returns the log lines delivered through client.
+func sent(client *throttlefakes.FakeNotifierClient) []notifier.LogLine {
+	var logLines []notifier.LogLine
+	for i := 0; i < client.NotifyCallCount(); i++ {
+		logLines = append(logLines, client.NotifyArgsForCall(i))
+	}
+	return logLines
+}
+
+func TestNewDedup(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []string
+		wantErr bool
+	}{
+		{name: "known fields", fields: []string{"event_type", "ip_address", "username"}},
+		{name: "no fields", wantErr: true},
+		{name: "unknown field", fields: []string{"event_type", "shell"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDedup(&throttlefakes.FakeNotifierClient{}, time.Minute, tt.fields, zerolog.Nop())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDedup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDedup_Notify(t *testing.T) {
+	next := &throttlefakes.FakeNotifierClient{}
+	d, err := NewDedup(next, time.Minute, []string{"event_type", "ip_address"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewDedup() error = %v", err)
+	}
+	now := start
+	d.now = func() time.Time { return now }
+
+	for _, logLine := range []notifier.LogLine{
+		invalidUser("admin", "1.2.3.4"),
+		invalidUser("oracle", "1.2.3.4"),
+		invalidUser("admin", "5.6.7.8"),
+		invalidUser("test", "1.2.3.4"),
+		{EventType: notifier.DigestSummary, Digest: &notifier.Digest{}},
+		{EventType: notifier.DigestSummary, Digest: &notifier.Digest{}},
+	} {
+		if err := d.Notify(logLine); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+	if got := next.NotifyCallCount(); got != 4 {
+		t.Fatalf("Notify() calls = %d, want the first event of each source and both digests", got)
+	}
+
+	now = start.Add(59 * time.Second)
+	d.Flush()
+	if got := next.NotifyCallCount(); got != 4 {
+		t.Fatalf("Notify() calls before the window ended = %d, want 4", got)
+	}
+
+	now = start.Add(time.Minute)
+	d.Flush()
+	logLines := sent(next)
+	if len(logLines) != 5 {
+		t.Fatalf("sent = %v, want one follow-up", logLines)
+	}
+	followUp := logLines[4]
+	if followUp.Repeats != 2 || followUp.Username != "test" || followUp.IpAddress != "1.2.3.4" {
+		t.Errorf("follow-up = %+v, want the last repeat of 1.2.3.4 repeated 2 times", followUp)
+	}
+
+	// A new window starts once the previous one ended.
+	if err := d.Notify(invalidUser("admin", "1.2.3.4")); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := next.NotifyCallCount(); got != 6 {
+		t.Errorf("Notify() calls = %d, want the event delivered in the new window", got)
+	}
+}
+
+func TestDedup_NotifyCanary(t *testing.T) {
+	next := &throttlefakes.FakeNotifierClient{}
+	d, err := NewDedup(next, time.Minute, []string{"event_type", "ip_address"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewDedup() error = %v", err)
+	}
+	canary := invalidUser("oracle", "1.2.3.4")
+	canary.Canary = true
+	for i := 0; i < 3; i++ {
+		if err := d.Notify(canary); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+	if got := next.NotifyCallCount(); got != 3 {
+		t.Errorf("Notify() calls = %d, want every canary event delivered", got)
+	}
+}
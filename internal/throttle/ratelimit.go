@@ -0,0 +1,156 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+// RateLimitSettings configures a rate limiter.
+type RateLimitSettings struct {
+	// Sink is the name of the limited sink, reported in overflow summaries.
+	Sink string
+	// HostMachine is reported as the host of overflow summaries.
+	HostMachine string
+	// PerMinute is how many events are delivered per minute on average.
+	PerMinute float64
+	// Burst is how many events are delivered at once after a quiet period.
+	Burst int
+}
+
+// NewRateLimiter creates a token bucket rate limiter delivering to next.
+func NewRateLimiter(next notifierClient, settings RateLimitSettings, log zerolog.Logger) (*RateLimiter, error) {
+	if settings.PerMinute <= 0 {
+		return nil, fmt.Errorf("rate limit of sink %q must be positive", settings.Sink)
+	}
+	if settings.Burst < 1 {
+		settings.Burst = 1
+	}
+	r := &RateLimiter{
+		next:     next,
+		settings: settings,
+		tokens:   float64(settings.Burst),
+		dropped:  map[notifier.EventType]int{},
+		now:      time.Now,
+		log:      log,
+	}
+	r.last = r.now()
+	return r, nil
+}
+
+// RateLimiter is a notifierClient dropping events once its token bucket is
+// empty. The dropped events are reported in an overflow summary as soon as a
+// token is available again.
+type RateLimiter struct {
+	next     notifierClient
+	settings RateLimitSettings
+	tokens   float64
+	last     time.Time
+	dropped  map[notifier.EventType]int
+	now      func() time.Time
+	log      zerolog.Logger
+	mu       sync.Mutex
+}
+
+// refill adds the tokens earned since the last refill. r.mu must be held.
+func (r *RateLimiter) refill() {
+	now := r.now()
+	r.tokens += now.Sub(r.last).Minutes() * r.settings.PerMinute
+	if burst := float64(r.settings.Burst); r.tokens > burst {
+		r.tokens = burst
+	}
+	r.last = now
+}
+
+// take takes a token if one is available. r.mu must be held.
+func (r *RateLimiter) take() bool {
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// overflow takes a token for the summary of the dropped events and returns
+// it, if there are dropped events and a token is available. r.mu must be
+// held.
+func (r *RateLimiter) overflow() (notifier.LogLine, bool) {
+	if len(r.dropped) == 0 || !r.take() {
+		return notifier.LogLine{}, false
+	}
+	eventTypes := make([]string, 0, len(r.dropped))
+	total := 0
+	for eventType, count := range r.dropped {
+		eventTypes = append(eventTypes, fmt.Sprintf("%d %s", count, eventType))
+		total += count
+	}
+	sort.Strings(eventTypes)
+	r.dropped = map[notifier.EventType]int{}
+	return notifier.LogLine{
+		LoginTime:   r.now().Format(time.RFC3339),
+		EventType:   notifier.NotificationsDropped,
+		HostMachine: r.settings.HostMachine,
+		Severity:    notifier.DefaultSeverity(notifier.NotificationsDropped),
+		Reason:      fmt.Sprintf("rate limit of sink %s exceeded, dropped %d events: %s", r.settings.Sink, total, strings.Join(eventTypes, ", ")),
+	}, true
+}
+
+// Notify delivers the log line if a token is available and drops it
+// otherwise. Canary events are always delivered, using a token if one is
+// available. A pending overflow summary is delivered first.
+func (r *RateLimiter) Notify(logLine notifier.LogLine) error {
+	r.mu.Lock()
+	r.refill()
+	summary, hasSummary := r.overflow()
+	allowed := r.take() || logLine.Canary
+	if !allowed {
+		r.dropped[logLine.EventType]++
+	}
+	r.mu.Unlock()
+
+	if hasSummary {
+		r.sendSummary(summary)
+	}
+	if !allowed {
+		r.log.Debug().Msg(fmt.Sprintf("rate limit of sink %s exceeded, dropping %s", r.settings.Sink, logLine.EventType))
+		return nil
+	}
+	return r.next.Notify(logLine)
+}
+
+// Flush delivers the overflow summary once a token is available.
+func (r *RateLimiter) Flush() {
+	r.mu.Lock()
+	r.refill()
+	summary, ok := r.overflow()
+	r.mu.Unlock()
+	if ok {
+		r.sendSummary(summary)
+	}
+}
+
+// Run delivers overflow summaries every interval until ctx is done.
+func (r *RateLimiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Flush()
+		}
+	}
+}
+
+func (r *RateLimiter) sendSummary(summary notifier.LogLine) {
+	if err := r.next.Notify(summary); err != nil {
+		r.log.Error().Err(err).Msg(fmt.Sprintf("failed sending overflow summary of sink %s", r.settings.Sink))
+	}
+}
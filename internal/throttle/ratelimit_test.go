@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mgla96/ssh-watcher/internal/notifier"
+	"github.com/mgla96/ssh-watcher/internal/throttle/throttlefakes"
+	"github.com/rs/zerolog"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	if _, err := NewRateLimiter(&throttlefakes.FakeNotifierClient{}, RateLimitSettings{Sink: "slack"}, zerolog.Nop()); err == nil {
+		t.Error("NewRateLimiter() error = nil, want error for a zero rate")
+	}
+}
+
+func TestRateLimiter_Notify(t *testing.T) {
+	next := &throttlefakes.FakeNotifierClient{}
+	r, err := NewRateLimiter(next, RateLimitSettings{Sink: "slack", HostMachine: "web-1", PerMinute: 60, Burst: 2}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error = %v", err)
+	}
+	now := start
+	r.now = func() time.Time { return now }
+	r.last = now
+
+	for _, logLine := range []notifier.LogLine{
+		invalidUser("admin", "1.2.3.4"),
+		invalidUser("oracle", "1.2.3.4"),
+		invalidUser("test", "1.2.3.4"),
+		invalidUser("guest", "1.2.3.4"),
+		{Username: "alice", EventType: notifier.LoggedIn},
+	} {
+		if err := r.Notify(logLine); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+	if got := next.NotifyCallCount(); got != 2 {
+		t.Fatalf("Notify() calls = %d, want the burst of 2", got)
+	}
+
+	// Half a token is not enough for the summary.
+	now = start.Add(500 * time.Millisecond)
+	r.Flush()
+	if got := next.NotifyCallCount(); got != 2 {
+		t.Fatalf("Notify() calls = %d, want no summary without a token", got)
+	}
+
+	now = start.Add(time.Second)
+	r.Flush()
+	logLines := sent(next)
+	if len(logLines) != 3 {
+		t.Fatalf("sent = %v, want an overflow summary", logLines)
+	}
+	summary := logLines[2]
+	wantReason := "rate limit of sink slack exceeded, dropped 3 events: 1 logged in, 2 failed login attempt with invalid username"
+	if summary.EventType != notifier.NotificationsDropped || summary.HostMachine != "web-1" || summary.Reason != wantReason {
+		t.Errorf("summary = %+v, want reason %q", summary, wantReason)
+	}
+
+	// Canary events are delivered without a token.
+	canary := invalidUser("oracle", "1.2.3.4")
+	canary.Canary = true
+	if err := r.Notify(canary); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := next.NotifyCallCount(); got != 4 {
+		t.Fatalf("Notify() calls = %d, want the canary event delivered", got)
+	}
+
+	// The bucket never holds more than the burst.
+	now = start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := r.Notify(invalidUser("admin", "1.2.3.4")); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+	if got := next.NotifyCallCount(); got != 6 {
+		t.Errorf("Notify() calls = %d, want 2 more after a quiet period", got)
+	}
+}